@@ -0,0 +1,47 @@
+package auth
+
+import (
+	"context"
+	"net"
+
+	"github.com/google/uuid"
+)
+
+// Authenticator authenticates players that are logging in.
+type Authenticator interface {
+	// Authenticate determines the profile of the player that is described by the
+	// given request. If the player can not be authenticated, an error is returned.
+	Authenticate(context.Context, Request) (Profile, error)
+}
+
+// Request holds the information about a player that is logging in.
+type Request struct {
+	// Username is the name that the player sent in the login start packet.
+	Username string
+	// ServerHash is the hash that was computed with ServerHash from the
+	// encryption handshake. This is empty if the connection is not encrypted.
+	ServerHash string
+	// IP is the IP address of the player.
+	IP net.IP
+}
+
+// Profile is the identity of an authenticated player.
+type Profile struct {
+	// UUID is the unique ID of the player.
+	UUID uuid.UUID
+	// Name is the name of the player. This may differ from the username in
+	// the Request, e.g. in case.
+	Name string
+	// Properties are additional properties of the player, such as the skin
+	// textures.
+	Properties []Property
+}
+
+// Property is a signed or unsigned property of a player profile.
+type Property struct {
+	Name  string `json:"name"`
+	Value string `json:"value"`
+	// Signature is the base64 encoded signature of the value. If this is empty,
+	// the property is not signed.
+	Signature string `json:"signature,omitempty"`
+}
@@ -0,0 +1,103 @@
+package auth
+
+import (
+	"context"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/suite"
+)
+
+func TestAuthSuite(t *testing.T) {
+	suite.Run(t, new(AuthSuite))
+}
+
+type AuthSuite struct {
+	suite.Suite
+}
+
+func (suite *AuthSuite) TestOfflineUUID() {
+	suite.Equal(uuid.MustParse("b50ad385-829d-3141-a216-7e7d7539ba7f"), OfflineUUID("Notch"))
+	suite.Equal(OfflineUUID("Notch"), OfflineUUID("Notch"))
+	suite.NotEqual(OfflineUUID("Notch"), OfflineUUID("notch"))
+	suite.EqualValues(3, OfflineUUID("Notch").Version())
+	suite.Equal(uuid.RFC4122, OfflineUUID("Notch").Variant())
+}
+
+func (suite *AuthSuite) TestOffline() {
+	profile, err := Offline{}.Authenticate(context.Background(), Request{
+		Username: "Notch",
+	})
+	suite.NoError(err)
+	suite.Equal(OfflineUUID("Notch"), profile.UUID)
+	suite.Equal("Notch", profile.Name)
+}
+
+func (suite *AuthSuite) TestServerHash() {
+	// test vectors from https://wiki.vg/Protocol_Encryption
+	suite.Equal("4ed1f46bbe04bc756bcb17c0c7ce3e4632f06a48", ServerHash("Notch", nil, nil))
+	suite.Equal("-7c9d5b0044c130109a5d7b5fb5c317c02b4e28c1", ServerHash("jeb_", nil, nil))
+	suite.Equal("88e16a1019277b15d58faf0541e11910eb756f6", ServerHash("simon", nil, nil))
+}
+
+func (suite *AuthSuite) TestSessionServer() {
+	var gotQuery map[string][]string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		gotQuery = r.URL.Query()
+		if r.URL.Path != "/session/minecraft/hasJoined" {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		if r.URL.Query().Get("username") != "Notch" {
+			w.WriteHeader(http.StatusNoContent)
+			return
+		}
+		_, _ = w.Write([]byte(`{
+			"id": "069a79f444e94726a5befca90e38aaf5",
+			"name": "Notch",
+			"properties": [
+				{
+					"name": "textures",
+					"value": "dGV4dHVyZXM=",
+					"signature": "c2lnbmF0dXJl"
+				}
+			]
+		}`))
+	}))
+	defer srv.Close()
+
+	authenticator := NewSessionServer(srv.URL)
+	authenticator.PreventProxyConnections = true
+
+	suite.Run("joined", func() {
+		profile, err := authenticator.Authenticate(context.Background(), Request{
+			Username:   "Notch",
+			ServerHash: "-7c9d5b0044c130109a5d7b5fb5c317c02b4e28c1",
+			IP:         net.IPv4(127, 0, 0, 1),
+		})
+		suite.NoError(err)
+		suite.Equal(Profile{
+			UUID: uuid.MustParse("069a79f4-44e9-4726-a5be-fca90e38aaf5"),
+			Name: "Notch",
+			Properties: []Property{
+				{
+					Name:      "textures",
+					Value:     "dGV4dHVyZXM=",
+					Signature: "c2lnbmF0dXJl",
+				},
+			},
+		}, profile)
+		suite.Equal([]string{"-7c9d5b0044c130109a5d7b5fb5c317c02b4e28c1"}, gotQuery["serverId"])
+		suite.Equal([]string{"127.0.0.1"}, gotQuery["ip"])
+	})
+	suite.Run("not joined", func() {
+		_, err := authenticator.Authenticate(context.Background(), Request{
+			Username:   "jeb_",
+			ServerHash: "4ed1f46bbe04bc756bcb17c0c7ce3e4632f06a48",
+		})
+		suite.ErrorIs(err, ErrNotAuthenticated)
+	})
+}
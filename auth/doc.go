@@ -0,0 +1,18 @@
+// Package auth provides means for authenticating players that are logging in.
+// An Authenticator takes the name that a player sent in the login phase and
+// determines the profile of the player, most importantly the player's UUID.
+//
+// There are two implementations. Offline trusts the name that the player sent
+// and derives the UUID from it, just as the vanilla server does in offline mode.
+//
+//	profile, _ := auth.Offline{}.Authenticate(ctx, auth.Request{Username: "Notch"})
+//	profile.UUID // == auth.OfflineUUID("Notch")
+//
+// SessionServer asks a session server (by default the one of Mojang) whether
+// the player has joined the server. This requires an encrypted connection, since
+// the server hash is computed from the shared secret of the encryption handshake.
+//
+//	serverHash := auth.ServerHash("", sharedSecret, publicKey)
+//	profile, err := auth.NewSessionServer(auth.DefaultSessionServerURL).
+//		Authenticate(ctx, auth.Request{Username: "Notch", ServerHash: serverHash})
+package auth
@@ -0,0 +1,12 @@
+package auth
+
+type Error string
+
+func (e Error) Error() string { return string(e) }
+
+const (
+	// ErrNotAuthenticated indicates, that the session server didn't confirm that
+	// the player has joined the server, e.g. because the player isn't logged in
+	// or sent the wrong server hash.
+	ErrNotAuthenticated Error = "player is not authenticated"
+)
@@ -0,0 +1,33 @@
+package auth
+
+import (
+	"context"
+	"crypto/md5" // #nosec, used for compatibility with the vanilla server, not for security
+
+	"github.com/google/uuid"
+)
+
+// Offline is an Authenticator that trusts the username that the player sent.
+// The UUID of the player is derived from the username, so the same name always
+// results in the same UUID.
+type Offline struct{}
+
+// Authenticate returns a profile with the requested username and its OfflineUUID.
+// This never fails.
+func (Offline) Authenticate(_ context.Context, req Request) (Profile, error) {
+	return Profile{
+		UUID: OfflineUUID(req.Username),
+		Name: req.Username,
+	}, nil
+}
+
+// OfflineUUID returns the UUID that the vanilla server uses for the player with the
+// given name in offline mode. This is the version 3 UUID of "OfflinePlayer:<name>",
+// as created by Java's UUID.nameUUIDFromBytes, which - as opposed to uuid.NewMD5 -
+// doesn't use a namespace.
+func OfflineUUID(name string) uuid.UUID {
+	sum := md5.Sum([]byte("OfflinePlayer:" + name)) // #nosec
+	sum[6] = (sum[6] & 0x0f) | 0x30                 // version 3
+	sum[8] = (sum[8] & 0x3f) | 0x80                 // RFC 4122 variant
+	return uuid.UUID(sum)
+}
@@ -0,0 +1,114 @@
+package auth
+
+import (
+	"context"
+	"crypto/sha1" // #nosec, the server hash is defined with sha1
+	"encoding/json"
+	"fmt"
+	"math/big"
+	"net/http"
+	"net/url"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+const (
+	// DefaultSessionServerURL is the base URL of Mojang's session server.
+	DefaultSessionServerURL = "https://sessionserver.mojang.com"
+
+	defaultSessionServerTimeout = 10 * time.Second
+)
+
+// SessionServer is an Authenticator that asks a session server whether the player
+// has joined this server, just like the vanilla server does in online mode.
+type SessionServer struct {
+	// BaseURL is the base URL of the session server, e.g. DefaultSessionServerURL.
+	BaseURL string
+	// PreventProxyConnections controls whether the IP address of the player is sent
+	// to the session server, which will then only authenticate the player if the IP
+	// address matches the one the player used to log in to the session server.
+	PreventProxyConnections bool
+	// Client is the client that is used to send requests to the session server.
+	Client *http.Client
+}
+
+// NewSessionServer creates a new SessionServer authenticator, which uses the session
+// server at the given base URL.
+func NewSessionServer(baseURL string) *SessionServer {
+	return &SessionServer{
+		BaseURL: baseURL,
+		Client: &http.Client{
+			Timeout: defaultSessionServerTimeout,
+		},
+	}
+}
+
+type hasJoinedResponse struct {
+	ID         string     `json:"id"`
+	Name       string     `json:"name"`
+	Properties []Property `json:"properties"`
+}
+
+// Authenticate asks the session server whether the player has joined this server.
+// If the session server doesn't confirm that, ErrNotAuthenticated is returned.
+func (s *SessionServer) Authenticate(ctx context.Context, req Request) (Profile, error) {
+	query := url.Values{}
+	query.Set("username", req.Username)
+	query.Set("serverId", req.ServerHash)
+	if s.PreventProxyConnections && req.IP != nil {
+		query.Set("ip", req.IP.String())
+	}
+
+	httpReq, err := http.NewRequestWithContext(ctx, http.MethodGet, s.BaseURL+"/session/minecraft/hasJoined?"+query.Encode(), nil)
+	if err != nil {
+		return Profile{}, fmt.Errorf("create request: %w", err)
+	}
+	resp, err := s.Client.Do(httpReq)
+	if err != nil {
+		return Profile{}, fmt.Errorf("has joined: %w", err)
+	}
+	defer func() { _ = resp.Body.Close() }()
+
+	switch resp.StatusCode {
+	case http.StatusOK:
+	case http.StatusNoContent:
+		return Profile{}, ErrNotAuthenticated
+	default:
+		return Profile{}, fmt.Errorf("has joined: unexpected status %s", resp.Status)
+	}
+
+	var joined hasJoinedResponse
+	if err := json.NewDecoder(resp.Body).Decode(&joined); err != nil {
+		return Profile{}, fmt.Errorf("decode response: %w", err)
+	}
+	id, err := uuid.Parse(joined.ID)
+	if err != nil {
+		return Profile{}, fmt.Errorf("parse uuid: %w", err)
+	}
+
+	return Profile{
+		UUID:       id,
+		Name:       joined.Name,
+		Properties: joined.Properties,
+	}, nil
+}
+
+// ServerHash computes the hash that the client and the server send to the session
+// server. It is the sha1 hash of the server ID, the shared secret and the public key
+// of the server, formatted as signed hexadecimal number, like Java's
+// new BigInteger(digest).toString(16) does.
+func ServerHash(serverID string, sharedSecret, publicKey []byte) string {
+	h := sha1.New() // #nosec
+	_, _ = h.Write([]byte(serverID))
+	_, _ = h.Write(sharedSecret)
+	_, _ = h.Write(publicKey)
+	digest := h.Sum(nil)
+
+	n := new(big.Int).SetBytes(digest)
+	if digest[0]&0x80 != 0 {
+		// the digest is a negative two's complement number
+		n.Sub(n, new(big.Int).Lsh(big.NewInt(1), uint(len(digest)*8)))
+	}
+	return n.Text(16)
+}
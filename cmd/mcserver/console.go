@@ -0,0 +1,40 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"io"
+
+	"github.com/rs/zerolog"
+
+	"github.com/tsatke/mcserver"
+)
+
+// runConsole executes the commands that are read line by line from the given reader,
+// and writes their output to the given writer, until the reader is exhausted or the
+// given context is cancelled.
+func runConsole(ctx context.Context, log zerolog.Logger, srv *mcserver.MCServer, stdin io.Reader, stdout io.Writer) {
+	scanner := bufio.NewScanner(stdin)
+	for scanner.Scan() {
+		if ctx.Err() != nil {
+			return
+		}
+		if scanner.Text() == "" {
+			continue
+		}
+
+		output, err := srv.ExecuteCommand(ctx, scanner.Text())
+		if err != nil {
+			output = err.Error()
+		}
+		if output != "" {
+			_, _ = fmt.Fprintln(stdout, output)
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		log.Error().
+			Err(err).
+			Msg("read console input failed")
+	}
+}
@@ -0,0 +1,588 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"math/rand"
+	"net"
+	"os"
+	"os/signal"
+	"sort"
+	"strconv"
+	"sync"
+	"sync/atomic"
+	"syscall"
+	"time"
+
+	"github.com/rs/zerolog"
+	"github.com/spf13/cobra"
+
+	"github.com/tsatke/mcserver/game/voxel"
+	"github.com/tsatke/mcserver/network/client"
+	"github.com/tsatke/mcserver/network/packet"
+	"github.com/tsatke/mcserver/network/rcon"
+)
+
+const (
+	// walkSpeed is the speed of simulated players in blocks per second, which is
+	// the walking speed of the vanilla client.
+	walkSpeed = 4.3
+	// rconTimeout is the time that the server has to answer an RCON command.
+	rconTimeout = 5 * time.Second
+)
+
+var (
+	loadtestFlags loadtestConfig
+
+	loadtestCmd = &cobra.Command{
+		Use:   "loadtest",
+		Short: "Connect simulated players to a server and report how it copes",
+		Long: `Connect simulated players to an offline mode server, one after another over
+the ramp duration. The players walk randomly, chat and place blocks. Login latency,
+packet throughput and disconnects are reported periodically and at the end. If an
+RCON address is given, the tick health of the server is reported as well.
+
+All players connect from the same IP address, so network.max_connections_per_ip,
+network.login_rate and network.login_burst of the server must be high enough.`,
+		Args: cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			cfg := loadtestFlags
+			if cfg.players < 1 {
+				return fmt.Errorf("need at least one player, but got %d", cfg.players)
+			}
+			if cfg.reportInterval <= 0 {
+				return fmt.Errorf("report interval must be positive, but is %s", cfg.reportInterval)
+			}
+			if len(cfg.namePrefix)+len(strconv.Itoa(cfg.players-1)) > 16 {
+				return fmt.Errorf("name prefix %q is too long for %d players, names must not be longer than 16 characters", cfg.namePrefix, cfg.players)
+			}
+
+			ctx, cancel := context.WithCancel(context.Background())
+			defer cancel()
+			if cfg.duration > 0 {
+				ctx, cancel = context.WithTimeout(ctx, cfg.duration)
+				defer cancel()
+			}
+
+			signalChan := make(chan os.Signal, 1)
+			signal.Notify(signalChan, os.Interrupt, syscall.SIGINT, syscall.SIGTERM)
+			defer signal.Stop(signalChan)
+			go func() {
+				select {
+				case <-signalChan:
+					cancel()
+				case <-ctx.Done():
+				}
+			}()
+
+			log := zerolog.New(
+				zerolog.ConsoleWriter{
+					Out: cmd.ErrOrStderr(),
+				},
+			).Level(zerolog.WarnLevel).
+				With().
+				Timestamp().
+				Logger()
+			return newLoadtest(cfg, log, cmd.OutOrStdout()).run(ctx)
+		},
+	}
+)
+
+func init() {
+	flags := loadtestCmd.Flags()
+	flags.StringVar(&loadtestFlags.addr, "addr", "localhost:25565", "address of the server")
+	flags.IntVar(&loadtestFlags.players, "players", 100, "amount of simulated players")
+	flags.DurationVar(&loadtestFlags.ramp, "ramp", 10*time.Second, "time over which the players connect")
+	flags.DurationVar(&loadtestFlags.duration, "duration", time.Minute, "time after which the test stops, or 0 to run until interrupted")
+	flags.StringVar(&loadtestFlags.namePrefix, "name-prefix", "bot", "prefix of the player names, which are followed by the number of the player")
+	flags.DurationVar(&loadtestFlags.moveInterval, "move-interval", 250*time.Millisecond, "interval in which players move, or 0 to not move")
+	flags.DurationVar(&loadtestFlags.chatInterval, "chat-interval", 10*time.Second, "interval in which players chat, or 0 to not chat")
+	flags.DurationVar(&loadtestFlags.placeInterval, "place-interval", 5*time.Second, "interval in which players place blocks, or 0 to not place blocks")
+	flags.DurationVar(&loadtestFlags.reportInterval, "report-interval", 5*time.Second, "interval in which the progress is reported")
+	flags.StringVar(&loadtestFlags.rconAddr, "rcon-addr", "", "RCON address of the server, to report its tick health")
+	flags.StringVar(&loadtestFlags.rconPassword, "rcon-password", "", "RCON password of the server")
+
+	rootCmd.AddCommand(loadtestCmd)
+}
+
+// loadtestConfig holds the flags of the loadtest command.
+type loadtestConfig struct {
+	addr           string
+	players        int
+	ramp           time.Duration
+	duration       time.Duration
+	namePrefix     string
+	moveInterval   time.Duration
+	chatInterval   time.Duration
+	placeInterval  time.Duration
+	reportInterval time.Duration
+	rconAddr       string
+	rconPassword   string
+}
+
+// loadtest connects simulated players to a server and collects statistics.
+type loadtest struct {
+	cfg loadtestConfig
+	log zerolog.Logger
+	out io.Writer
+
+	// online, loggedIn, loginFailures and disconnects are updated atomically.
+	online        int64
+	loggedIn      int64
+	loginFailures int64
+	disconnects   int64
+
+	// mu guards the fields below.
+	mu             sync.Mutex
+	loginLatencies []time.Duration
+	// disconnectReasons counts the reasons of failed logins and disconnects.
+	disconnectReasons map[string]int
+	// clients are the clients of the online players.
+	clients map[*client.Client]struct{}
+	// finished are the summed stats of the clients that are not online anymore.
+	finished client.Stats
+}
+
+func newLoadtest(cfg loadtestConfig, log zerolog.Logger, out io.Writer) *loadtest {
+	return &loadtest{
+		cfg:               cfg,
+		log:               log,
+		out:               out,
+		disconnectReasons: make(map[string]int),
+		clients:           make(map[*client.Client]struct{}),
+	}
+}
+
+// run connects the players over the ramp and reports the statistics, until the
+// given context is cancelled.
+func (l *loadtest) run(ctx context.Context) error {
+	var rconClient *rconClient
+	if l.cfg.rconAddr != "" {
+		c, err := dialRCON(l.cfg.rconAddr, l.cfg.rconPassword)
+		if err != nil {
+			return fmt.Errorf("rcon: %w", err)
+		}
+		defer func() { _ = c.Close() }()
+		rconClient = c
+	}
+
+	var wg sync.WaitGroup
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		l.rampUp(ctx, &wg)
+	}()
+
+	start := time.Now()
+	last := l.stats()
+	ticker := time.NewTicker(l.cfg.reportInterval)
+	defer ticker.Stop()
+	for ctx.Err() == nil {
+		select {
+		case <-ticker.C:
+			current := l.stats()
+			l.report(time.Since(start), current, last, rconClient)
+			last = current
+		case <-ctx.Done():
+		}
+	}
+
+	wg.Wait()
+	l.summarize(time.Since(start), rconClient)
+	return nil
+}
+
+// rampUp starts the players evenly distributed over the ramp duration, and adds
+// them to the given wait group.
+func (l *loadtest) rampUp(ctx context.Context, wg *sync.WaitGroup) {
+	interval := l.cfg.ramp / time.Duration(l.cfg.players)
+	for i := 0; i < l.cfg.players; i++ {
+		if i > 0 {
+			select {
+			case <-time.After(interval):
+			case <-ctx.Done():
+				return
+			}
+		}
+
+		wg.Add(1)
+		go func(index int) {
+			defer wg.Done()
+			l.runPlayer(ctx, index)
+		}(i)
+	}
+}
+
+// runPlayer connects a single player and simulates it until the given context is
+// cancelled or the player is disconnected.
+func (l *loadtest) runPlayer(ctx context.Context, index int) {
+	name := l.cfg.namePrefix + strconv.Itoa(index)
+
+	start := time.Now()
+	c, err := client.Dial(ctx, l.log, l.cfg.addr)
+	if err != nil {
+		l.loginFailed(ctx, err)
+		return
+	}
+	if _, err := c.Login(ctx, name); err != nil {
+		l.loginFailed(ctx, err)
+		return
+	}
+	l.loggedInAfter(c, time.Since(start))
+	defer l.loggedOut(c)
+
+	p := &simulatedPlayer{
+		name:   name,
+		client: c,
+		rand:   rand.New(rand.NewSource(int64(index))),
+	}
+	moves := tickerOrNil(l.cfg.moveInterval)
+	chats := tickerOrNil(l.cfg.chatInterval)
+	placements := tickerOrNil(l.cfg.placeInterval)
+	defer func() {
+		for _, t := range []*time.Ticker{moves, chats, placements} {
+			if t != nil {
+				t.Stop()
+			}
+		}
+	}()
+
+	done := ctx.Done()
+	for {
+		var err error
+		select {
+		case <-done:
+			// wait for the DisconnectEvent
+			_ = c.Close()
+			done = nil
+		case event := <-c.Events():
+			switch e := event.(type) {
+			case client.PositionEvent:
+				p.spawned = true
+				p.position = e
+			case client.DisconnectEvent:
+				if !errors.Is(e.Err, client.ErrClosed) {
+					reason := e.Reason.PlainText()
+					if e.Err != nil {
+						reason = e.Err.Error()
+					}
+					atomic.AddInt64(&l.disconnects, 1)
+					l.recordReason(reason)
+				}
+				return
+			}
+		case <-tickerC(moves):
+			err = p.walk(l.cfg.moveInterval)
+		case <-tickerC(chats):
+			err = p.chat()
+		case <-tickerC(placements):
+			err = p.placeBlock()
+		}
+		if err != nil && !errors.Is(err, client.ErrClosed) {
+			l.log.Warn().
+				Err(err).
+				Str("player", name).
+				Msg("simulated player failed, closing connection")
+			_ = c.Close()
+		}
+	}
+}
+
+// simulatedPlayer is the state of a single player of the load test.
+type simulatedPlayer struct {
+	name   string
+	client *client.Client
+	rand   *rand.Rand
+
+	// spawned indicates, that the server sent the position of the player.
+	spawned  bool
+	position client.PositionEvent
+	// messages is the amount of chat messages that were sent.
+	messages int
+}
+
+// walk moves the player into a slightly random direction, as far as a player can
+// walk in the given time.
+func (p *simulatedPlayer) walk(interval time.Duration) error {
+	if !p.spawned {
+		return nil
+	}
+
+	p.position.Yaw += float32(p.rand.Intn(61) - 30)
+	distance := walkSpeed * interval.Seconds()
+	yaw := float64(p.position.Yaw) * math.Pi / 180
+	p.position.X -= math.Sin(yaw) * distance
+	p.position.Z += math.Cos(yaw) * distance
+	return p.client.WritePacket(packet.ServerboundPlayerPositionAndRotation{
+		X:        p.position.X,
+		FeetY:    p.position.Y,
+		Z:        p.position.Z,
+		Yaw:      p.position.Yaw,
+		Pitch:    p.position.Pitch,
+		OnGround: true,
+	})
+}
+
+func (p *simulatedPlayer) chat() error {
+	p.messages++
+	return p.client.WritePacket(packet.ServerboundChatMessage{
+		Message: fmt.Sprintf("Hello from %s, message %d", p.name, p.messages),
+	})
+}
+
+// placeBlock places a block on top of the block that the player stands on.
+func (p *simulatedPlayer) placeBlock() error {
+	if !p.spawned {
+		return nil
+	}
+
+	return p.client.WritePacket(packet.ServerboundPlayerBlockPlacement{
+		Hand: 0,
+		Location: voxel.V3{
+			X: int(math.Floor(p.position.X)),
+			Y: int(math.Floor(p.position.Y)) - 1,
+			Z: int(math.Floor(p.position.Z)),
+		},
+		Face:    1, // top
+		CursorX: 0.5,
+		CursorY: 1,
+		CursorZ: 0.5,
+	})
+}
+
+func (l *loadtest) loginFailed(ctx context.Context, err error) {
+	if ctx.Err() != nil {
+		// the test is over
+		return
+	}
+	atomic.AddInt64(&l.loginFailures, 1)
+	l.recordReason(err.Error())
+}
+
+func (l *loadtest) loggedInAfter(c *client.Client, latency time.Duration) {
+	atomic.AddInt64(&l.loggedIn, 1)
+	atomic.AddInt64(&l.online, 1)
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	l.loginLatencies = append(l.loginLatencies, latency)
+	l.clients[c] = struct{}{}
+}
+
+func (l *loadtest) loggedOut(c *client.Client) {
+	atomic.AddInt64(&l.online, -1)
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	delete(l.clients, c)
+	l.finished = addStats(l.finished, c.Stats())
+}
+
+func (l *loadtest) recordReason(reason string) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	l.disconnectReasons[reason]++
+}
+
+// stats returns the summed stats of all clients.
+func (l *loadtest) stats() client.Stats {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	stats := l.finished
+	for c := range l.clients {
+		stats = addStats(stats, c.Stats())
+	}
+	return stats
+}
+
+// report writes the progress, with the throughput since the last report.
+func (l *loadtest) report(elapsed time.Duration, current, last client.Stats, rconClient *rconClient) {
+	seconds := l.cfg.reportInterval.Seconds()
+	_, _ = fmt.Fprintf(l.out, "%s: %d online, %d logged in, %d failed logins, %d disconnects, %.1f packets/s in (%s/s), %.1f packets/s out (%s/s)\n",
+		elapsed.Round(time.Second),
+		atomic.LoadInt64(&l.online),
+		atomic.LoadInt64(&l.loggedIn),
+		atomic.LoadInt64(&l.loginFailures),
+		atomic.LoadInt64(&l.disconnects),
+		float64(current.PacketsRead-last.PacketsRead)/seconds,
+		formatBytes(float64(current.BytesRead-last.BytesRead)/seconds),
+		float64(current.PacketsWritten-last.PacketsWritten)/seconds,
+		formatBytes(float64(current.BytesWritten-last.BytesWritten)/seconds),
+	)
+	l.reportTickHealth(rconClient)
+}
+
+// summarize writes the results of the whole test.
+func (l *loadtest) summarize(elapsed time.Duration, rconClient *rconClient) {
+	stats := l.stats()
+	seconds := elapsed.Seconds()
+
+	_, _ = fmt.Fprintf(l.out, "\nfinished after %s\n", elapsed.Round(time.Second))
+	_, _ = fmt.Fprintf(l.out, "logins: %d succeeded, %d failed\n",
+		atomic.LoadInt64(&l.loggedIn), atomic.LoadInt64(&l.loginFailures))
+
+	// all players stopped, so the fields are not modified anymore
+	latencies := l.loginLatencies
+	counts := l.disconnectReasons
+	reasons := make([]string, 0, len(counts))
+	for reason := range counts {
+		reasons = append(reasons, reason)
+	}
+
+	if len(latencies) > 0 {
+		sort.Slice(latencies, func(i, j int) bool { return latencies[i] < latencies[j] })
+		_, _ = fmt.Fprintf(l.out, "login latency: min %s, p50 %s, p95 %s, p99 %s, max %s\n",
+			latencies[0].Round(time.Microsecond),
+			percentile(latencies, 0.5).Round(time.Microsecond),
+			percentile(latencies, 0.95).Round(time.Microsecond),
+			percentile(latencies, 0.99).Round(time.Microsecond),
+			latencies[len(latencies)-1].Round(time.Microsecond),
+		)
+	}
+	_, _ = fmt.Fprintf(l.out, "packets in: %d (%.1f/s, %s/s), packets out: %d (%.1f/s, %s/s)\n",
+		stats.PacketsRead, float64(stats.PacketsRead)/seconds, formatBytes(float64(stats.BytesRead)/seconds),
+		stats.PacketsWritten, float64(stats.PacketsWritten)/seconds, formatBytes(float64(stats.BytesWritten)/seconds),
+	)
+	_, _ = fmt.Fprintf(l.out, "disconnects: %d\n", atomic.LoadInt64(&l.disconnects))
+
+	// most frequent reasons first
+	sort.Slice(reasons, func(i, j int) bool {
+		if counts[reasons[i]] != counts[reasons[j]] {
+			return counts[reasons[i]] > counts[reasons[j]]
+		}
+		return reasons[i] < reasons[j]
+	})
+	for _, reason := range reasons {
+		_, _ = fmt.Fprintf(l.out, "  %5d  %s\n", counts[reason], reason)
+	}
+	l.reportTickHealth(rconClient)
+}
+
+// reportTickHealth writes the tick health of the server, if an RCON client is given.
+func (l *loadtest) reportTickHealth(rconClient *rconClient) {
+	if rconClient == nil {
+		return
+	}
+	output, err := rconClient.Execute("tps")
+	if err != nil {
+		_, _ = fmt.Fprintf(l.out, "tick health: %s\n", err)
+		return
+	}
+	_, _ = fmt.Fprintf(l.out, "tick health: %s\n", output)
+}
+
+// percentile returns the value at the given percentile of the given sorted values.
+func percentile(sorted []time.Duration, p float64) time.Duration {
+	index := int(math.Ceil(p*float64(len(sorted)))) - 1
+	if index < 0 {
+		index = 0
+	}
+	return sorted[index]
+}
+
+func addStats(a, b client.Stats) client.Stats {
+	return client.Stats{
+		PacketsRead:    a.PacketsRead + b.PacketsRead,
+		PacketsWritten: a.PacketsWritten + b.PacketsWritten,
+		BytesRead:      a.BytesRead + b.BytesRead,
+		BytesWritten:   a.BytesWritten + b.BytesWritten,
+	}
+}
+
+// formatBytes formats the given amount of bytes with a binary unit.
+func formatBytes(bytes float64) string {
+	units := []string{"B", "KiB", "MiB", "GiB"}
+	unit := 0
+	for bytes >= 1024 && unit < len(units)-1 {
+		bytes /= 1024
+		unit++
+	}
+	return fmt.Sprintf("%.1f%s", bytes, units[unit])
+}
+
+// tickerOrNil returns a ticker with the given interval, or nil if the interval
+// is not positive.
+func tickerOrNil(interval time.Duration) *time.Ticker {
+	if interval <= 0 {
+		return nil
+	}
+	return time.NewTicker(interval)
+}
+
+// tickerC returns the channel of the given ticker, or nil if the ticker is nil,
+// so that it never fires in a select.
+func tickerC(t *time.Ticker) <-chan time.Time {
+	if t == nil {
+		return nil
+	}
+	return t.C
+}
+
+// rconClient executes commands on a server over RCON.
+type rconClient struct {
+	conn      net.Conn
+	rd        *bufio.Reader
+	requestID int32
+}
+
+// dialRCON connects to the RCON server with the given address and logs in with
+// the given password.
+func dialRCON(addr, password string) (*rconClient, error) {
+	conn, err := net.DialTimeout("tcp", addr, rconTimeout)
+	if err != nil {
+		return nil, fmt.Errorf("dial: %w", err)
+	}
+	c := &rconClient{
+		conn: conn,
+		rd:   bufio.NewReader(conn),
+	}
+
+	response, err := c.roundTrip(rcon.TypeLogin, password)
+	if err != nil {
+		_ = conn.Close()
+		return nil, fmt.Errorf("login: %w", err)
+	}
+	if response.RequestID != c.requestID {
+		_ = conn.Close()
+		return nil, errors.New("login: wrong password")
+	}
+	return c, nil
+}
+
+// Execute executes the given command and returns its output. Only the first
+// response packet is read, so the output must not be longer than
+// rcon.MaxResponseBodyLength.
+func (c *rconClient) Execute(command string) (string, error) {
+	response, err := c.roundTrip(rcon.TypeCommand, command)
+	if err != nil {
+		return "", err
+	}
+	return response.Body, nil
+}
+
+func (c *rconClient) roundTrip(typ int32, body string) (rcon.Packet, error) {
+	if err := c.conn.SetDeadline(time.Now().Add(rconTimeout)); err != nil {
+		return rcon.Packet{}, err
+	}
+	c.requestID++
+	if err := rcon.WritePacket(c.conn, rcon.Packet{
+		RequestID: c.requestID,
+		Type:      typ,
+		Body:      body,
+	}); err != nil {
+		return rcon.Packet{}, err
+	}
+	return rcon.ReadPacket(c.rd)
+}
+
+func (c *rconClient) Close() error {
+	return c.conn.Close()
+}
@@ -0,0 +1,126 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"io/ioutil"
+	"net"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/rs/zerolog"
+	"github.com/spf13/viper"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/tsatke/mcserver"
+	"github.com/tsatke/mcserver/config"
+	"github.com/tsatke/mcserver/network/capture"
+	"github.com/tsatke/mcserver/network/packet"
+)
+
+func TestLoadtest(t *testing.T) {
+	assert := assert.New(t)
+
+	captureDir, err := ioutil.TempDir("", "loadtest")
+	require.NoError(t, err)
+	defer func() { _ = os.RemoveAll(captureDir) }()
+
+	lis, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	rconLis, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+
+	vp := viper.New()
+	vp.Set(config.KeyGameWorld, "../../game/testdata/maps/world01")
+	vp.Set(config.KeyNetworkCompressionThreshold, 256)
+	vp.Set(config.KeyNetworkLoginRate, 100)
+	vp.Set(config.KeyNetworkLoginBurst, 100)
+	vp.Set(config.KeyNetworkCaptureDir, captureDir)
+	vp.Set(config.KeyRCONPassword, "secret")
+	cfg := config.New(vp)
+	cfg.ApplyDefaults()
+	srv, err := mcserver.New(cfg,
+		mcserver.WithListener(lis),
+		mcserver.WithRCONListener(rconLis),
+		mcserver.WithLogger(zerolog.Nop()),
+	)
+	require.NoError(t, err)
+
+	serverCtx, stopServer := context.WithCancel(context.Background())
+	defer stopServer()
+	go func() { _ = srv.Start(serverCtx) }()
+
+	var out bytes.Buffer
+	l := newLoadtest(loadtestConfig{
+		addr:           lis.Addr().String(),
+		players:        3,
+		ramp:           150 * time.Millisecond,
+		namePrefix:     "bot",
+		moveInterval:   50 * time.Millisecond,
+		chatInterval:   200 * time.Millisecond,
+		reportInterval: 500 * time.Millisecond,
+		rconAddr:       rconLis.Addr().String(),
+		rconPassword:   "secret",
+	}, zerolog.Nop(), &out)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 1500*time.Millisecond)
+	defer cancel()
+	require.NoError(t, l.run(ctx))
+
+	assert.EqualValues(3, l.loggedIn)
+	assert.EqualValues(0, l.online)
+	assert.EqualValues(0, l.loginFailures)
+	assert.EqualValues(0, l.disconnects)
+	assert.Len(l.loginLatencies, 3)
+	assert.Empty(l.disconnectReasons)
+	stats := l.stats()
+	assert.NotZero(stats.PacketsRead)
+	assert.NotZero(stats.BytesRead)
+	assert.NotZero(stats.PacketsWritten)
+	assert.NotZero(stats.BytesWritten)
+
+	assert.Contains(out.String(), "3 online, 3 logged in, 0 failed logins, 0 disconnects")
+	assert.Contains(out.String(), "logins: 3 succeeded, 0 failed\n")
+	assert.Contains(out.String(), "login latency: min ")
+	assert.Contains(out.String(), "disconnects: 0\n")
+	assert.Contains(out.String(), "tick health: ")
+
+	// the server recorded what every player sent
+	stopServer()
+	files, err := filepath.Glob(filepath.Join(captureDir, "*.mccap"))
+	require.NoError(t, err)
+	require.Len(t, files, 3)
+	for _, file := range files {
+		counts := countServerbound(t, file)
+		assert.Equal(1, counts[packet.ServerboundLoginStart{}.Name()], file)
+		assert.NotZero(counts[packet.ServerboundChatMessage{}.Name()], file)
+		assert.NotZero(counts[packet.ServerboundPlayerPositionAndRotation{}.Name()], file)
+	}
+}
+
+// countServerbound decodes the given capture and counts the serverbound packets
+// by their name.
+func countServerbound(t *testing.T, file string) map[string]int {
+	f, err := os.Open(file)
+	require.NoError(t, err)
+	defer func() { _ = f.Close() }()
+
+	rd, err := capture.NewReader(f)
+	require.NoError(t, err)
+	s := newSession()
+	counts := make(map[string]int)
+	for {
+		r, err := rd.Read()
+		if err == io.EOF {
+			return counts
+		}
+		require.NoError(t, err)
+		if dumped := s.decode(r); r.Direction == capture.DirectionServerbound {
+			counts[dumped.Name]++
+		}
+	}
+}
@@ -0,0 +1,241 @@
+package main
+
+import (
+	"bytes"
+	"encoding/binary"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/spf13/cobra"
+
+	"github.com/tsatke/mcserver/network/capture"
+	"github.com/tsatke/mcserver/network/packet"
+)
+
+var (
+	packetsJSON   bool
+	packetsFilter []string
+
+	packetsCmd = &cobra.Command{
+		Use:   "packets <capture file>",
+		Short: "Decode a packet capture into a readable dump",
+		Long: `Decode a packet capture that was recorded with network.capture_dir and print
+every packet with its fields. Packets with unknown IDs and packets that can't be
+decoded are printed as hex dump.`,
+		Args: cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			f, err := os.Open(args[0])
+			if err != nil {
+				return fmt.Errorf("open capture: %w", err)
+			}
+			defer func() { _ = f.Close() }()
+
+			return dumpPackets(f, cmd.OutOrStdout(), packetsJSON, packetsFilter)
+		},
+	}
+)
+
+func init() {
+	packetsCmd.Flags().BoolVar(&packetsJSON, "json", false, "print one JSON object per packet")
+	packetsCmd.Flags().StringSliceVar(&packetsFilter, "filter", nil, `only print packets with one of the given names (e.g. "Login Start" or ServerboundLoginStart) or directions (serverbound or clientbound)`)
+
+	rootCmd.AddCommand(packetsCmd)
+}
+
+// dumpedPacket is a decoded packet from a capture.
+type dumpedPacket struct {
+	Index     int       `json:"index"`
+	Time      time.Time `json:"time"`
+	Direction string    `json:"direction"`
+	Phase     string    `json:"phase"`
+	ID        string    `json:"id"`
+	// Name is the name of the packet, or empty if the ID is unknown.
+	Name string `json:"name,omitempty"`
+	// Packet is the decoded packet, or nil if the packet couldn't be decoded.
+	Packet interface{} `json:"packet,omitempty"`
+	// Payload is the payload of the packet, if the packet couldn't be decoded.
+	Payload []byte `json:"payload,omitempty"`
+	// Error is the error that occurred while decoding the packet.
+	Error string `json:"error,omitempty"`
+
+	typ reflect.Type
+}
+
+// dumpPackets reads all records from the given capture and writes them into the
+// given writer, either as text or as one JSON object per line. If filter is not
+// empty, only packets whose name or direction is in the filter are written.
+func dumpPackets(rd io.Reader, out io.Writer, asJSON bool, filter []string) error {
+	captureReader, err := capture.NewReader(rd)
+	if err != nil {
+		return err
+	}
+
+	s := newSession()
+	for index := 1; ; index++ {
+		r, err := captureReader.Read()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+
+		dumped := s.decode(r)
+		dumped.Index = index
+		if !dumped.matches(filter) {
+			continue
+		}
+
+		if asJSON {
+			err = json.NewEncoder(out).Encode(dumped)
+		} else {
+			_, err = io.WriteString(out, dumped.String())
+		}
+		if err != nil {
+			return fmt.Errorf("write: %w", err)
+		}
+	}
+}
+
+// session tracks the phase and protocol of a recorded connection, the same way
+// that the server does.
+type session struct {
+	phase    packet.Phase
+	protocol *packet.Protocol
+}
+
+func newSession() *session {
+	return &session{
+		phase:    packet.PhaseHandshaking,
+		protocol: packet.Latest,
+	}
+}
+
+// decode decodes the given record in the current phase, and transitions to the
+// next phase if the record is a packet that ends the current phase.
+func (s *session) decode(r capture.Record) dumpedPacket {
+	dumped := dumpedPacket{
+		Time:      r.Time,
+		Direction: r.Direction.String(),
+		Phase:     s.phase.String(),
+	}
+
+	payloadReader := bytes.NewReader(r.Data)
+	id, err := binary.ReadUvarint(payloadReader)
+	if err != nil {
+		dumped.Error = fmt.Sprintf("read packet id: %v", err)
+		dumped.Payload = r.Data
+		return dumped
+	}
+	dumped.ID = packet.ID(id).String()
+	dumped.Payload = r.Data[len(r.Data)-payloadReader.Len():]
+
+	if r.Direction == capture.DirectionServerbound {
+		dumped.typ = s.protocol.ServerboundType(s.phase, packet.ID(id))
+	} else {
+		dumped.typ = s.protocol.ClientboundType(s.phase, packet.ID(id))
+	}
+	if dumped.typ == nil {
+		return dumped
+	}
+
+	created := reflect.New(dumped.typ).Interface()
+	dumped.Name = created.(packet.Packet).Name()
+	decodable, ok := created.(packet.Serverbound)
+	if !ok {
+		dumped.Error = "decoding is not supported for this packet"
+		return dumped
+	}
+	if err := decodable.DecodeFrom(payloadReader); err != nil {
+		dumped.Error = err.Error()
+		return dumped
+	}
+	dumped.Packet = created
+	dumped.Payload = nil
+
+	s.transition(created)
+	return dumped
+}
+
+func (s *session) transition(p interface{}) {
+	switch p := p.(type) {
+	case *packet.ServerboundHandshake:
+		if version, ok := packet.LookupVersion(p.ProtocolVersion); ok {
+			s.protocol = version.Protocol
+		}
+		switch p.NextState {
+		case packet.NextStateStatus:
+			s.phase = packet.PhaseStatus
+		case packet.NextStateLogin:
+			s.phase = packet.PhaseLogin
+		}
+	case *packet.ClientboundLoginSuccess:
+		s.phase = packet.PhasePlay
+	}
+}
+
+// matches determines whether this packet matches any of the given filters, which
+// are names or directions. An empty filter matches all packets.
+func (p dumpedPacket) matches(filter []string) bool {
+	if len(filter) == 0 {
+		return true
+	}
+	for _, f := range filter {
+		if strings.EqualFold(f, p.Direction) ||
+			(p.Name != "" && strings.EqualFold(f, p.Name)) ||
+			(p.typ != nil && strings.EqualFold(f, p.typ.Name())) {
+			return true
+		}
+	}
+	return false
+}
+
+// String formats this packet as header line, followed by one line per field,
+// or a hex dump of the payload.
+func (p dumpedPacket) String() string {
+	var buf strings.Builder
+
+	name := p.Name
+	if name == "" {
+		name = "unknown packet"
+	}
+	_, _ = fmt.Fprintf(&buf, "#%d %s %s %s %s %s\n",
+		p.Index, p.Time.Format("15:04:05.000"), p.Direction, p.Phase, p.ID, name)
+	if p.Error != "" {
+		_, _ = fmt.Fprintf(&buf, "    error: %s\n", p.Error)
+	}
+
+	if p.Packet != nil {
+		value := reflect.ValueOf(p.Packet).Elem()
+		for i := 0; i < value.NumField(); i++ {
+			_, _ = fmt.Fprintf(&buf, "    %s: %s\n", value.Type().Field(i).Name, formatValue(value.Field(i)))
+		}
+	} else if len(p.Payload) > 0 {
+		for _, line := range strings.SplitAfter(strings.TrimSuffix(hex.Dump(p.Payload), "\n"), "\n") {
+			buf.WriteString("    " + line)
+		}
+		buf.WriteString("\n")
+	}
+	return buf.String()
+}
+
+// formatValue formats a single packet field.
+func formatValue(v reflect.Value) string {
+	switch {
+	case v.Kind() == reflect.String:
+		return strconv.Quote(v.String())
+	case v.Kind() == reflect.Slice && v.Type().Elem().Kind() == reflect.Uint8:
+		return hex.EncodeToString(v.Bytes())
+	}
+	if stringer, ok := v.Interface().(fmt.Stringer); ok {
+		return stringer.String()
+	}
+	return fmt.Sprintf("%+v", v.Interface())
+}
@@ -0,0 +1,171 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/tsatke/mcserver/network/capture"
+	"github.com/tsatke/mcserver/network/packet"
+)
+
+// recordedLogin returns a capture of a client that logs in with compression and
+// chats once, followed by a packet with an unknown ID.
+func recordedLogin(t *testing.T) []byte {
+	var buf bytes.Buffer
+	w, err := capture.NewWriter(&buf)
+	require.NoError(t, err)
+
+	start := time.Unix(1600000000, 0)
+	write := func(direction capture.Direction, phase packet.Phase, data []byte) {
+		require.NoError(t, w.Write(capture.Record{
+			Direction: direction,
+			Phase:     phase,
+			Time:      start,
+			Data:      data,
+		}))
+		start = start.Add(time.Millisecond)
+	}
+	serverbound := func(phase packet.Phase, p packet.Encodable) {
+		data, err := packet.Latest.EncodeServerboundData(p)
+		require.NoError(t, err)
+		write(capture.DirectionServerbound, phase, data)
+	}
+	clientbound := func(phase packet.Phase, p packet.Clientbound) {
+		data, err := packet.Latest.EncodeData(p)
+		require.NoError(t, err)
+		write(capture.DirectionClientbound, phase, data)
+	}
+
+	serverbound(packet.PhaseHandshaking, packet.ServerboundHandshake{
+		ProtocolVersion: packet.LatestVersionNumber,
+		ServerAddress:   "localhost",
+		ServerPort:      25565,
+		NextState:       packet.NextStateLogin,
+	})
+	serverbound(packet.PhaseLogin, packet.ServerboundLoginStart{
+		Username: "Steve",
+	})
+	clientbound(packet.PhaseLogin, packet.ClientboundSetCompression{
+		Threshold: 256,
+	})
+	clientbound(packet.PhaseLogin, packet.ClientboundLoginSuccess{
+		UUID:     uuid.MustParse("b50ad385-829d-3141-a216-7e7d7539ba7f"),
+		Username: "Steve",
+	})
+	serverbound(packet.PhasePlay, packet.ServerboundChatMessage{
+		Message: "hello",
+	})
+	clientbound(packet.PhasePlay, packet.ClientboundKeepAlive{
+		KeepAliveID: 42,
+	})
+	write(capture.DirectionClientbound, packet.PhasePlay, []byte{0x7F, 0xCA, 0xFE})
+
+	return buf.Bytes()
+}
+
+func TestDumpPacketsText(t *testing.T) {
+	assert := assert.New(t)
+
+	var out bytes.Buffer
+	require.NoError(t, dumpPackets(bytes.NewReader(recordedLogin(t)), &out, false, nil))
+
+	var headers []string
+	for _, line := range strings.Split(out.String(), "\n") {
+		if strings.HasPrefix(line, "#") {
+			// cut off the index and the local time
+			fields := strings.SplitN(line, " ", 3)
+			headers = append(headers, fields[2])
+		}
+	}
+	assert.Equal([]string{
+		"serverbound Handshaking 0x00 Handshake",
+		"serverbound Login 0x00 Login Start",
+		"clientbound Login 0x03 Set Compression",
+		"clientbound Login 0x02 Login Success",
+		"serverbound Play 0x03 Chat Message (serverbound)",
+		"clientbound Play 0x1f Keep Alive (clientbound)",
+		"clientbound Play 0x7f unknown packet",
+	}, headers)
+
+	assert.Contains(out.String(), "    Username: \"Steve\"\n")
+	assert.Contains(out.String(), "    Threshold: 256\n")
+	assert.Contains(out.String(), "    Message: \"hello\"\n")
+	assert.Contains(out.String(), "    00000000  ca fe")
+}
+
+func TestDumpPacketsJSONFilter(t *testing.T) {
+	assert := assert.New(t)
+
+	var out bytes.Buffer
+	require.NoError(t, dumpPackets(bytes.NewReader(recordedLogin(t)), &out, true, []string{"Set Compression", "ServerboundChatMessage", "login success"}))
+
+	var dumped []dumpedPacket
+	dec := json.NewDecoder(&out)
+	for dec.More() {
+		var p dumpedPacket
+		require.NoError(t, dec.Decode(&p))
+		dumped = append(dumped, p)
+	}
+	require.Len(t, dumped, 3)
+
+	assert.Equal(3, dumped[0].Index)
+	assert.Equal("Set Compression", dumped[0].Name)
+	assert.Equal("Login", dumped[0].Phase)
+	assert.Equal(map[string]interface{}{"Threshold": float64(256)}, dumped[0].Packet)
+
+	assert.Equal(4, dumped[1].Index)
+	assert.Equal("Login Success", dumped[1].Name)
+	assert.Equal("Login", dumped[1].Phase)
+
+	assert.Equal(5, dumped[2].Index)
+	assert.Equal("Chat Message (serverbound)", dumped[2].Name)
+	assert.Equal("Play", dumped[2].Phase)
+	assert.Equal("serverbound", dumped[2].Direction)
+	assert.Equal(map[string]interface{}{"Message": "hello"}, dumped[2].Packet)
+	assert.Empty(dumped[2].Error)
+}
+
+func TestSessionTransition(t *testing.T) {
+	tests := []struct {
+		name    string
+		packets []interface{}
+		want    packet.Phase
+	}{
+		{"initial", nil, packet.PhaseHandshaking},
+		{"status", []interface{}{
+			&packet.ServerboundHandshake{ProtocolVersion: packet.LatestVersionNumber, NextState: packet.NextStateStatus},
+		}, packet.PhaseStatus},
+		{"login", []interface{}{
+			&packet.ServerboundHandshake{ProtocolVersion: packet.LatestVersionNumber, NextState: packet.NextStateLogin},
+		}, packet.PhaseLogin},
+		{"compression", []interface{}{
+			&packet.ServerboundHandshake{ProtocolVersion: packet.LatestVersionNumber, NextState: packet.NextStateLogin},
+			&packet.ClientboundSetCompression{Threshold: 256},
+		}, packet.PhaseLogin},
+		{"play", []interface{}{
+			&packet.ServerboundHandshake{ProtocolVersion: packet.LatestVersionNumber, NextState: packet.NextStateLogin},
+			&packet.ClientboundSetCompression{Threshold: 256},
+			&packet.ClientboundLoginSuccess{Username: "Steve"},
+		}, packet.PhasePlay},
+		{"unsupported version", []interface{}{
+			&packet.ServerboundHandshake{ProtocolVersion: 47, NextState: packet.NextStateLogin},
+		}, packet.PhaseLogin},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := newSession()
+			for _, p := range tt.packets {
+				s.transition(p)
+			}
+			assert.Equal(t, tt.want, s.phase)
+			assert.Same(t, packet.Latest, s.protocol)
+		})
+	}
+}
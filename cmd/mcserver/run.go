@@ -16,7 +16,7 @@ import (
 	"github.com/tsatke/mcserver/config"
 )
 
-func run(_ io.Reader, stdout io.Writer) error {
+func run(stdin io.Reader, stdout io.Writer) error {
 	cfg, err := loadConfig()
 	if err != nil {
 		return fmt.Errorf("load config: %w", err)
@@ -34,9 +34,14 @@ func run(_ io.Reader, stdout io.Writer) error {
 	ctx, cancel := context.WithCancel(context.Background())
 	defer cancel()
 
-	srv, err := mcserver.New(cfg,
+	opts := []mcserver.Option{
 		mcserver.WithLogger(log),
-	)
+	}
+	if cfg.ProxyProtocol() {
+		opts = append(opts, mcserver.WithProxyProtocol())
+	}
+
+	srv, err := mcserver.New(cfg, opts...)
 	if err != nil {
 		return fmt.Errorf("create server: %w", err)
 	}
@@ -62,6 +67,8 @@ func run(_ io.Reader, stdout io.Writer) error {
 		os.Exit(2)
 	}()
 
+	go runConsole(ctx, log, srv, stdin, stdout)
+
 	return srv.Start(ctx)
 }
 
@@ -0,0 +1,146 @@
+package mcserver
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/tsatke/mcserver/game"
+	"github.com/tsatke/mcserver/game/chat"
+	"github.com/tsatke/mcserver/game/command"
+)
+
+// RegisterCommand registers the given command with the game, see game.RegisterCommand.
+// Commands can be registered before the server is started. If there already is a
+// command with the same name, it is replaced.
+func (s *MCServer) RegisterCommand(node *command.Node) {
+	s.commandsLock.Lock()
+	defer s.commandsLock.Unlock()
+
+	if s.game != nil {
+		s.game.RegisterCommand(node)
+		return
+	}
+	s.commands = append(s.commands, node)
+}
+
+// ExecuteCommand executes the given command line, like it is entered into the console,
+// with the highest permission level, and returns the feedback of the command. A leading
+// slash is ignored. This fails with ErrNotStarted if the server is not ready yet, and
+// with a *command.SyntaxError if the command line can't be parsed.
+func (s *MCServer) ExecuteCommand(_ context.Context, line string) (string, error) {
+	select {
+	case <-s.ready:
+	default:
+		return "", ErrNotStarted
+	}
+
+	s.log.Info().
+		Str("command", line).
+		Msg("executing command")
+	return s.game.ExecuteCommand(strings.TrimPrefix(strings.TrimSpace(line), "/"))
+}
+
+func (s *MCServer) registerDefaultCommands() {
+	s.RegisterCommand(command.Literal("list").
+		Executes(s.commandList))
+	s.RegisterCommand(command.Literal("kick").
+		Requires(command.PermissionLevelAdmin).
+		Then(command.Argument("targets", command.Players()).
+			Suggests(s.suggestPlayerNames).
+			Executes(s.commandKick).
+			Then(command.Argument("reason", command.GreedyString()).
+				Executes(s.commandKick))))
+	s.RegisterCommand(command.Literal("tps").
+		Requires(command.PermissionLevelGamemaster).
+		Executes(s.commandTPS))
+	s.RegisterCommand(command.Literal("say").
+		Requires(command.PermissionLevelGamemaster).
+		Then(command.Argument("message", command.GreedyString()).
+			Executes(s.commandSay)))
+}
+
+// sendFeedback sends the given text as feedback of a command to the source of the
+// given context.
+func sendFeedback(ctx *command.Context, format string, args ...interface{}) {
+	ctx.Source.SendMessage(chat.Chat{
+		ChatFragment: chat.ChatFragment{
+			Text: fmt.Sprintf(format, args...),
+		},
+	})
+}
+
+// suggestPlayerNames suggests the names of all online players. The game is only
+// created when the server is started, so this can't be referenced directly.
+func (s *MCServer) suggestPlayerNames(ctx *command.Context, text string) []string {
+	return s.game.SuggestPlayerNames(ctx, text)
+}
+
+// commandList lists the names of all online players.
+//
+//	list
+func (s *MCServer) commandList(ctx *command.Context) error {
+	players := s.game.Players()
+	names := make([]string, len(players))
+	for i, p := range players {
+		names[i] = p.Name()
+	}
+	sort.Strings(names)
+
+	sendFeedback(ctx, "There are %d of a max of %d players online: %s",
+		len(players), s.config.MaxPlayers(), strings.Join(names, ", "))
+	return nil
+}
+
+// commandKick disconnects the selected players, with an optional reason.
+//
+//	kick <targets> [<reason>]
+func (s *MCServer) commandKick(ctx *command.Context) error {
+	players, err := s.game.SelectPlayers(ctx.Source, ctx.Entity("targets"))
+	if err != nil {
+		return err
+	}
+
+	reason := "Kicked by an operator"
+	if val, ok := ctx.Argument("reason"); ok {
+		reason = val.(string)
+	}
+	for _, p := range players {
+		s.game.DisconnectWithReason(p, chat.Chat{
+			ChatFragment: chat.ChatFragment{
+				Text: reason,
+			},
+		})
+		sendFeedback(ctx, "Kicked %s: %s", p.Name(), reason)
+	}
+	return nil
+}
+
+// commandTPS reports how well the tick loop keeps up, see game.TickStats.
+//
+//	tps
+func (s *MCServer) commandTPS(ctx *command.Context) error {
+	stats := s.game.TickStats()
+	sendFeedback(ctx, "TPS: %.2f, mean tick: %s, max tick: %s, skipped ticks: %d",
+		stats.TPS, stats.MeanTickDuration, stats.MaxTickDuration, stats.SkippedTicks)
+	return nil
+}
+
+// commandSay sends the given message to all players, like the say command of the
+// vanilla server.
+//
+//	say <message>
+func (s *MCServer) commandSay(ctx *command.Context) error {
+	message := chat.Chat{
+		ChatFragment: chat.ChatFragment{
+			Text: "[" + ctx.Source.Name() + "] " + ctx.String("message"),
+		},
+	}
+	s.game.BroadcastMessage(message, game.ChatPositionSystem)
+	if _, ok := s.game.SourcePlayer(ctx.Source); !ok {
+		// players see the message in the chat
+		ctx.Source.SendMessage(message)
+	}
+	return nil
+}
@@ -2,18 +2,64 @@ package config
 
 import (
 	"net"
+	"strings"
+	"time"
 
 	"github.com/rs/zerolog"
 	"github.com/spf13/viper"
+
+	"github.com/tsatke/mcserver/game/chat"
+	"github.com/tsatke/mcserver/network"
 )
 
 // Config keys according to
 // https://crushit.atlassian.net/wiki/x/AQBiCQ
 const (
-	KeyServerAddress = "server.address"
-	KeyServerPort    = "server.port"
-	KeyGameWorld     = "game.world"
-	KeyLogLevel      = "log.level"
+	KeyServerAddress           = "server.address"
+	KeyServerPort              = "server.port"
+	KeyServerOnlineMode        = "server.online_mode"
+	KeyServerSessionServer     = "server.session_server"
+	KeyServerMaxPlayers        = "server.max_players"
+	KeyServerBannedPlayers     = "server.banned_players"
+	KeyServerOperators         = "server.operators"
+	KeyServerOpPermissionLevel = "server.op_permission_level"
+	KeyServerProxyProtocol     = "server.proxy_protocol"
+	KeyServerMOTD              = "server.motd"
+	KeyServerIcon              = "server.icon"
+
+	KeyServerForwarding       = "server.forwarding"
+	KeyServerForwardingSecret = "server.forwarding_secret"
+
+	KeyQueryEnabled = "query.enabled"
+	KeyQueryPort    = "query.port"
+
+	KeyRCONEnabled  = "rcon.enabled"
+	KeyRCONPort     = "rcon.port"
+	KeyRCONPassword = "rcon.password"
+
+	KeyGameWorld = "game.world"
+	KeyLogLevel  = "log.level"
+
+	KeyNetworkCompressionThreshold = "network.compression_threshold"
+	KeyNetworkQueueSize            = "network.queue_size"
+	KeyNetworkOverflowPolicy       = "network.overflow_policy"
+	KeyNetworkCaptureDir           = "network.capture_dir"
+	KeyNetworkMaxConnections       = "network.max_connections"
+	KeyNetworkMaxConnectionsPerIP  = "network.max_connections_per_ip"
+	KeyNetworkLoginRate            = "network.login_rate"
+	KeyNetworkLoginBurst           = "network.login_burst"
+	KeyNetworkHandshakeTimeout     = "network.handshake_timeout"
+	KeyNetworkStatusTimeout        = "network.status_timeout"
+	KeyNetworkLoginTimeout         = "network.login_timeout"
+	KeyNetworkIdleTimeout          = "network.idle_timeout"
+	KeyNetworkLoginPluginTimeout   = "network.login_plugin_timeout"
+)
+
+// Player info forwarding modes, see Config.Forwarding.
+const (
+	ForwardingNone       = "none"
+	ForwardingBungeeCord = "bungeecord"
+	ForwardingVelocity   = "velocity"
 )
 
 type Config struct {
@@ -33,10 +79,42 @@ func (c Config) ApplyDefaults() {
 	*/
 	c.vp.SetDefault(KeyServerAddress, "localhost")
 	c.vp.SetDefault(KeyServerPort, 25565)
+	c.vp.SetDefault(KeyServerOnlineMode, false)
+	c.vp.SetDefault(KeyServerSessionServer, "https://sessionserver.mojang.com")
+	c.vp.SetDefault(KeyServerMaxPlayers, 100)
+	c.vp.SetDefault(KeyServerBannedPlayers, []string{})
+	c.vp.SetDefault(KeyServerOperators, []string{})
+	c.vp.SetDefault(KeyServerOpPermissionLevel, 4)
+	c.vp.SetDefault(KeyServerProxyProtocol, false)
+	c.vp.SetDefault(KeyServerMOTD, "Timi loves Tanni §c❤")
+	c.vp.SetDefault(KeyServerIcon, "server-icon.png")
+	c.vp.SetDefault(KeyServerForwarding, ForwardingNone)
+	c.vp.SetDefault(KeyServerForwardingSecret, "")
+
+	c.vp.SetDefault(KeyQueryEnabled, false)
+	c.vp.SetDefault(KeyQueryPort, 25565)
+
+	c.vp.SetDefault(KeyRCONEnabled, false)
+	c.vp.SetDefault(KeyRCONPort, 25575)
+	c.vp.SetDefault(KeyRCONPassword, "")
 
 	c.vp.SetDefault(KeyGameWorld, "world")
 
 	c.vp.SetDefault(KeyLogLevel, "info")
+
+	c.vp.SetDefault(KeyNetworkCompressionThreshold, 256)
+	c.vp.SetDefault(KeyNetworkQueueSize, network.DefaultQueueSize)
+	c.vp.SetDefault(KeyNetworkOverflowPolicy, "disconnect")
+	c.vp.SetDefault(KeyNetworkCaptureDir, "")
+	c.vp.SetDefault(KeyNetworkMaxConnections, 2048)
+	c.vp.SetDefault(KeyNetworkMaxConnectionsPerIP, 128)
+	c.vp.SetDefault(KeyNetworkLoginRate, 1.0)
+	c.vp.SetDefault(KeyNetworkLoginBurst, 8)
+	c.vp.SetDefault(KeyNetworkHandshakeTimeout, 10*time.Second)
+	c.vp.SetDefault(KeyNetworkStatusTimeout, 10*time.Second)
+	c.vp.SetDefault(KeyNetworkLoginTimeout, 30*time.Second)
+	c.vp.SetDefault(KeyNetworkIdleTimeout, 30*time.Second)
+	c.vp.SetDefault(KeyNetworkLoginPluginTimeout, 10*time.Second)
 }
 
 func (c Config) LogLevel() zerolog.Level {
@@ -60,6 +138,198 @@ func (c Config) ServerAddr() string {
 	return net.JoinHostPort(c.vp.GetString(KeyServerAddress), c.vp.GetString(KeyServerPort))
 }
 
+// OnlineMode determines whether the server encrypts connections of players
+// that are logging in.
+func (c Config) OnlineMode() bool {
+	return c.vp.GetBool(KeyServerOnlineMode)
+}
+
+// SessionServer is the base URL of the session server that players are
+// authenticated with in online mode.
+func (c Config) SessionServer() string {
+	return c.vp.GetString(KeyServerSessionServer)
+}
+
+// MaxPlayers is the amount of players that can be connected at the same time.
+// Players that try to log in while the server is full are rejected.
+func (c Config) MaxPlayers() int {
+	return c.vp.GetInt(KeyServerMaxPlayers)
+}
+
+// BannedPlayers are the names or UUIDs of players that are not allowed to
+// log in.
+func (c Config) BannedPlayers() []string {
+	return c.vp.GetStringSlice(KeyServerBannedPlayers)
+}
+
+// Operators are the names or UUIDs of players that can use the commands of
+// their permission level, see OpPermissionLevel.
+func (c Config) Operators() []string {
+	return c.vp.GetStringSlice(KeyServerOperators)
+}
+
+// OpPermissionLevel is the permission level of all operators, from 1 to 4, like
+// op-permission-level of the vanilla server. Players that are not operators
+// have level 0.
+func (c Config) OpPermissionLevel() int {
+	return c.vp.GetInt(KeyServerOpPermissionLevel)
+}
+
+// ProxyProtocol determines whether the server runs behind a load balancer that
+// sends a PROXY protocol header at the start of every connection.
+func (c Config) ProxyProtocol() bool {
+	return c.vp.GetBool(KeyServerProxyProtocol)
+}
+
+// MOTD is the message of the day, which is displayed below the server name in the
+// server list of the client. This is either a JSON chat component or text with §
+// formatting codes.
+func (c Config) MOTD() chat.Chat {
+	return chat.Parse(c.vp.GetString(KeyServerMOTD))
+}
+
+// Icon is the path of the PNG image with 64x64 pixels that is displayed next to
+// the server in the server list of the client. If the file doesn't exist, no icon
+// is displayed.
+func (c Config) Icon() string {
+	return c.vp.GetString(KeyServerIcon)
+}
+
+// Forwarding is the mode in which a proxy that the server runs behind forwards
+// the identity of players. This is one of ForwardingNone, ForwardingBungeeCord
+// (legacy forwarding in the handshake) and ForwardingVelocity (modern forwarding
+// in a login plugin message). Unknown values are treated as ForwardingNone.
+func (c Config) Forwarding() string {
+	switch mode := strings.ToLower(c.vp.GetString(KeyServerForwarding)); mode {
+	case ForwardingBungeeCord, ForwardingVelocity:
+		return mode
+	}
+	return ForwardingNone
+}
+
+// ForwardingSecret is the secret that is shared with a Velocity proxy, with
+// which the forwarded player information is signed.
+func (c Config) ForwardingSecret() string {
+	return c.vp.GetString(KeyServerForwardingSecret)
+}
+
+// QueryEnabled determines whether the server answers GameSpy4 queries over UDP,
+// which are used by server lists and monitoring tools.
+func (c Config) QueryEnabled() bool {
+	return c.vp.GetBool(KeyQueryEnabled)
+}
+
+// QueryAddr is the UDP address that the server listens on for queries. The host
+// is the same as the one of ServerAddr.
+func (c Config) QueryAddr() string {
+	return net.JoinHostPort(c.vp.GetString(KeyServerAddress), c.vp.GetString(KeyQueryPort))
+}
+
+// RCONEnabled determines whether the server accepts remote console connections,
+// over which commands can be executed.
+func (c Config) RCONEnabled() bool {
+	return c.vp.GetBool(KeyRCONEnabled)
+}
+
+// RCONAddr is the TCP address that the server listens on for remote console
+// connections. The host is the same as the one of ServerAddr.
+func (c Config) RCONAddr() string {
+	return net.JoinHostPort(c.vp.GetString(KeyServerAddress), c.vp.GetString(KeyRCONPort))
+}
+
+// RCONPassword is the password that remote console clients must log in with. The
+// remote console can't be enabled without a password.
+func (c Config) RCONPassword() string {
+	return c.vp.GetString(KeyRCONPassword)
+}
+
 func (c Config) GameWorld() string {
 	return c.vp.GetString(KeyGameWorld)
 }
+
+// CompressionThreshold is the minimum size of a packet in bytes, before it is
+// compressed. A negative value disables compression.
+func (c Config) CompressionThreshold() int {
+	return c.vp.GetInt(KeyNetworkCompressionThreshold)
+}
+
+// QueueSize is the amount of packets that can be queued for writing on a
+// connection, before the OverflowPolicy is applied.
+func (c Config) QueueSize() int {
+	return c.vp.GetInt(KeyNetworkQueueSize)
+}
+
+// OverflowPolicy determines what happens if a client doesn't read packets fast
+// enough and its write queue is full. It is either "disconnect" or "block".
+func (c Config) OverflowPolicy() network.OverflowPolicy {
+	policy := network.OverflowDisconnect
+	switch c.vp.GetString(KeyNetworkOverflowPolicy) {
+	case "block":
+		policy = network.OverflowBlock
+	}
+	return policy
+}
+
+// CaptureDir is the directory that the packets of every connection are recorded
+// in, one capture file per connection. If this is empty, packets are not recorded.
+func (c Config) CaptureDir() string {
+	return c.vp.GetString(KeyNetworkCaptureDir)
+}
+
+// MaxConnections is the amount of connections that the server accepts at the same
+// time. Connections that exceed this are closed immediately. Zero or a negative
+// value disables the limit.
+func (c Config) MaxConnections() int {
+	return c.vp.GetInt(KeyNetworkMaxConnections)
+}
+
+// MaxConnectionsPerIP is the amount of connections that the server accepts from
+// a single IP at the same time. Zero or a negative value disables the limit. The
+// limit doesn't apply if the server runs behind a proxy that forwards players,
+// since all players connect from the IP of the proxy.
+func (c Config) MaxConnectionsPerIP() int {
+	return c.vp.GetInt(KeyNetworkMaxConnectionsPerIP)
+}
+
+// LoginRate is the amount of login attempts per second that are allowed from a
+// single IP, on average. Zero or a negative value disables the limit.
+func (c Config) LoginRate() float64 {
+	return c.vp.GetFloat64(KeyNetworkLoginRate)
+}
+
+// LoginBurst is the amount of login attempts that are allowed from a single IP
+// in quick succession, before the LoginRate applies.
+func (c Config) LoginBurst() int {
+	return c.vp.GetInt(KeyNetworkLoginBurst)
+}
+
+// HandshakeTimeout is the time that a client has to send the handshake after it
+// connected. Zero disables the timeout.
+func (c Config) HandshakeTimeout() time.Duration {
+	return c.vp.GetDuration(KeyNetworkHandshakeTimeout)
+}
+
+// StatusTimeout is the time that a client has to complete the server list ping
+// after the handshake. Zero disables the timeout.
+func (c Config) StatusTimeout() time.Duration {
+	return c.vp.GetDuration(KeyNetworkStatusTimeout)
+}
+
+// LoginTimeout is the time that a client has to complete the login after the
+// handshake, including authentication. Zero disables the timeout.
+func (c Config) LoginTimeout() time.Duration {
+	return c.vp.GetDuration(KeyNetworkLoginTimeout)
+}
+
+// IdleTimeout is the maximum time between two packets of a player that is playing.
+// Players that don't send a packet in time are disconnected. Zero disables the
+// timeout.
+func (c Config) IdleTimeout() time.Duration {
+	return c.vp.GetDuration(KeyNetworkIdleTimeout)
+}
+
+// LoginPluginTimeout is the time that the login plugin handlers have to complete
+// during the login of a player, including the time that the client needs to answer.
+func (c Config) LoginPluginTimeout() time.Duration {
+	return c.vp.GetDuration(KeyNetworkLoginPluginTimeout)
+}
@@ -0,0 +1,89 @@
+package mcserver
+
+import (
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/subtle"
+	"crypto/x509"
+	"fmt"
+
+	"github.com/tsatke/mcserver/network"
+	"github.com/tsatke/mcserver/network/packet"
+)
+
+const (
+	// keyBits is the size of the RSA key that is used in the encryption handshake.
+	// The vanilla client only supports 1024 bit keys.
+	keyBits = 1024
+	// verifyTokenLength is the length of the random verify token that is sent
+	// in the encryption request.
+	verifyTokenLength = 4
+	// sharedSecretLength is the length of the shared secret that the client has
+	// to send in the encryption response.
+	sharedSecretLength = 16
+)
+
+// generateKey generates the RSA keypair that is used for the encryption handshake
+// and stores it in the server.
+func (s *MCServer) generateKey() error {
+	key, err := rsa.GenerateKey(rand.Reader, keyBits)
+	if err != nil {
+		return fmt.Errorf("generate key: %w", err)
+	}
+	publicKey, err := x509.MarshalPKIXPublicKey(&key.PublicKey)
+	if err != nil {
+		return fmt.Errorf("marshal public key: %w", err)
+	}
+
+	s.privateKey = key
+	s.publicKey = publicKey
+	return nil
+}
+
+// enableEncryption performs the encryption handshake with the client. It sends an
+// encryption request, waits for the encryption response and enables encryption
+// on the given connection. The decrypted shared secret is returned.
+func (s *MCServer) enableEncryption(conn *network.Conn) ([]byte, error) {
+	verifyToken := make([]byte, verifyTokenLength)
+	if _, err := rand.Read(verifyToken); err != nil {
+		return nil, fmt.Errorf("generate verify token: %w", err)
+	}
+
+	if err := conn.WritePacket(packet.ClientboundEncryptionRequest{
+		ServerID:    "",
+		PublicKey:   s.publicKey,
+		VerifyToken: verifyToken,
+	}); err != nil {
+		return nil, fmt.Errorf("write encryption request: %w", err)
+	}
+
+	p, err := conn.ReadPacket()
+	if err != nil {
+		return nil, fmt.Errorf("read encryption response: %w", err)
+	}
+	response, ok := p.(*packet.ServerboundEncryptionResponse)
+	if !ok {
+		return nil, fmt.Errorf("require encryption response, but got %s", p.Name())
+	}
+
+	decryptedToken, err := rsa.DecryptPKCS1v15(rand.Reader, s.privateKey, response.VerifyToken)
+	if err != nil {
+		return nil, fmt.Errorf("decrypt verify token: %w", err)
+	}
+	if subtle.ConstantTimeCompare(verifyToken, decryptedToken) != 1 {
+		return nil, fmt.Errorf("verify token mismatch")
+	}
+
+	sharedSecret, err := rsa.DecryptPKCS1v15(rand.Reader, s.privateKey, response.SharedSecret)
+	if err != nil {
+		return nil, fmt.Errorf("decrypt shared secret: %w", err)
+	}
+	if len(sharedSecret) != sharedSecretLength {
+		return nil, fmt.Errorf("shared secret must be %d bytes long, but was %d", sharedSecretLength, len(sharedSecret))
+	}
+
+	if err := conn.EnableEncryption(sharedSecret); err != nil {
+		return nil, fmt.Errorf("enable encryption: %w", err)
+	}
+	return sharedSecret, nil
+}
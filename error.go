@@ -0,0 +1,17 @@
+package mcserver
+
+type Error string
+
+func (e Error) Error() string { return string(e) }
+
+const (
+	// ErrNotStarted indicates, that the server has not been started yet, or is not
+	// ready yet, so the requested operation can't be performed.
+	ErrNotStarted Error = "server not started"
+	// ErrNoRCONPassword indicates, that the remote console is enabled, but no
+	// password is configured.
+	ErrNoRCONPassword Error = "rcon enabled without password"
+	// ErrInvalidOpPermissionLevel indicates, that the configured permission level
+	// of operators is not between 1 and 4.
+	ErrInvalidOpPermissionLevel Error = "op permission level must be between 1 and 4"
+)
@@ -0,0 +1,146 @@
+package mcserver
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/json"
+	"fmt"
+	"net"
+	"strings"
+
+	"github.com/google/uuid"
+
+	"github.com/tsatke/mcserver/auth"
+	"github.com/tsatke/mcserver/game/id"
+	"github.com/tsatke/mcserver/network/packet"
+)
+
+const (
+	// velocityChannel is the channel of the login plugin request with which the
+	// player information is requested from a Velocity proxy.
+	velocityChannel = "velocity:player_info"
+	// velocityForwardingVersion is the version of the modern forwarding format
+	// that is requested from Velocity. Newer versions append data that we don't need.
+	velocityForwardingVersion = 1
+)
+
+// forwardedPlayer is the information about a player that a proxy forwarded.
+type forwardedPlayer struct {
+	// IP is the IP address of the player.
+	IP net.IP
+	// Profile is the profile of the player that was authenticated by the proxy.
+	Profile auth.Profile
+}
+
+// parseBungeeCordForwarding parses the player information that a BungeeCord proxy
+// with legacy forwarding appends to the server address of the handshake, separated
+// by null bytes. The forwarded data contains the IP and UUID of the player and
+// optionally the profile properties as JSON, but not the name, which is the username
+// from the login start packet.
+//
+//	<host>\x00<ip>\x00<uuid without dashes>[\x00<properties>]
+func parseBungeeCordForwarding(serverAddress string) (forwardedPlayer, error) {
+	parts := strings.Split(serverAddress, "\x00")
+	if len(parts) != 3 && len(parts) != 4 {
+		return forwardedPlayer{}, fmt.Errorf("expected 3 or 4 parts in server address, but got %d", len(parts))
+	}
+
+	ip := net.ParseIP(parts[1])
+	if ip == nil {
+		return forwardedPlayer{}, fmt.Errorf("invalid ip %q", parts[1])
+	}
+	playerUUID, err := uuid.Parse(parts[2])
+	if err != nil {
+		return forwardedPlayer{}, fmt.Errorf("parse uuid: %w", err)
+	}
+
+	var properties []auth.Property
+	if len(parts) == 4 {
+		if err := json.Unmarshal([]byte(parts[3]), &properties); err != nil {
+			return forwardedPlayer{}, fmt.Errorf("unmarshal properties: %w", err)
+		}
+	}
+
+	return forwardedPlayer{
+		IP: ip,
+		Profile: auth.Profile{
+			UUID:       playerUUID,
+			Properties: properties,
+		},
+	}, nil
+}
+
+// requestVelocityForwarding requests the player information from a Velocity proxy
+// with modern forwarding, using a login plugin request. The proxy signs the
+// information with the forwarding secret, so the response can't be forged by a
+// client that connects directly.
+func (s *MCServer) requestVelocityForwarding(ctx context.Context, plugins *loginPluginSession) (forwardedPlayer, error) {
+	var forwarded forwardedPlayer
+	err := plugins.run(ctx, s.config.LoginPluginTimeout(), auth.Profile{}, map[id.ID]LoginPluginHandler{
+		id.ParseID(velocityChannel): func(ctx context.Context, requester LoginPluginRequester) error {
+			response, err := requester.RequestAndWait([]byte{velocityForwardingVersion})
+			if err != nil {
+				return err
+			}
+			if !response.Understood {
+				return fmt.Errorf("client didn't understand the request, probably not connected through velocity")
+			}
+
+			forwarded, err = parseVelocityForwarding(response.Data, []byte(s.config.ForwardingSecret()))
+			return err
+		},
+	})
+	return forwarded, err
+}
+
+// parseVelocityForwarding verifies and parses the player information that a Velocity
+// proxy sent. The data starts with the HMAC-SHA256 of the remaining data, keyed with
+// the forwarding secret.
+func parseVelocityForwarding(data, secret []byte) (forwarded forwardedPlayer, err error) {
+	if len(data) < sha256.Size {
+		return forwardedPlayer{}, fmt.Errorf("data too short for signature")
+	}
+	signature, payload := data[:sha256.Size], data[sha256.Size:]
+	mac := hmac.New(sha256.New, secret)
+	_, _ = mac.Write(payload)
+	if !hmac.Equal(signature, mac.Sum(nil)) {
+		return forwardedPlayer{}, fmt.Errorf("invalid signature, check the forwarding secret")
+	}
+
+	defer func() {
+		if rec := recover(); rec != nil {
+			if recErr, ok := rec.(error); ok {
+				err = fmt.Errorf("decode: %w", recErr)
+			} else {
+				err = fmt.Errorf("decode: %v", rec)
+			}
+		}
+	}()
+
+	dec := packet.Decoder{Rd: bytes.NewReader(payload)}
+	if version := dec.ReadVarInt("version"); version < velocityForwardingVersion {
+		return forwardedPlayer{}, fmt.Errorf("unsupported forwarding version %d", version)
+	}
+	address := dec.ReadString("address")
+	ip := net.ParseIP(address)
+	if ip == nil {
+		return forwardedPlayer{}, fmt.Errorf("invalid ip %q", address)
+	}
+	forwarded.IP = ip
+	forwarded.Profile.UUID = dec.ReadUUID("uuid")
+	forwarded.Profile.Name = dec.ReadString("username")
+	// no preallocation, since the count is sent by the client
+	for i, n := 0, dec.ReadVarInt("property count"); i < n; i++ {
+		property := auth.Property{
+			Name:  dec.ReadString("property name"),
+			Value: dec.ReadString("property value"),
+		}
+		if dec.ReadBoolean("property is signed") {
+			property.Signature = dec.ReadString("property signature")
+		}
+		forwarded.Profile.Properties = append(forwarded.Profile.Properties, property)
+	}
+	return forwarded, nil
+}
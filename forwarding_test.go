@@ -0,0 +1,134 @@
+package mcserver
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/sha256"
+	"net"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/tsatke/mcserver/auth"
+	"github.com/tsatke/mcserver/network/packet"
+)
+
+func TestParseBungeeCordForwarding(t *testing.T) {
+	playerUUID := uuid.MustParse("069a79f4-44e9-4726-a5be-fca90e38aaf5")
+
+	tests := []struct {
+		name    string
+		address string
+		want    forwardedPlayer
+		wantErr bool
+	}{
+		{
+			"without properties",
+			"localhost\x00203.0.113.7\x00069a79f444e94726a5befca90e38aaf5",
+			forwardedPlayer{
+				IP:      net.ParseIP("203.0.113.7"),
+				Profile: auth.Profile{UUID: playerUUID},
+			},
+			false,
+		},
+		{
+			"with properties",
+			"localhost\x002001:db8::1\x00069a79f444e94726a5befca90e38aaf5\x00" + `[{"name":"textures","value":"abc","signature":"def"}]`,
+			forwardedPlayer{
+				IP: net.ParseIP("2001:db8::1"),
+				Profile: auth.Profile{
+					UUID:       playerUUID,
+					Properties: []auth.Property{{Name: "textures", Value: "abc", Signature: "def"}},
+				},
+			},
+			false,
+		},
+		{
+			"not forwarded",
+			"localhost",
+			forwardedPlayer{},
+			true,
+		},
+		{
+			"invalid uuid",
+			"localhost\x00203.0.113.7\x00notauuid",
+			forwardedPlayer{},
+			true,
+		},
+		{
+			"invalid properties",
+			"localhost\x00203.0.113.7\x00069a79f444e94726a5befca90e38aaf5\x00{",
+			forwardedPlayer{},
+			true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert := assert.New(t)
+
+			got, err := parseBungeeCordForwarding(tt.address)
+			if tt.wantErr {
+				assert.Error(err)
+				return
+			}
+			assert.NoError(err)
+			assert.Equal(tt.want, got)
+		})
+	}
+}
+
+// velocityForwardingData creates the data that a Velocity proxy sends in response
+// to the player info request, signed with the given secret.
+func velocityForwardingData(secret string, playerUUID uuid.UUID, name string) []byte {
+	var payload bytes.Buffer
+	enc := packet.Encoder{W: &payload}
+	enc.WriteVarInt("version", 1)
+	enc.WriteString("address", "203.0.113.7")
+	enc.WriteUUID("uuid", playerUUID)
+	enc.WriteString("username", name)
+	enc.WriteVarInt("property count", 2)
+	enc.WriteString("property name", "textures")
+	enc.WriteString("property value", "abc")
+	enc.WriteBoolean("property is signed", true)
+	enc.WriteString("property signature", "def")
+	enc.WriteString("property name", "unsigned")
+	enc.WriteString("property value", "ghi")
+	enc.WriteBoolean("property is signed", false)
+
+	mac := hmac.New(sha256.New, []byte(secret))
+	_, _ = mac.Write(payload.Bytes())
+	return append(mac.Sum(nil), payload.Bytes()...)
+}
+
+func TestParseVelocityForwarding(t *testing.T) {
+	assert := assert.New(t)
+
+	playerUUID := uuid.MustParse("069a79f4-44e9-4726-a5be-fca90e38aaf5")
+	data := velocityForwardingData("secret", playerUUID, "Notch")
+
+	got, err := parseVelocityForwarding(data, []byte("secret"))
+	assert.NoError(err)
+	assert.Equal(forwardedPlayer{
+		IP: net.ParseIP("203.0.113.7"),
+		Profile: auth.Profile{
+			UUID: playerUUID,
+			Name: "Notch",
+			Properties: []auth.Property{
+				{Name: "textures", Value: "abc", Signature: "def"},
+				{Name: "unsigned", Value: "ghi"},
+			},
+		},
+	}, got)
+
+	_, err = parseVelocityForwarding(data, []byte("wrong secret"))
+	assert.Error(err)
+	_, err = parseVelocityForwarding(data[:10], []byte("secret"))
+	assert.Error(err)
+
+	// valid signature, but truncated payload
+	mac := hmac.New(sha256.New, []byte("secret"))
+	_, _ = mac.Write([]byte{1})
+	_, err = parseVelocityForwarding(append(mac.Sum(nil), 1), []byte("secret"))
+	assert.Error(err)
+}
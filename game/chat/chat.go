@@ -1,5 +1,7 @@
 package chat
 
+import "strings"
+
 type (
 	Chat struct {
 		ChatFragment
@@ -16,3 +18,29 @@ type (
 		Insertion     string `json:"insertion,omitempty"`
 	}
 )
+
+// PlainText returns the text of this chat and all its extra fragments
+// without any formatting.
+func (c Chat) PlainText() string {
+	var buf strings.Builder
+	buf.WriteString(c.Text)
+	for _, fragment := range c.Extra {
+		buf.WriteString(fragment.Text)
+	}
+	return buf.String()
+}
+
+// WithoutColors returns a copy of this chat, in which no fragment has a color.
+// Other formatting, such as bold text, is kept.
+func (c Chat) WithoutColors() Chat {
+	c.Color = ""
+	if len(c.Extra) > 0 {
+		extra := make([]ChatFragment, len(c.Extra))
+		for i, fragment := range c.Extra {
+			fragment.Color = ""
+			extra[i] = fragment
+		}
+		c.Extra = extra
+	}
+	return c
+}
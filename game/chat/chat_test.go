@@ -0,0 +1,24 @@
+package chat
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestWithoutColors(t *testing.T) {
+	c := Chat{
+		ChatFragment: ChatFragment{Text: "a", Color: "red"},
+		Extra: []ChatFragment{
+			{Text: "b", Color: "gold", Bold: true},
+		},
+	}
+	assert.Equal(t, Chat{
+		ChatFragment: ChatFragment{Text: "a"},
+		Extra: []ChatFragment{
+			{Text: "b", Bold: true},
+		},
+	}, c.WithoutColors())
+	// the original is not modified
+	assert.Equal(t, "gold", c.Extra[0].Color)
+}
@@ -0,0 +1,100 @@
+package chat
+
+import (
+	"encoding/json"
+	"strings"
+)
+
+// legacyColors are the colors of the legacy formatting codes 0-9 and a-f.
+var legacyColors = map[rune]string{
+	'0': "black",
+	'1': "dark_blue",
+	'2': "dark_green",
+	'3': "dark_aqua",
+	'4': "dark_red",
+	'5': "dark_purple",
+	'6': "gold",
+	'7': "gray",
+	'8': "dark_gray",
+	'9': "blue",
+	'a': "green",
+	'b': "aqua",
+	'c': "red",
+	'd': "light_purple",
+	'e': "yellow",
+	'f': "white",
+}
+
+// Parse parses the given text, which is either a JSON chat component, or plain
+// text with legacy formatting codes (see ParseLegacy). Text that starts with '{'
+// but isn't a valid chat component is treated as plain text.
+func Parse(text string) Chat {
+	if strings.HasPrefix(strings.TrimSpace(text), "{") {
+		var c Chat
+		if err := json.Unmarshal([]byte(text), &c); err == nil {
+			return c
+		}
+	}
+	return ParseLegacy(text)
+}
+
+// ParseLegacy converts text with legacy formatting codes, like "§cred §lbold",
+// into a chat. A color code resets all formats, like in the vanilla client, and
+// §r resets the color and all formats. Unknown codes are dropped.
+func ParseLegacy(text string) Chat {
+	var fragments []ChatFragment
+	var current ChatFragment
+	var buf strings.Builder
+	flush := func() {
+		if buf.Len() > 0 {
+			current.Text = buf.String()
+			fragments = append(fragments, current)
+			buf.Reset()
+		}
+	}
+
+	runes := []rune(text)
+	for i := 0; i < len(runes); i++ {
+		if runes[i] != '§' || i+1 == len(runes) {
+			buf.WriteRune(runes[i])
+			continue
+		}
+		i++
+		flush()
+
+		code := runes[i]
+		if code >= 'A' && code <= 'Z' {
+			code += 'a' - 'A'
+		}
+		if color, ok := legacyColors[code]; ok {
+			current = ChatFragment{Color: color}
+			continue
+		}
+		switch code {
+		case 'k':
+			current.Obfuscated = true
+		case 'l':
+			current.Bold = true
+		case 'm':
+			current.Strikethrough = true
+		case 'n':
+			current.Underlined = true
+		case 'o':
+			current.Italic = true
+		case 'r':
+			current = ChatFragment{}
+		}
+	}
+	flush()
+
+	switch {
+	case len(fragments) == 0:
+		return Chat{}
+	case len(fragments) == 1:
+		return Chat{ChatFragment: fragments[0]}
+	case fragments[0] == ChatFragment{Text: fragments[0].Text}:
+		// extra fragments inherit the format of the root, so the root must not have one
+		return Chat{ChatFragment: fragments[0], Extra: fragments[1:]}
+	}
+	return Chat{Extra: fragments}
+}
@@ -0,0 +1,64 @@
+package chat
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParse(t *testing.T) {
+	tests := []struct {
+		name string
+		text string
+		want Chat
+	}{
+		{
+			"plain",
+			"A Minecraft Server",
+			Chat{ChatFragment: ChatFragment{Text: "A Minecraft Server"}},
+		},
+		{
+			"json",
+			`{"text":"hello","color":"gold","extra":[{"text":" world","bold":true}]}`,
+			Chat{
+				ChatFragment: ChatFragment{Text: "hello", Color: "gold"},
+				Extra:        []ChatFragment{{Text: " world", Bold: true}},
+			},
+		},
+		{
+			"invalid json",
+			`{"text":`,
+			Chat{ChatFragment: ChatFragment{Text: `{"text":`}},
+		},
+		{
+			"legacy unformatted first",
+			"Timi loves Tanni §c❤",
+			Chat{
+				ChatFragment: ChatFragment{Text: "Timi loves Tanni "},
+				Extra:        []ChatFragment{{Text: "❤", Color: "red"}},
+			},
+		},
+		{
+			"legacy formats",
+			"§6§lGold bold§r plain §ncolor §Aresets",
+			Chat{
+				Extra: []ChatFragment{
+					{Text: "Gold bold", Color: "gold", Bold: true},
+					{Text: " plain "},
+					{Text: "color ", Underlined: true},
+					{Text: "resets", Color: "green"},
+				},
+			},
+		},
+		{
+			"legacy single",
+			"§ewelcome§",
+			Chat{ChatFragment: ChatFragment{Text: "welcome§", Color: "yellow"}},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, Parse(tt.text))
+		})
+	}
+}
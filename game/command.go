@@ -0,0 +1,206 @@
+package game
+
+import (
+	"math"
+	"math/rand"
+	"strings"
+	"unicode/utf16"
+
+	"github.com/tsatke/mcserver/game/chat"
+	"github.com/tsatke/mcserver/game/command"
+	"github.com/tsatke/mcserver/network/packet"
+)
+
+// playerSource is the command source of a player that typed a command in the chat.
+type playerSource struct {
+	g *Game
+	p *Player
+}
+
+func (s playerSource) Name() string         { return s.p.name }
+func (s playerSource) PermissionLevel() int { return s.p.PermissionLevel() }
+func (s playerSource) SendMessage(message chat.Chat) {
+	s.g.SendMessage(s.p, message, ChatPositionSystem)
+}
+
+// consoleSource is the command source of ExecuteCommand, which collects the feedback
+// of the command.
+type consoleSource struct {
+	output []string
+}
+
+func (s *consoleSource) Name() string         { return "Server" }
+func (s *consoleSource) PermissionLevel() int { return command.PermissionLevelOwner }
+func (s *consoleSource) SendMessage(message chat.Chat) {
+	s.output = append(s.output, message.PlainText())
+}
+
+// RegisterCommand registers the given command, see command.Dispatcher.Register. The
+// updated commands are sent to all connected players.
+func (g *Game) RegisterCommand(node *command.Node) {
+	g.commands.Register(node)
+	for _, p := range g.Players() {
+		g.sendCommands(p)
+	}
+}
+
+// ExecuteCommand executes the given command, which must not have a leading slash,
+// with the highest permission level, and returns the feedback of the command, one
+// line per message. The source is called "Server", like the console of the vanilla
+// server.
+func (g *Game) ExecuteCommand(line string) (string, error) {
+	source := &consoleSource{}
+	err := g.commands.Execute(source, line)
+	return strings.Join(source.output, "\n"), err
+}
+
+// SourcePlayer returns the player that executed a command with the given source, or
+// false if the command was not executed by a player.
+func (g *Game) SourcePlayer(source command.Source) (*Player, bool) {
+	if s, ok := source.(playerSource); ok {
+		return s.p, true
+	}
+	return nil, false
+}
+
+// SelectPlayers returns the connected players that the given selector selects, if it
+// is used by the given source. Players are the only entities, so @e selects all
+// players. If no player is selected, ErrPlayerNotFound is returned.
+func (g *Game) SelectPlayers(source command.Source, selector command.EntitySelector) ([]*Player, error) {
+	var selected []*Player
+	switch selector.Selector {
+	case command.SelectorSelf:
+		if p, ok := g.SourcePlayer(source); ok {
+			selected = append(selected, p)
+		}
+	case command.SelectorAllPlayers, command.SelectorAllEntities:
+		selected = g.Players()
+	case command.SelectorRandomPlayer:
+		if players := g.Players(); len(players) > 0 {
+			selected = append(selected, players[rand.Intn(len(players))])
+		}
+	case command.SelectorNearestPlayer:
+		if p := g.nearestPlayer(source); p != nil {
+			selected = append(selected, p)
+		}
+	default:
+		for _, p := range g.Players() {
+			if p.UUID == selector.UUID || strings.EqualFold(p.name, selector.Name) {
+				selected = append(selected, p)
+			}
+		}
+	}
+
+	if len(selected) == 0 {
+		return nil, ErrPlayerNotFound
+	}
+	return selected, nil
+}
+
+// nearestPlayer returns the player that is nearest to the given source, which is
+// the source itself, if it is a player. For other sources, the origin is used.
+func (g *Game) nearestPlayer(source command.Source) *Player {
+	if p, ok := g.SourcePlayer(source); ok {
+		return p
+	}
+
+	var nearest *Player
+	nearestDistance := math.Inf(1)
+	for _, p := range g.Players() {
+		p.Lock()
+		distance := p.Pos[0]*p.Pos[0] + p.Pos[1]*p.Pos[1] + p.Pos[2]*p.Pos[2]
+		p.Unlock()
+		if distance < nearestDistance {
+			nearest, nearestDistance = p, distance
+		}
+	}
+	return nearest
+}
+
+// SuggestPlayerNames is a command.SuggestionProvider that suggests the names of all
+// connected players.
+func (g *Game) SuggestPlayerNames(*command.Context, string) []string {
+	players := g.Players()
+	names := make([]string, len(players))
+	for i, p := range players {
+		names[i] = p.name
+	}
+	return names
+}
+
+func (g *Game) registerDefaultCommands() {
+	g.commands.Register(command.Literal("help").Executes(g.commandHelp))
+}
+
+// commandHelp lists the usage of all commands that the source can use.
+func (g *Game) commandHelp(ctx *command.Context) error {
+	for _, usage := range g.commands.Usage(ctx.Source) {
+		ctx.Source.SendMessage(chat.Chat{
+			ChatFragment: chat.ChatFragment{
+				Text: "/" + usage,
+			},
+		})
+	}
+	return nil
+}
+
+// sendCommands sends the commands that the given player can use, and the permission
+// level of the player, which the client uses to decide whether the player can switch
+// game modes with F3+F4.
+func (g *Game) sendCommands(p *Player) {
+	g.WritePacket(p, packet.ClientboundEntityStatus{
+		EntityID: 1,                              // same EID as when joining
+		Status:   int8(24 + p.PermissionLevel()), // permission levels 0 to 4 are statuses 24 to 28
+	})
+	g.WritePacket(p, g.commands.DeclareCommands(playerSource{g, p}))
+}
+
+// executePlayerCommand executes the given command, without the leading slash, that
+// the given player typed in the chat. Errors are sent to the player.
+func (g *Game) executePlayerCommand(source *Player, line string) {
+	g.log.Info().
+		Str("player", source.name).
+		Str("command", line).
+		Msg("executing command")
+
+	if err := g.commands.Execute(playerSource{g, source}, line); err != nil {
+		g.SendMessage(source, chat.Chat{
+			ChatFragment: chat.ChatFragment{
+				Text:  err.Error(),
+				Color: "red",
+			},
+		}, ChatPositionSystem)
+	}
+}
+
+// processServerboundTabComplete answers the request for suggestions of the given
+// player. This is called without holding the lock of the player, since suggestion
+// providers may need it.
+func (g *Game) processServerboundTabComplete(source *Player, p *packet.ServerboundTabComplete) {
+	input := p.Text
+	offset := 0
+	if strings.HasPrefix(input, "/") {
+		input = input[1:]
+		offset = 1
+	}
+
+	start, suggestions := g.commands.Suggest(playerSource{g, source}, input)
+	matches := make([]packet.TabCompleteMatch, len(suggestions))
+	for i, suggestion := range suggestions {
+		matches[i] = packet.TabCompleteMatch{
+			Match: suggestion,
+		}
+	}
+	// the client counts in UTF-16 code units, like Java strings do
+	g.WritePacket(source, packet.ClientboundTabComplete{
+		TransactionID: p.TransactionID,
+		Start:         utf16Len(input[:start]) + offset,
+		Length:        utf16Len(input[start:]),
+		Matches:       matches,
+	})
+}
+
+// utf16Len returns the length of the given string in UTF-16 code units.
+func utf16Len(s string) int {
+	return len(utf16.Encode([]rune(s)))
+}
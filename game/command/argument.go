@@ -0,0 +1,203 @@
+package command
+
+import (
+	"fmt"
+	"math"
+
+	"github.com/tsatke/mcserver/game/id"
+	"github.com/tsatke/mcserver/network/packet"
+)
+
+// ArgumentType parses the value of an argument node. The parser and its properties
+// are sent to clients, which parse arguments themselves to highlight them, so each
+// type must correspond to a parser of the vanilla client.
+type ArgumentType interface {
+	// Parse reads the value of the argument from the given reader. Errors wrap
+	// ErrInvalidArgument.
+	Parse(rd *Reader) (interface{}, error)
+	// Parser returns the identifier of the parser, such as brigadier:integer.
+	Parser() id.ID
+	// EncodeProperties writes the properties of the parser, as they are sent in the
+	// Declare Commands packet.
+	EncodeProperties(enc packet.Encoder)
+}
+
+// Suggester is implemented by argument types that can suggest values by themselves.
+type Suggester interface {
+	// Suggest returns suggestions for the given text, which is the input of the
+	// argument so far.
+	Suggest(text string) []string
+}
+
+// Flags of the properties of the number parsers.
+const (
+	numberHasMin = 0x01
+	numberHasMax = 0x02
+)
+
+// IntegerArgument is an argument type for 32 bit integers within a range.
+type IntegerArgument struct {
+	Min, Max int
+}
+
+// Integer returns an argument type for any 32 bit integer.
+func Integer() IntegerArgument {
+	return IntegerBetween(math.MinInt32, math.MaxInt32)
+}
+
+// IntegerBetween returns an argument type for integers within the given range, both
+// inclusive.
+func IntegerBetween(min, max int) IntegerArgument {
+	return IntegerArgument{
+		Min: min,
+		Max: max,
+	}
+}
+
+// Parse implements ArgumentType.
+func (a IntegerArgument) Parse(rd *Reader) (interface{}, error) {
+	start := rd.Cursor()
+	val, err := rd.ReadInt()
+	if err != nil {
+		return nil, err
+	}
+	if val < a.Min || val > a.Max {
+		rd.cursor = start
+		return nil, fmt.Errorf("%w: integer must be within %d and %d, found %d", ErrInvalidArgument, a.Min, a.Max, val)
+	}
+	return val, nil
+}
+
+// Parser implements ArgumentType.
+func (IntegerArgument) Parser() id.ID { return id.ParseID("brigadier:integer") }
+
+// EncodeProperties implements ArgumentType.
+func (a IntegerArgument) EncodeProperties(enc packet.Encoder) {
+	var flags byte
+	if a.Min != math.MinInt32 {
+		flags |= numberHasMin
+	}
+	if a.Max != math.MaxInt32 {
+		flags |= numberHasMax
+	}
+	enc.WriteUbyte("flags", flags)
+	if flags&numberHasMin != 0 {
+		enc.WriteInt("min", int32(a.Min))
+	}
+	if flags&numberHasMax != 0 {
+		enc.WriteInt("max", int32(a.Max))
+	}
+}
+
+// FloatArgument is an argument type for 32 bit floating point numbers within a range.
+type FloatArgument struct {
+	Min, Max float64
+}
+
+// Float returns an argument type for any 32 bit floating point number.
+func Float() FloatArgument {
+	return FloatBetween(-math.MaxFloat32, math.MaxFloat32)
+}
+
+// FloatBetween returns an argument type for floating point numbers within the given
+// range, both inclusive.
+func FloatBetween(min, max float64) FloatArgument {
+	return FloatArgument{
+		Min: min,
+		Max: max,
+	}
+}
+
+// Parse implements ArgumentType.
+func (a FloatArgument) Parse(rd *Reader) (interface{}, error) {
+	start := rd.Cursor()
+	val, err := rd.ReadFloat()
+	if err != nil {
+		return nil, err
+	}
+	if val < a.Min || val > a.Max {
+		rd.cursor = start
+		return nil, fmt.Errorf("%w: float must be within %v and %v, found %v", ErrInvalidArgument, a.Min, a.Max, val)
+	}
+	return val, nil
+}
+
+// Parser implements ArgumentType.
+func (FloatArgument) Parser() id.ID { return id.ParseID("brigadier:float") }
+
+// EncodeProperties implements ArgumentType.
+func (a FloatArgument) EncodeProperties(enc packet.Encoder) {
+	var flags byte
+	if a.Min != -math.MaxFloat32 {
+		flags |= numberHasMin
+	}
+	if a.Max != math.MaxFloat32 {
+		flags |= numberHasMax
+	}
+	enc.WriteUbyte("flags", flags)
+	if flags&numberHasMin != 0 {
+		enc.WriteFloat("min", float32(a.Min))
+	}
+	if flags&numberHasMax != 0 {
+		enc.WriteFloat("max", float32(a.Max))
+	}
+}
+
+// StringKind determines how much of the input a StringArgument reads.
+type StringKind int
+
+// Available string kinds.
+const (
+	// StringWord reads a single word of the characters that are allowed in unquoted
+	// strings.
+	StringWord StringKind = iota
+	// StringQuotablePhrase reads a single word, or a phrase in quotes.
+	StringQuotablePhrase
+	// StringGreedyPhrase reads all of the remaining input.
+	StringGreedyPhrase
+)
+
+// StringArgument is an argument type for strings.
+type StringArgument struct {
+	Kind StringKind
+}
+
+// Word returns an argument type for a single word.
+func Word() StringArgument { return StringArgument{Kind: StringWord} }
+
+// QuotablePhrase returns an argument type for a single word, or a phrase in quotes.
+func QuotablePhrase() StringArgument { return StringArgument{Kind: StringQuotablePhrase} }
+
+// GreedyString returns an argument type for all of the remaining input, which must
+// be the last argument of a command.
+func GreedyString() StringArgument { return StringArgument{Kind: StringGreedyPhrase} }
+
+// Parse implements ArgumentType.
+func (a StringArgument) Parse(rd *Reader) (interface{}, error) {
+	var val string
+	switch a.Kind {
+	case StringGreedyPhrase:
+		val = rd.Remaining()
+		rd.cursor = len(rd.input)
+	case StringQuotablePhrase:
+		if rd.CanRead() && isQuote(rd.Peek()) {
+			// quotes make empty strings explicit
+			return rd.ReadQuotedString()
+		}
+		val = rd.ReadUnquotedString()
+	default:
+		val = rd.ReadUnquotedString()
+	}
+	if val == "" {
+		return nil, fmt.Errorf("%w: expected string", ErrInvalidArgument)
+	}
+	return val, nil
+}
+
+// Parser implements ArgumentType.
+func (StringArgument) Parser() id.ID { return id.ParseID("brigadier:string") }
+
+// EncodeProperties implements ArgumentType.
+func (a StringArgument) EncodeProperties(enc packet.Encoder) {
+	enc.WriteVarInt("kind", int(a.Kind))
+}
@@ -0,0 +1,82 @@
+package command
+
+import "github.com/tsatke/mcserver/game/chat"
+
+// Permission levels, as they are used by the vanilla server. A source can use all
+// nodes that require a permission level up to its own.
+const (
+	PermissionLevelAll        = 0
+	PermissionLevelModerator  = 1
+	PermissionLevelGamemaster = 2
+	PermissionLevelAdmin      = 3
+	PermissionLevelOwner      = 4
+)
+
+// Source is what executes a command, such as a player or the console.
+type Source interface {
+	// Name is the name of the source, such as the name of the player.
+	Name() string
+	// PermissionLevel determines which nodes the source can use, see Node.Requires.
+	PermissionLevel() int
+	// SendMessage sends feedback of a command to the source.
+	SendMessage(message chat.Chat)
+}
+
+// Context is passed to the handler of a command, and holds the parsed arguments.
+type Context struct {
+	Source Source
+	// Input is the complete command that was entered, without a leading slash.
+	Input string
+
+	arguments map[string]interface{}
+}
+
+func newContext(source Source, input string) *Context {
+	return &Context{
+		Source:    source,
+		Input:     input,
+		arguments: make(map[string]interface{}),
+	}
+}
+
+// with returns a copy of this context, that additionally holds the given argument.
+// Copies are used, because parsing tries multiple branches of the command tree.
+func (c *Context) with(name string, value interface{}) *Context {
+	arguments := make(map[string]interface{}, len(c.arguments)+1)
+	for k, v := range c.arguments {
+		arguments[k] = v
+	}
+	arguments[name] = value
+	return &Context{
+		Source:    c.Source,
+		Input:     c.Input,
+		arguments: arguments,
+	}
+}
+
+// Argument returns the parsed value of the argument with the given name, and whether
+// the argument was present in the input. Optional arguments are not present if the
+// command was executed by a node before them.
+func (c *Context) Argument(name string) (interface{}, bool) {
+	val, ok := c.arguments[name]
+	return val, ok
+}
+
+// The following methods return the value of the argument with the given name, and
+// panic if there is no such argument of the respective type, which is a programming
+// error.
+
+// Int returns the value of an Integer argument.
+func (c *Context) Int(name string) int { return c.arguments[name].(int) }
+
+// Float returns the value of a Float argument.
+func (c *Context) Float(name string) float64 { return c.arguments[name].(float64) }
+
+// String returns the value of a Word, QuotablePhrase or GreedyString argument.
+func (c *Context) String(name string) string { return c.arguments[name].(string) }
+
+// Entity returns the value of an Entity, Entities, Player or Players argument.
+func (c *Context) Entity(name string) EntitySelector { return c.arguments[name].(EntitySelector) }
+
+// BlockPos returns the value of a BlockPos argument.
+func (c *Context) BlockPos(name string) BlockPosition { return c.arguments[name].(BlockPosition) }
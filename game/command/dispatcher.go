@@ -0,0 +1,245 @@
+// Package command implements a command framework that is compatible with Brigadier,
+// the command library of the vanilla client and server.
+//
+// Commands are registered as a tree of literal and argument nodes with a Dispatcher,
+// which parses and executes input, suggests completions, and encodes the tree for the
+// Declare Commands packet, so that clients can highlight commands.
+//
+//	dispatcher.Register(command.Literal("kick").
+//		Requires(command.PermissionLevelAdmin).
+//		Then(command.Argument("targets", command.Players()).
+//			Executes(kick).
+//			Then(command.Argument("reason", command.GreedyString()).
+//				Executes(kick))))
+package command
+
+import (
+	"bytes"
+	"errors"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/tsatke/mcserver/network/packet"
+)
+
+// Dispatcher holds the command tree. It is safe for concurrent use.
+type Dispatcher struct {
+	lock sync.RWMutex
+	root *Node
+}
+
+// NewDispatcher creates a dispatcher without any commands.
+func NewDispatcher() *Dispatcher {
+	return &Dispatcher{
+		root: &Node{},
+	}
+}
+
+// Register adds the given node to the root of the tree. If there already is a command
+// with the same name, it is replaced.
+func (d *Dispatcher) Register(node *Node) {
+	d.lock.Lock()
+	defer d.lock.Unlock()
+
+	for i, child := range d.root.children {
+		if strings.EqualFold(child.name, node.name) {
+			d.root.children[i] = node
+			return
+		}
+	}
+	d.root.children = append(d.root.children, node)
+}
+
+// Execute parses the given input, which must not have a leading slash, and calls the
+// handler of the command. If the input can't be parsed, a *SyntaxError is returned,
+// otherwise the error of the handler.
+func (d *Dispatcher) Execute(source Source, input string) error {
+	d.lock.RLock()
+	node, ctx, err := d.parse(d.root, *NewReader(input), newContext(source, input))
+	d.lock.RUnlock()
+	if err != nil {
+		return err
+	}
+	return node.handler(ctx)
+}
+
+// parse parses the input of the children of the given node, and returns the node at
+// which the input ends, together with the context that holds the arguments. All
+// possible branches of the tree are tried, and if none of them matches, the error of
+// the branch that got furthest is returned.
+func (d *Dispatcher) parse(node *Node, rd Reader, ctx *Context) (*Node, *Context, error) {
+	var furthest *SyntaxError
+	fail := func(err error, cursor int) {
+		var syntaxErr *SyntaxError
+		if !errors.As(err, &syntaxErr) {
+			syntaxErr = &SyntaxError{
+				Err:    err,
+				Input:  rd.input,
+				Cursor: cursor,
+			}
+		}
+		if furthest == nil || syntaxErr.Cursor > furthest.Cursor {
+			furthest = syntaxErr
+		}
+	}
+
+	for _, child := range node.relevantChildren(rd, ctx.Source) {
+		childRd := rd
+		value, err := child.parse(&childRd)
+		if err != nil {
+			fail(err, childRd.Cursor())
+			continue
+		}
+		if childRd.CanRead() && childRd.Peek() != ' ' {
+			fail(ErrTrailingData, childRd.Cursor())
+			continue
+		}
+
+		childCtx := ctx
+		if !child.isLiteral() {
+			childCtx = ctx.with(child.name, value)
+		}
+		if !childRd.CanRead() {
+			if child.handler == nil {
+				fail(ErrIncompleteCommand, childRd.Cursor())
+				continue
+			}
+			return child, childCtx, nil
+		}
+
+		childRd.Skip()
+		executable, executableCtx, err := d.parse(child, childRd, childCtx)
+		if err != nil {
+			fail(err, childRd.Cursor())
+			continue
+		}
+		return executable, executableCtx, nil
+	}
+
+	if furthest == nil {
+		// nothing matches the next word, so point at its end
+		wordRd := rd
+		wordRd.ReadWord()
+		if node == d.root {
+			fail(ErrUnknownCommand, wordRd.Cursor())
+		} else {
+			fail(ErrIncorrectArgument, wordRd.Cursor())
+		}
+	}
+	return nil, nil, furthest
+}
+
+// Suggest returns completions for the last word of the given input, which must not
+// have a leading slash, and the index in the input at which the completions start.
+func (d *Dispatcher) Suggest(source Source, input string) (int, []string) {
+	d.lock.RLock()
+	defer d.lock.RUnlock()
+
+	start, suggestions := d.suggest(d.root, *NewReader(input), newContext(source, input))
+	sort.Strings(suggestions)
+	unique := suggestions[:0]
+	for i, suggestion := range suggestions {
+		if i == 0 || suggestion != suggestions[i-1] {
+			unique = append(unique, suggestion)
+		}
+	}
+	return start, unique
+}
+
+// suggest returns the suggestions for the children of the given node at the position
+// of the given reader, or for nodes further down the tree, if the input continues
+// after a child. Only the suggestions that start furthest in the input are kept.
+func (d *Dispatcher) suggest(node *Node, rd Reader, ctx *Context) (int, []string) {
+	start := rd.Cursor()
+	var suggestions []string
+	for _, child := range node.permittedChildren(ctx.Source) {
+		suggestions = append(suggestions, child.suggest(ctx, rd.Remaining())...)
+	}
+
+	for _, child := range node.relevantChildren(rd, ctx.Source) {
+		childRd := rd
+		value, err := child.parse(&childRd)
+		if err != nil || !childRd.CanRead() || childRd.Peek() != ' ' {
+			continue
+		}
+		childRd.Skip()
+
+		childCtx := ctx
+		if !child.isLiteral() {
+			childCtx = ctx.with(child.name, value)
+		}
+		childStart, childSuggestions := d.suggest(child, childRd, childCtx)
+		switch {
+		case childStart > start:
+			start, suggestions = childStart, childSuggestions
+		case childStart == start:
+			suggestions = append(suggestions, childSuggestions...)
+		}
+	}
+	return start, suggestions
+}
+
+// Usage returns the usage of all commands that the given source can use, such as
+// "kick <targets> [<reason>]", sorted by name.
+func (d *Dispatcher) Usage(source Source) []string {
+	d.lock.RLock()
+	defer d.lock.RUnlock()
+
+	var usage []string
+	for _, child := range d.root.permittedChildren(source) {
+		usage = append(usage, child.smartUsage(source))
+	}
+	sort.Strings(usage)
+	return usage
+}
+
+// DeclareCommands returns the packet that declares the part of the tree, which the
+// given source can use, to the client.
+func (d *Dispatcher) DeclareCommands(source Source) packet.ClientboundDeclareCommands {
+	d.lock.RLock()
+	defer d.lock.RUnlock()
+
+	// collect the nodes breadth first, the root is at index 0
+	nodes := []*Node{d.root}
+	indices := map[*Node]int{d.root: 0}
+	for i := 0; i < len(nodes); i++ {
+		for _, child := range nodes[i].permittedChildren(source) {
+			if _, ok := indices[child]; !ok {
+				indices[child] = len(nodes)
+				nodes = append(nodes, child)
+			}
+		}
+	}
+
+	p := packet.ClientboundDeclareCommands{
+		Nodes:     make([]packet.CommandNode, len(nodes)),
+		RootIndex: 0,
+	}
+	for i, node := range nodes {
+		encoded := packet.CommandNode{
+			Executable: node.handler != nil,
+			Name:       node.name,
+		}
+		for _, child := range node.permittedChildren(source) {
+			encoded.Children = append(encoded.Children, indices[child])
+		}
+		switch {
+		case node == d.root:
+			encoded.Type = packet.CommandNodeRoot
+		case node.isLiteral():
+			encoded.Type = packet.CommandNodeLiteral
+		default:
+			encoded.Type = packet.CommandNodeArgument
+			encoded.Parser = node.argument.Parser()
+			var properties bytes.Buffer
+			node.argument.EncodeProperties(packet.Encoder{W: &properties})
+			encoded.Properties = properties.Bytes()
+			if node.suggests != nil {
+				encoded.SuggestionsType = packet.SuggestionsAskServer
+			}
+		}
+		p.Nodes[i] = encoded
+	}
+	return p
+}
@@ -0,0 +1,229 @@
+package command
+
+import (
+	"bytes"
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/tsatke/mcserver/game/chat"
+	"github.com/tsatke/mcserver/game/id"
+	"github.com/tsatke/mcserver/network/packet"
+)
+
+type testSource struct {
+	level    int
+	messages []string
+}
+
+func (s *testSource) Name() string         { return "test" }
+func (s *testSource) PermissionLevel() int { return s.level }
+func (s *testSource) SendMessage(message chat.Chat) {
+	s.messages = append(s.messages, message.PlainText())
+}
+
+func testDispatcher() *Dispatcher {
+	feedback := func(ctx *Context) error {
+		ctx.Source.SendMessage(chat.Chat{ChatFragment: chat.ChatFragment{Text: ctx.Input}})
+		return nil
+	}
+
+	d := NewDispatcher()
+	d.Register(Literal("list").Executes(feedback))
+	d.Register(Literal("kick").
+		Requires(PermissionLevelAdmin).
+		Then(Argument("targets", Players()).
+			Suggests(func(*Context, string) []string { return []string{"Notch", "jeb_"} }).
+			Executes(feedback).
+			Then(Argument("reason", GreedyString()).
+				Executes(feedback))))
+	d.Register(Literal("setblock").
+		Then(Argument("pos", BlockPos()).
+			Then(Literal("stone").Executes(feedback))))
+	d.Register(Literal("time").
+		Then(Literal("set").
+			Then(Literal("day").Executes(feedback)).
+			Then(Argument("time", IntegerBetween(0, 24000)).Executes(func(ctx *Context) error {
+				if ctx.Int("time") == 42 {
+					return errors.New("handler failed")
+				}
+				return feedback(ctx)
+			}))))
+	return d
+}
+
+func TestDispatcherExecute(t *testing.T) {
+	d := testDispatcher()
+
+	tests := []struct {
+		name   string
+		input  string
+		level  int
+		err    error
+		errMsg string
+	}{
+		{"literal", "list", 0, nil, ""},
+		{"literal ignores case", "LIST", 0, nil, ""},
+		{"unknown", "unknown", 0, ErrUnknownCommand, "unknown command at position 7: unknown<--[HERE]"},
+		{"empty", "", 0, ErrUnknownCommand, "unknown command at position 0: <--[HERE]"},
+		{"no permission", "kick Notch", 0, ErrUnknownCommand, ""},
+		{"permission", "kick Notch", 3, nil, ""},
+		{"optional argument", "kick Notch go away", 4, nil, ""},
+		{"incomplete", "time set", 0, ErrIncompleteCommand, ""},
+		{"literal before argument", "time set day", 0, nil, ""},
+		{"integer", "time set 1000", 0, nil, ""},
+		{"integer out of range", "time set 30000", 0, ErrInvalidArgument, ""},
+		{"incorrect argument", "time get", 0, ErrIncorrectArgument, ""},
+		{"trailing data", "time set 10x", 0, ErrTrailingData, ""},
+		{"selector", "kick @a", 3, nil, ""},
+		{"entity selector for players", "kick @e", 3, ErrInvalidArgument, ""},
+		{"block position", "setblock ~ 64 ~-2 stone", 0, nil, ""},
+		{"incomplete block position", "setblock 1 2 stone", 0, ErrInvalidArgument, ""},
+		{"long context", "setblock 1 2 3 dirt", 0, ErrIncorrectArgument, "incorrect argument for command at position 19: ...1 2 3 dirt<--[HERE]"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			source := &testSource{level: tt.level}
+			err := d.Execute(source, tt.input)
+			if tt.err == nil {
+				require.NoError(t, err)
+				assert.Equal(t, []string{tt.input}, source.messages)
+				return
+			}
+
+			assert.True(t, errors.Is(err, tt.err), "expected %v, got %v", tt.err, err)
+			var syntaxErr *SyntaxError
+			assert.True(t, errors.As(err, &syntaxErr))
+			if tt.errMsg != "" {
+				assert.EqualError(t, err, tt.errMsg)
+			}
+			assert.Empty(t, source.messages)
+		})
+	}
+
+	assert.EqualError(t, d.Execute(&testSource{}, "time set 42"), "handler failed")
+}
+
+func TestDispatcherArguments(t *testing.T) {
+	d := NewDispatcher()
+	var ctx *Context
+	store := func(c *Context) error {
+		ctx = c
+		return nil
+	}
+	d.Register(Literal("test").
+		Then(Argument("float", Float()).
+			Then(Argument("word", QuotablePhrase()).
+				Then(Argument("pos", BlockPos()).
+					Then(Argument("target", Entity()).
+						Executes(store))))))
+
+	require.NoError(t, d.Execute(&testSource{}, `test -1.5 "quoted \"phrase\"" ~1 ~ 3 b50ad385-829d-3141-a216-7e7d7539ba7f`))
+	assert.Equal(t, -1.5, ctx.Float("float"))
+	assert.Equal(t, `quoted "phrase"`, ctx.String("word"))
+	assert.Equal(t, BlockPosition{
+		X: Coordinate{Value: 1, Relative: true},
+		Y: Coordinate{Relative: true},
+		Z: Coordinate{Value: 3},
+	}, ctx.BlockPos("pos"))
+	assert.Equal(t, "b50ad385-829d-3141-a216-7e7d7539ba7f", ctx.Entity("target").String())
+	assert.False(t, ctx.Entity("target").PlayersOnly())
+	_, ok := ctx.Argument("missing")
+	assert.False(t, ok)
+}
+
+func TestDispatcherSuggest(t *testing.T) {
+	d := testDispatcher()
+
+	tests := []struct {
+		name        string
+		input       string
+		level       int
+		start       int
+		suggestions []string
+	}{
+		{"all commands", "", 0, 0, []string{"list", "setblock", "time"}},
+		{"all commands with permission", "", 3, 0, []string{"kick", "list", "setblock", "time"}},
+		{"prefix", "Se", 0, 0, []string{"setblock"}},
+		{"literal", "time s", 0, 5, []string{"set"}},
+		{"literals and arguments", "time set ", 0, 9, []string{"day"}},
+		{"provider", "kick ", 3, 5, []string{"@a", "@p", "@r", "@s", "Notch", "jeb_"}},
+		{"provider prefix", "kick j", 3, 5, []string{"jeb_"}},
+		{"no permission", "kick ", 0, 0, nil},
+		{"block position", "setblock ", 0, 9, []string{"~ ~ ~"}},
+		{"nothing", "list x", 0, 5, nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			start, suggestions := d.Suggest(&testSource{level: tt.level}, tt.input)
+			assert.Equal(t, tt.start, start)
+			assert.Equal(t, tt.suggestions, suggestions)
+		})
+	}
+}
+
+func TestDispatcherUsage(t *testing.T) {
+	d := testDispatcher()
+	assert.Equal(t, []string{
+		"kick <targets> [<reason>]",
+		"list",
+		"setblock <pos> stone",
+		"time set (day|<time>)",
+	}, d.Usage(&testSource{level: 4}))
+	assert.NotContains(t, d.Usage(&testSource{}), "kick <targets> [<reason>]")
+}
+
+func TestDispatcherDeclareCommands(t *testing.T) {
+	d := NewDispatcher()
+	d.Register(Literal("tp").
+		Then(Argument("target", Player()).
+			Suggests(func(*Context, string) []string { return nil }).
+			Executes(func(*Context) error { return nil })))
+	d.Register(Literal("stop").
+		Requires(PermissionLevelOwner).
+		Executes(func(*Context) error { return nil }))
+
+	p := d.DeclareCommands(&testSource{})
+	assert.Equal(t, packet.ClientboundDeclareCommands{
+		Nodes: []packet.CommandNode{
+			{Type: packet.CommandNodeRoot, Children: []int{1}},
+			{Type: packet.CommandNodeLiteral, Name: "tp", Children: []int{2}},
+			{
+				Type:            packet.CommandNodeArgument,
+				Executable:      true,
+				Name:            "target",
+				Parser:          id.ParseID("minecraft:entity"),
+				Properties:      []byte{0x03},
+				SuggestionsType: packet.SuggestionsAskServer,
+			},
+		},
+		RootIndex: 0,
+	}, p)
+	assert.Len(t, d.DeclareCommands(&testSource{level: PermissionLevelOwner}).Nodes, 4)
+}
+
+func TestArgumentProperties(t *testing.T) {
+	tests := []struct {
+		name       string
+		typ        ArgumentType
+		parser     string
+		properties []byte
+	}{
+		{"integer", Integer(), "brigadier:integer", []byte{0x00}},
+		{"integer range", IntegerBetween(0, 10), "brigadier:integer", []byte{0x03, 0, 0, 0, 0, 0, 0, 0, 10}},
+		{"float minimum", FloatBetween(0, Float().Max), "brigadier:float", []byte{0x01, 0, 0, 0, 0}},
+		{"greedy string", GreedyString(), "brigadier:string", []byte{0x02}},
+		{"entities", Entities(), "minecraft:entity", []byte{0x00}},
+		{"block position", BlockPos(), "minecraft:block_pos", nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var buf bytes.Buffer
+			tt.typ.EncodeProperties(packet.Encoder{W: &buf})
+			assert.Equal(t, id.ParseID(tt.parser), tt.typ.Parser())
+			assert.Equal(t, tt.properties, buf.Bytes())
+		})
+	}
+}
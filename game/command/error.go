@@ -0,0 +1,47 @@
+package command
+
+import "fmt"
+
+type Error string
+
+func (e Error) Error() string { return string(e) }
+
+const (
+	// ErrUnknownCommand indicates, that there is no command with the name that was
+	// entered, or that the source is not permitted to use it.
+	ErrUnknownCommand Error = "unknown command"
+	// ErrIncompleteCommand indicates, that the input ended before a node that can
+	// be executed.
+	ErrIncompleteCommand Error = "incomplete command"
+	// ErrIncorrectArgument indicates, that no node of the command matches the next
+	// word of the input.
+	ErrIncorrectArgument Error = "incorrect argument for command"
+	// ErrTrailingData indicates, that an argument was not followed by a space.
+	ErrTrailingData Error = "expected whitespace to end one argument, but found trailing data"
+	// ErrInvalidArgument indicates, that an argument could not be parsed. Argument
+	// types wrap this with the reason.
+	ErrInvalidArgument Error = "invalid argument"
+)
+
+// contextLength is the amount of characters of the input before the cursor that are
+// shown by a SyntaxError.
+const contextLength = 10
+
+// SyntaxError is returned if an input can't be parsed as a command. It wraps the
+// reason, such as ErrUnknownCommand, and shows the position in the input at which
+// parsing failed, like Brigadier does.
+type SyntaxError struct {
+	Err    error
+	Input  string
+	Cursor int
+}
+
+func (e *SyntaxError) Error() string {
+	context := e.Input[:e.Cursor]
+	if e.Cursor > contextLength {
+		context = "..." + e.Input[e.Cursor-contextLength:e.Cursor]
+	}
+	return fmt.Sprintf("%v at position %d: %s<--[HERE]", e.Err, e.Cursor, context)
+}
+
+func (e *SyntaxError) Unwrap() error { return e.Err }
@@ -0,0 +1,187 @@
+package command
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Handler executes a command with the arguments in the given context. Feedback is
+// sent to the source of the context.
+type Handler func(ctx *Context) error
+
+// SuggestionProvider returns suggestions for an argument, whose input so far is the
+// given text. Suggestions that don't start with the text are dropped.
+type SuggestionProvider func(ctx *Context, text string) []string
+
+// Node is a node of the command tree, which is either a literal, that only matches
+// its name, or an argument, whose value is parsed by an ArgumentType. Nodes are
+// built with Literal and Argument, and must not be changed after they were
+// registered with a Dispatcher.
+type Node struct {
+	name string
+	// argument is the type of argument nodes, and nil for literals and the root.
+	argument   ArgumentType
+	children   []*Node
+	handler    Handler
+	permission int
+	suggests   SuggestionProvider
+}
+
+// Literal creates a node that matches the given name, ignoring the case.
+func Literal(name string) *Node {
+	return &Node{
+		name: name,
+	}
+}
+
+// Argument creates a node for an argument with the given name, that is parsed by the
+// given type. The value can be obtained from the Context with the name.
+func Argument(name string, typ ArgumentType) *Node {
+	return &Node{
+		name:     name,
+		argument: typ,
+	}
+}
+
+// Then adds the given nodes as children of this node, and returns this node.
+func (n *Node) Then(children ...*Node) *Node {
+	n.children = append(n.children, children...)
+	return n
+}
+
+// Executes sets the handler that is called if the input ends after this node, and
+// returns this node.
+func (n *Node) Executes(handler Handler) *Node {
+	n.handler = handler
+	return n
+}
+
+// Requires sets the permission level that a source needs to use this node and its
+// children, and returns this node. To sources with a lower level, the node is
+// invisible.
+func (n *Node) Requires(permissionLevel int) *Node {
+	n.permission = permissionLevel
+	return n
+}
+
+// Suggests sets the provider of suggestions for this argument node, which are
+// requested by clients from the server, and returns this node. The suggestions are
+// added to the ones of the argument type, if it implements Suggester.
+func (n *Node) Suggests(provider SuggestionProvider) *Node {
+	n.suggests = provider
+	return n
+}
+
+// Name returns the name of this node.
+func (n *Node) Name() string {
+	return n.name
+}
+
+func (n *Node) isLiteral() bool {
+	return n.argument == nil
+}
+
+func (n *Node) permits(source Source) bool {
+	return source.PermissionLevel() >= n.permission
+}
+
+// permittedChildren returns the children of this node that the given source can use.
+func (n *Node) permittedChildren(source Source) []*Node {
+	children := make([]*Node, 0, len(n.children))
+	for _, child := range n.children {
+		if child.permits(source) {
+			children = append(children, child)
+		}
+	}
+	return children
+}
+
+// relevantChildren returns the children that may match the input at the position of
+// the given reader. Like in Brigadier, a literal that matches the next word takes
+// precedence over arguments.
+func (n *Node) relevantChildren(rd Reader, source Source) []*Node {
+	word := rd.ReadWord()
+	var arguments []*Node
+	for _, child := range n.permittedChildren(source) {
+		if child.isLiteral() {
+			if strings.EqualFold(child.name, word) {
+				return []*Node{child}
+			}
+			continue
+		}
+		arguments = append(arguments, child)
+	}
+	return arguments
+}
+
+// parse parses the input of this node at the position of the given reader. Literals
+// have no value.
+func (n *Node) parse(rd *Reader) (interface{}, error) {
+	if n.isLiteral() {
+		if word := rd.ReadWord(); !strings.EqualFold(word, n.name) {
+			return nil, fmt.Errorf("%w: expected '%s'", ErrInvalidArgument, n.name)
+		}
+		return nil, nil
+	}
+	return n.argument.Parse(rd)
+}
+
+// suggest returns suggestions for the input of this node, which is the given text.
+func (n *Node) suggest(ctx *Context, text string) []string {
+	var candidates []string
+	if n.isLiteral() {
+		candidates = []string{n.name}
+	} else {
+		if suggester, ok := n.argument.(Suggester); ok {
+			candidates = suggester.Suggest(text)
+		}
+		if n.suggests != nil {
+			candidates = append(candidates, n.suggests(ctx, text)...)
+		}
+	}
+
+	suggestions := make([]string, 0, len(candidates))
+	for _, candidate := range candidates {
+		if strings.HasPrefix(strings.ToLower(candidate), strings.ToLower(text)) {
+			suggestions = append(suggestions, candidate)
+		}
+	}
+	return suggestions
+}
+
+// usageText is the text of this node in a usage, such as "kick" or "<player>".
+func (n *Node) usageText() string {
+	if n.isLiteral() {
+		return n.name
+	}
+	return "<" + n.name + ">"
+}
+
+// smartUsage describes how this node and its children can be used by the given
+// source, like the smart usage of Brigadier, e.g. "kick <targets> [<reason>]".
+// Children are optional if this node can be executed.
+func (n *Node) smartUsage(source Source) string {
+	usage := n.usageText()
+	children := n.permittedChildren(source)
+	if len(children) == 0 {
+		return usage
+	}
+
+	open, close := "(", ")"
+	if n.handler != nil {
+		open, close = "[", "]"
+	}
+	if len(children) == 1 {
+		childUsage := children[0].smartUsage(source)
+		if n.handler != nil {
+			childUsage = open + childUsage + close
+		}
+		return usage + " " + childUsage
+	}
+
+	texts := make([]string, len(children))
+	for i, child := range children {
+		texts[i] = child.usageText()
+	}
+	return usage + " " + open + strings.Join(texts, "|") + close
+}
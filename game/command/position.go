@@ -0,0 +1,105 @@
+package command
+
+import (
+	"fmt"
+
+	"github.com/tsatke/mcserver/game/id"
+	"github.com/tsatke/mcserver/game/voxel"
+	"github.com/tsatke/mcserver/network/packet"
+)
+
+// Coordinate is a single coordinate of a BlockPosition, which is either absolute or
+// relative to the position of the source, as in ~ or ~-2.
+type Coordinate struct {
+	Value    int
+	Relative bool
+}
+
+// Resolve returns the absolute value of this coordinate, if the source is at the
+// given origin.
+func (c Coordinate) Resolve(origin int) int {
+	if c.Relative {
+		return origin + c.Value
+	}
+	return c.Value
+}
+
+// BlockPosition is the value of a BlockPosArgument.
+type BlockPosition struct {
+	X, Y, Z Coordinate
+}
+
+// Resolve returns the absolute position, if the source is at the given origin.
+func (p BlockPosition) Resolve(origin voxel.V3) voxel.V3 {
+	return voxel.V3{
+		X: p.X.Resolve(origin.X),
+		Y: p.Y.Resolve(origin.Y),
+		Z: p.Z.Resolve(origin.Z),
+	}
+}
+
+// BlockPosArgument is an argument type for block positions, which consist of three
+// coordinates that are separated by spaces. Local coordinates, as in ^ ^ ^1, are not
+// supported.
+type BlockPosArgument struct{}
+
+// BlockPos returns an argument type for block positions.
+func BlockPos() BlockPosArgument { return BlockPosArgument{} }
+
+// Parse implements ArgumentType.
+func (a BlockPosArgument) Parse(rd *Reader) (interface{}, error) {
+	start := rd.Cursor()
+	var coordinates [3]Coordinate
+	for i := range coordinates {
+		if i > 0 {
+			if !rd.CanRead() || rd.Peek() != ' ' {
+				rd.cursor = start
+				return nil, fmt.Errorf("%w: incomplete position, expected 3 coordinates", ErrInvalidArgument)
+			}
+			rd.Skip()
+		}
+
+		coordinate, err := parseCoordinate(rd)
+		if err != nil {
+			rd.cursor = start
+			return nil, err
+		}
+		coordinates[i] = coordinate
+	}
+	return BlockPosition{
+		X: coordinates[0],
+		Y: coordinates[1],
+		Z: coordinates[2],
+	}, nil
+}
+
+func parseCoordinate(rd *Reader) (Coordinate, error) {
+	if !rd.CanRead() {
+		return Coordinate{}, fmt.Errorf("%w: expected coordinate", ErrInvalidArgument)
+	}
+	switch rd.Peek() {
+	case '^':
+		return Coordinate{}, fmt.Errorf("%w: local coordinates are not supported", ErrInvalidArgument)
+	case '~':
+		rd.Skip()
+		if !rd.CanRead() || rd.Peek() == ' ' {
+			return Coordinate{Relative: true}, nil
+		}
+		val, err := rd.ReadInt()
+		return Coordinate{Value: val, Relative: true}, err
+	default:
+		val, err := rd.ReadInt()
+		return Coordinate{Value: val}, err
+	}
+}
+
+// Suggest implements Suggester, and suggests the position of the source.
+func (BlockPosArgument) Suggest(string) []string {
+	return []string{"~ ~ ~"}
+}
+
+// Parser implements ArgumentType.
+func (BlockPosArgument) Parser() id.ID { return id.ParseID("minecraft:block_pos") }
+
+// EncodeProperties implements ArgumentType. The parser has no properties.
+func (BlockPosArgument) EncodeProperties(packet.Encoder) {}
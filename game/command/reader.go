@@ -0,0 +1,150 @@
+package command
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// Reader reads the parts of a command input. The zero value is not usable, use
+// NewReader.
+type Reader struct {
+	input  string
+	cursor int
+}
+
+// NewReader creates a new reader that starts at the beginning of the given input.
+func NewReader(input string) *Reader {
+	return &Reader{
+		input: input,
+	}
+}
+
+// Cursor returns the index of the next byte that is read.
+func (rd *Reader) Cursor() int { return rd.cursor }
+
+// CanRead determines whether there is any input left.
+func (rd *Reader) CanRead() bool { return rd.cursor < len(rd.input) }
+
+// Peek returns the next byte without consuming it. This panics if there is
+// no input left.
+func (rd *Reader) Peek() byte { return rd.input[rd.cursor] }
+
+// Skip consumes the next byte.
+func (rd *Reader) Skip() { rd.cursor++ }
+
+// Remaining returns the input that is not read yet.
+func (rd *Reader) Remaining() string { return rd.input[rd.cursor:] }
+
+// ReadWord reads everything up to the next space.
+func (rd *Reader) ReadWord() string {
+	start := rd.cursor
+	for rd.CanRead() && rd.Peek() != ' ' {
+		rd.Skip()
+	}
+	return rd.input[start:rd.cursor]
+}
+
+// ReadUnquotedString reads the characters that are allowed in unquoted strings,
+// which are 0-9, A-Z, a-z, _, -, . and +.
+func (rd *Reader) ReadUnquotedString() string {
+	start := rd.cursor
+	for rd.CanRead() && isAllowedInUnquotedString(rd.Peek()) {
+		rd.Skip()
+	}
+	return rd.input[start:rd.cursor]
+}
+
+// ReadQuotedString reads a string in single or double quotes, in which quotes and
+// backslashes are escaped with a backslash.
+func (rd *Reader) ReadQuotedString() (string, error) {
+	if !rd.CanRead() || !isQuote(rd.Peek()) {
+		return "", fmt.Errorf("%w: expected quote to start a string", ErrInvalidArgument)
+	}
+	quote := rd.Peek()
+	rd.Skip()
+
+	var buf strings.Builder
+	escaped := false
+	for rd.CanRead() {
+		c := rd.Peek()
+		rd.Skip()
+		switch {
+		case escaped:
+			if c != quote && c != '\\' {
+				return "", fmt.Errorf("%w: invalid escape sequence '\\%c' in quoted string", ErrInvalidArgument, c)
+			}
+			buf.WriteByte(c)
+			escaped = false
+		case c == '\\':
+			escaped = true
+		case c == quote:
+			return buf.String(), nil
+		default:
+			buf.WriteByte(c)
+		}
+	}
+	return "", fmt.Errorf("%w: unclosed quoted string", ErrInvalidArgument)
+}
+
+// ReadString reads a quoted string if the next byte is a quote, and an unquoted
+// string otherwise.
+func (rd *Reader) ReadString() (string, error) {
+	if rd.CanRead() && isQuote(rd.Peek()) {
+		return rd.ReadQuotedString()
+	}
+	return rd.ReadUnquotedString(), nil
+}
+
+// ReadInt reads a 32 bit integer.
+func (rd *Reader) ReadInt() (int, error) {
+	start := rd.cursor
+	text := rd.readNumber()
+	if text == "" {
+		return 0, fmt.Errorf("%w: expected integer", ErrInvalidArgument)
+	}
+	val, err := strconv.ParseInt(text, 10, 32)
+	if err != nil {
+		rd.cursor = start
+		return 0, fmt.Errorf("%w: invalid integer '%s'", ErrInvalidArgument, text)
+	}
+	return int(val), nil
+}
+
+// ReadFloat reads a 32 bit floating point number.
+func (rd *Reader) ReadFloat() (float64, error) {
+	start := rd.cursor
+	text := rd.readNumber()
+	if text == "" {
+		return 0, fmt.Errorf("%w: expected float", ErrInvalidArgument)
+	}
+	val, err := strconv.ParseFloat(text, 32)
+	if err != nil {
+		rd.cursor = start
+		return 0, fmt.Errorf("%w: invalid float '%s'", ErrInvalidArgument, text)
+	}
+	return val, nil
+}
+
+func (rd *Reader) readNumber() string {
+	start := rd.cursor
+	for rd.CanRead() && isAllowedInNumber(rd.Peek()) {
+		rd.Skip()
+	}
+	return rd.input[start:rd.cursor]
+}
+
+func isAllowedInNumber(c byte) bool {
+	return c >= '0' && c <= '9' || c == '.' || c == '-'
+}
+
+func isAllowedInUnquotedString(c byte) bool {
+	return c >= '0' && c <= '9' ||
+		c >= 'A' && c <= 'Z' ||
+		c >= 'a' && c <= 'z' ||
+		c == '_' || c == '-' || c == '.' || c == '+'
+}
+
+func isQuote(c byte) bool {
+	return c == '"' || c == '\''
+}
@@ -0,0 +1,162 @@
+package command
+
+import (
+	"fmt"
+
+	"github.com/google/uuid"
+
+	"github.com/tsatke/mcserver/game/id"
+	"github.com/tsatke/mcserver/network/packet"
+)
+
+// Selector is the variable of a target selector, such as the 'p' of @p.
+type Selector byte
+
+// Available selectors.
+const (
+	// SelectorNone is used by selectors that select an entity by its name or UUID.
+	SelectorNone          Selector = 0
+	SelectorNearestPlayer Selector = 'p'
+	SelectorRandomPlayer  Selector = 'r'
+	SelectorAllPlayers    Selector = 'a'
+	SelectorAllEntities   Selector = 'e'
+	SelectorSelf          Selector = 's'
+)
+
+// EntitySelector is the value of an EntityArgument, which selects entities either with
+// a target selector, or by name or UUID. Selector arguments in brackets, such as
+// @a[distance=..5], are not supported.
+type EntitySelector struct {
+	Selector Selector
+	// Name is the name of the selected player, if the selector is SelectorNone and
+	// UUID is the zero UUID.
+	Name string
+	// UUID is the UUID of the selected entity, if the selector is SelectorNone.
+	UUID uuid.UUID
+}
+
+// Single determines whether this selector selects at most one entity.
+func (s EntitySelector) Single() bool {
+	return s.Selector != SelectorAllPlayers && s.Selector != SelectorAllEntities
+}
+
+// PlayersOnly determines whether this selector only selects players.
+func (s EntitySelector) PlayersOnly() bool {
+	return s.Selector != SelectorAllEntities && (s.Selector != SelectorNone || s.UUID == uuid.Nil)
+}
+
+func (s EntitySelector) String() string {
+	switch {
+	case s.Selector != SelectorNone:
+		return "@" + string(s.Selector)
+	case s.UUID != uuid.Nil:
+		return s.UUID.String()
+	default:
+		return s.Name
+	}
+}
+
+// Flags of the properties of the entity parser.
+const (
+	entitySingle      = 0x01
+	entityPlayersOnly = 0x02
+)
+
+// EntityArgument is an argument type for target selectors, names and UUIDs.
+type EntityArgument struct {
+	// Single restricts the selector to select at most one entity.
+	Single bool
+	// PlayersOnly restricts the selector to only select players.
+	PlayersOnly bool
+}
+
+// Entity returns an argument type that selects a single entity.
+func Entity() EntityArgument { return EntityArgument{Single: true} }
+
+// Entities returns an argument type that selects any number of entities.
+func Entities() EntityArgument { return EntityArgument{} }
+
+// Player returns an argument type that selects a single player.
+func Player() EntityArgument { return EntityArgument{Single: true, PlayersOnly: true} }
+
+// Players returns an argument type that selects any number of players.
+func Players() EntityArgument { return EntityArgument{PlayersOnly: true} }
+
+// Parse implements ArgumentType.
+func (a EntityArgument) Parse(rd *Reader) (interface{}, error) {
+	start := rd.Cursor()
+	selector, err := a.parse(rd)
+	if err != nil {
+		rd.cursor = start
+		return nil, err
+	}
+	if a.Single && !selector.Single() {
+		rd.cursor = start
+		return nil, fmt.Errorf("%w: only one entity is allowed, but the provided selector allows more than one", ErrInvalidArgument)
+	}
+	if a.PlayersOnly && !selector.PlayersOnly() {
+		rd.cursor = start
+		return nil, fmt.Errorf("%w: only players may be affected by this command, but the provided selector includes entities", ErrInvalidArgument)
+	}
+	return selector, nil
+}
+
+func (a EntityArgument) parse(rd *Reader) (EntitySelector, error) {
+	if rd.CanRead() && rd.Peek() == '@' {
+		rd.Skip()
+		if !rd.CanRead() {
+			return EntitySelector{}, fmt.Errorf("%w: missing selector type", ErrInvalidArgument)
+		}
+		selector := Selector(rd.Peek())
+		switch selector {
+		case SelectorNearestPlayer, SelectorRandomPlayer, SelectorAllPlayers, SelectorAllEntities, SelectorSelf:
+		default:
+			return EntitySelector{}, fmt.Errorf("%w: unknown selector type '@%c'", ErrInvalidArgument, rd.Peek())
+		}
+		rd.Skip()
+		if rd.CanRead() && rd.Peek() == '[' {
+			return EntitySelector{}, fmt.Errorf("%w: selector arguments are not supported", ErrInvalidArgument)
+		}
+		return EntitySelector{Selector: selector}, nil
+	}
+
+	name := rd.ReadUnquotedString()
+	if name == "" {
+		return EntitySelector{}, fmt.Errorf("%w: expected player name, UUID or selector", ErrInvalidArgument)
+	}
+	if parsed, err := uuid.Parse(name); err == nil && len(name) == 36 {
+		return EntitySelector{UUID: parsed}, nil
+	}
+	if len(name) > 16 {
+		return EntitySelector{}, fmt.Errorf("%w: player name '%s' is too long", ErrInvalidArgument, name)
+	}
+	return EntitySelector{Name: name}, nil
+}
+
+// Suggest implements Suggester, and suggests the selectors that are allowed. Names
+// have to be suggested with Node.Suggests.
+func (a EntityArgument) Suggest(string) []string {
+	suggestions := []string{"@p", "@r", "@s"}
+	if !a.Single {
+		suggestions = append(suggestions, "@a")
+		if !a.PlayersOnly {
+			suggestions = append(suggestions, "@e")
+		}
+	}
+	return suggestions
+}
+
+// Parser implements ArgumentType.
+func (EntityArgument) Parser() id.ID { return id.ParseID("minecraft:entity") }
+
+// EncodeProperties implements ArgumentType.
+func (a EntityArgument) EncodeProperties(enc packet.Encoder) {
+	var flags byte
+	if a.Single {
+		flags |= entitySingle
+	}
+	if a.PlayersOnly {
+		flags |= entityPlayersOnly
+	}
+	enc.WriteUbyte("flags", flags)
+}
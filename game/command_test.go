@@ -0,0 +1,84 @@
+package game
+
+import (
+	"github.com/tsatke/mcserver/game/command"
+	"github.com/tsatke/mcserver/network/packet"
+)
+
+func (suite *GameSuite) TestCommands() {
+	g, err := New(suite.world)
+	suite.Require().NoError(err)
+
+	p, client := suite.connectedPlayer(g)
+	defer func() { _ = client.Close() }()
+
+	var selected []*Player
+	g.RegisterCommand(command.Literal("select").
+		Requires(command.PermissionLevelGamemaster).
+		Then(command.Argument("targets", command.Players()).
+			Executes(func(ctx *command.Context) error {
+				selected, err = g.SelectPlayers(ctx.Source, ctx.Entity("targets"))
+				return err
+			})))
+	// registering sends the commands to connected players
+	id, _ := suite.readFrame(client)
+	suite.Equal(packet.IDClientboundEntityStatus, id)
+	id, _ = suite.readFrame(client)
+	suite.Equal(packet.IDClientboundDeclareCommands, id)
+
+	output, err := g.ExecuteCommand("help")
+	suite.NoError(err)
+	suite.Equal("/help\n/select <targets>", output)
+
+	for _, target := range []string{"@a", "@p", "@r", "notch", p.UUID.String()} {
+		selected = nil
+		_, err = g.ExecuteCommand("select " + target)
+		if target == p.UUID.String() {
+			// UUIDs may select entities that are not players
+			suite.ErrorIs(err, command.ErrInvalidArgument)
+			continue
+		}
+		suite.NoError(err, target)
+		suite.Equal([]*Player{p}, selected, target)
+	}
+	_, err = g.ExecuteCommand("select @s")
+	suite.ErrorIs(err, ErrPlayerNotFound)
+
+	// players only see the commands they may use
+	g.processPacket(p, &packet.ServerboundChatMessage{Message: "/help"})
+	suite.Equal("/help", suite.readChatMessage(client).Message.PlainText())
+	g.processPacket(p, &packet.ServerboundChatMessage{Message: "/select @s"})
+	suite.Equal("unknown command at position 6: select<--[HERE]", suite.readChatMessage(client).Message.PlainText())
+
+	p.SetPermissionLevel(command.PermissionLevelGamemaster)
+	g.processPacket(p, &packet.ServerboundChatMessage{Message: "/select @s"})
+	suite.Equal([]*Player{p}, selected)
+}
+
+func (suite *GameSuite) TestTabCompleteUTF16() {
+	g, err := New(suite.world)
+	suite.Require().NoError(err)
+
+	p, client := suite.connectedPlayer(g)
+	defer func() { _ = client.Close() }()
+
+	g.RegisterCommand(command.Literal("greet").
+		Then(command.Argument("message", command.QuotablePhrase()).
+			Then(command.Argument("target", command.Players()).
+				Suggests(g.SuggestPlayerNames))))
+	suite.readFrame(client) // entity status
+	suite.readFrame(client) // declare commands
+
+	// ä is one and 😀 is two UTF-16 code units, but they are 2 and 4 bytes long
+	g.processPacket(p, &packet.ServerboundTabComplete{TransactionID: 3, Text: `/greet "ä😀" No`})
+	id, payload := suite.readFrame(client)
+	suite.Require().Equal(packet.IDClientboundTabComplete, id)
+	var response packet.ClientboundTabComplete
+	suite.Require().NoError(response.DecodeFrom(payload))
+	suite.Equal(packet.ClientboundTabComplete{
+		TransactionID: 3,
+		Start:         13,
+		Length:        2,
+		Matches:       []packet.TabCompleteMatch{{Match: "Notch"}},
+	}, response)
+}
@@ -8,4 +8,6 @@ const (
 	// ErrPlayerNotExist indicates that the player that wanted to join does not exist and
 	// needs to be created.
 	ErrPlayerNotExist Sentinel = "player does not exist"
+	// ErrPlayerNotFound indicates that a selector of a command didn't select any player.
+	ErrPlayerNotFound Sentinel = "no player was found"
 )
@@ -2,8 +2,11 @@ package game
 
 import (
 	"context"
+	"crypto/sha256"
+	"encoding/binary"
 	"errors"
 	"io"
+	"sync"
 	"time"
 
 	"github.com/google/uuid"
@@ -11,15 +14,20 @@ import (
 	"github.com/tsatke/nbt"
 
 	"github.com/tsatke/mcserver/game/chat"
+	"github.com/tsatke/mcserver/game/command"
 	"github.com/tsatke/mcserver/game/entity"
 	"github.com/tsatke/mcserver/game/id"
 	"github.com/tsatke/mcserver/game/voxel"
 	"github.com/tsatke/mcserver/game/world"
+	"github.com/tsatke/mcserver/network"
 	"github.com/tsatke/mcserver/network/packet"
 )
 
 const (
 	TickDuration = 50 * time.Millisecond
+	// DefaultMaxPlayers is the amount of players that can be connected at the
+	// same time, if it is not configured with WithMaxPlayers.
+	DefaultMaxPlayers = 100
 
 	defaultQueueBufferSize = 100
 )
@@ -36,9 +44,26 @@ type Game struct {
 	world world.World
 
 	currentTick int64
+	tickStats   tickStats
 
+	maxPlayers        int
+	keepAliveInterval time.Duration
+	keepAliveTimeout  time.Duration
+
+	// playersLock guards connectedPlayers, which is accessed from the tick loop,
+	// the message worker and the connection handlers.
+	playersLock          sync.RWMutex
 	connectedPlayers     map[uuid.UUID]*Player
 	incomingMessageQueue chan incomingMessage
+
+	// pluginChannelsLock guards pluginChannels.
+	pluginChannelsLock sync.RWMutex
+	// pluginChannels holds the handlers of plugin messages by channel,
+	// see HandlePluginChannel.
+	pluginChannels map[id.ID]PluginChannelHandler
+
+	// commands holds the command tree, see RegisterCommand.
+	commands *command.Dispatcher
 }
 
 func New(world world.World, opts ...Option) (*Game, error) {
@@ -47,9 +72,17 @@ func New(world world.World, opts ...Option) (*Game, error) {
 		ready: make(chan struct{}),
 		world: world,
 
+		maxPlayers:        DefaultMaxPlayers,
+		keepAliveInterval: DefaultKeepAliveInterval,
+		keepAliveTimeout:  DefaultKeepAliveTimeout,
+
 		connectedPlayers:     make(map[uuid.UUID]*Player),
 		incomingMessageQueue: make(chan incomingMessage, defaultQueueBufferSize), // TODO: check if 100 is too large, too little or whatever
+		pluginChannels:       make(map[id.ID]PluginChannelHandler),
+		commands:             command.NewDispatcher(),
 	}
+	g.registerDefaultPluginChannels()
+	g.registerDefaultCommands()
 
 	for _, opt := range opts {
 		opt(g)
@@ -80,15 +113,19 @@ tickLoop:
 			break tickLoop
 		case t := <-ticker.C:
 			sinceLast := time.Since(lastTime)
+			skipped := 0
 			if sinceLast > 2*TickDuration {
+				skipped = int(sinceLast/TickDuration) - 1
 				g.log.Info().
 					Stringer("sinceLast", sinceLast).
-					Int("skipped", int(sinceLast/TickDuration)-1).
+					Int("skipped", skipped).
 					Msg("can't keep up, skipping ticks")
 			}
 			lastTime = t
 
+			start := time.Now()
 			g.tick()
+			g.tickStats.record(start, time.Since(start), skipped)
 			g.currentTick++
 		}
 	}
@@ -102,9 +139,31 @@ func (g *Game) Ready() <-chan struct{} {
 }
 
 func (g *Game) AmountOfConnectedPlayers() int {
+	g.playersLock.RLock()
+	defer g.playersLock.RUnlock()
+
 	return len(g.connectedPlayers)
 }
 
+// Players returns a snapshot of all currently connected players.
+func (g *Game) Players() []*Player {
+	g.playersLock.RLock()
+	defer g.playersLock.RUnlock()
+
+	players := make([]*Player, 0, len(g.connectedPlayers))
+	for _, p := range g.connectedPlayers {
+		players = append(players, p)
+	}
+	return players
+}
+
+// Broadcast writes the given packet to all connected players.
+func (g *Game) Broadcast(pkg packet.Clientbound) {
+	for _, p := range g.Players() {
+		g.WritePacket(p, pkg)
+	}
+}
+
 func (g *Game) WritePacket(p *Player, pkg packet.Clientbound) {
 	if err := p.conn.WritePacket(pkg); err != nil {
 		g.log.Debug().
@@ -128,7 +187,13 @@ func (g *Game) DisconnectWithReason(p *Player, reason chat.Chat) {
 
 func (g *Game) Disconnect(p *Player) {
 	p.Disconnect()
-	delete(g.connectedPlayers, p.UUID)
+
+	g.playersLock.Lock()
+	// the player may already have been replaced by a newer session with the same UUID
+	if g.connectedPlayers[p.UUID] == p {
+		delete(g.connectedPlayers, p.UUID)
+	}
+	g.playersLock.Unlock()
 }
 
 func (g *Game) AddPlayer(p *Player) {
@@ -173,7 +238,22 @@ func (g *Game) AddPlayer(p *Player) {
 	// 	}
 	// }
 
+	g.playersLock.Lock()
+	previous := g.connectedPlayers[p.UUID]
 	g.connectedPlayers[p.UUID] = p
+	g.playersLock.Unlock()
+
+	if previous != nil {
+		g.log.Info().
+			Stringer("uuid", p.UUID).
+			Str("username", previous.name).
+			Msg("player logged in again, disconnecting previous session")
+		g.DisconnectWithReason(previous, chat.Chat{
+			ChatFragment: chat.ChatFragment{
+				Text: "You logged in from another location",
+			},
+		})
+	}
 
 	g.log.Info().
 		Stringer("uuid", p.UUID).
@@ -196,6 +276,7 @@ func (g *Game) AddPlayer(p *Player) {
 		EntityID: 1,  // same EID as when joining
 		Status:   23, // disable reduced debug screen info
 	})
+	g.sendCommands(p)
 	g.WritePacket(p, packet.ClientboundPlayerPositionAndLook{
 		X:          0,
 		Y:          69,
@@ -212,19 +293,11 @@ func (g *Game) AddPlayer(p *Player) {
 				UUID:           p.UUID,
 				Name:           p.name,
 				Gamemode:       int(GamemodeSurvival),
-				Ping:           100,
+				Ping:           0, // not measured yet, updated after the first keep alive
 				HasDisplayName: false,
 			},
 		},
 	})
-	g.WritePacket(p, packet.ClientboundPlayerInfo{
-		Action: packet.PlayerInfoUpdateLatency,
-		Players: []packet.PlayerInfoPlayer{
-			{
-				Ping: 32,
-			},
-		},
-	})
 	g.WritePacket(p, packet.ClientboundUpdateViewPosition{
 		Chunk: voxel.V2{0, 0},
 	})
@@ -292,20 +365,30 @@ func (g *Game) handleIncomingPlayerMessages(p *Player) {
 	for {
 		pkg, err := p.conn.ReadPacket()
 		if err != nil {
+			if errors.Is(err, network.ErrClosed) {
+				// the player was disconnected by the server
+				return
+			}
 			if errors.Is(err, io.EOF) {
 				g.log.Info().
 					Err(err).
 					Stringer("player", p.UUID).
 					Msg("player disconnected")
-				g.Disconnect(p)
-				return
+			} else if errors.Is(err, network.ErrTimeout) {
+				// the connection already logged and closed itself
+				g.log.Info().
+					Stringer("player", p.UUID).
+					Msg("player timed out")
 			} else {
+				// unknown packets are skipped by the connection, so this is
+				// a malformed packet or the stream is out of sync
 				g.log.Error().
 					Err(err).
 					Stringer("player", p.UUID).
 					Msg("read packet failed, disconnect")
 			}
-			continue
+			g.Disconnect(p)
+			return
 		}
 
 	retryLoop:
@@ -351,8 +434,8 @@ func (g *Game) sendJoinGameMessage(p *Player, dimensionCodec *nbt.Compound) {
 			Value[0].(*nbt.Compound).
 			Value["element"],
 		WorldName:           id.ParseID("world"),
-		HashedSeed:          g.world.Seed(),
-		MaxPlayers:          100,
+		HashedSeed:          hashSeed(g.world.Seed()),
+		MaxPlayers:          g.maxPlayers,
 		ViewDistance:        5,
 		ReducedDebugInfo:    false,
 		EnableRespawnScreen: true,
@@ -361,6 +444,16 @@ func (g *Game) sendJoinGameMessage(p *Player, dimensionCodec *nbt.Compound) {
 	})
 }
 
+// hashSeed returns the first 8 bytes of the SHA-256 hash of the given world seed, as
+// the vanilla server sends it to clients, which use it for biome noise. The seed itself
+// must not be sent, because it would reveal the world generation to every client.
+func hashSeed(seed int64) int64 {
+	var data [8]byte
+	binary.LittleEndian.PutUint64(data[:], uint64(seed))
+	sum := sha256.Sum256(data[:])
+	return int64(binary.LittleEndian.Uint64(sum[:8]))
+}
+
 func (g *Game) loadPlayerEntity(p *Player) error {
 	// data, err := g.world.LoadNBTPlayerdata(p.UUID)
 	// if err != nil {
@@ -58,3 +58,8 @@ func (suite *GameSuite) TestLoadPlayerEntity() {
 	suite.True(e.Abilities.MayFly)
 	suite.EqualValues(0.10000000149011612, e.Abilities.WalkSpeed)
 }
+
+func (suite *GameSuite) TestHashSeed() {
+	suite.EqualValues(8794265229978523055, hashSeed(0))
+	suite.EqualValues(2159143436479834350, hashSeed(-4172144997902289642))
+}
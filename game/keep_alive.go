@@ -0,0 +1,100 @@
+package game
+
+import (
+	"time"
+
+	"github.com/tsatke/mcserver/game/chat"
+	"github.com/tsatke/mcserver/network/packet"
+)
+
+const (
+	// DefaultKeepAliveInterval is the interval in which keep alive packets are
+	// sent to players, as the vanilla server does.
+	DefaultKeepAliveInterval = 15 * time.Second
+	// DefaultKeepAliveTimeout is the time after which a player that didn't answer
+	// a keep alive packet is disconnected, as the vanilla server does.
+	DefaultKeepAliveTimeout = 30 * time.Second
+)
+
+// playerKeepAlive holds the keep alive state of a single player.
+type playerKeepAlive struct {
+	// pending indicates that a keep alive packet with the id was sent to the
+	// player, and no answer has been received yet.
+	pending bool
+	id      int64
+	// sentAt is the point in time where the last keep alive packet was sent.
+	sentAt time.Time
+	// latency is the smoothed round trip time of the keep alive packets.
+	latency time.Duration
+}
+
+// tickKeepAlive sends keep alive packets to all players that didn't get one for the
+// configured interval, and disconnects all players that didn't answer a keep alive
+// packet within the configured timeout.
+func (g *Game) tickKeepAlive(now time.Time) {
+	for _, p := range g.Players() {
+		p.Lock()
+		ka := p.keepAlive
+		timedOut := ka.pending && now.Sub(ka.sentAt) > g.keepAliveTimeout
+		due := !ka.pending && now.Sub(ka.sentAt) >= g.keepAliveInterval
+		if due {
+			p.keepAlive.pending = true
+			p.keepAlive.id = now.UnixNano() / int64(time.Millisecond)
+			p.keepAlive.sentAt = now
+		}
+		keepAliveID := p.keepAlive.id
+		p.Unlock()
+
+		if timedOut {
+			g.log.Info().
+				Stringer("uuid", p.UUID).
+				Str("player", p.name).
+				Stringer("timeout", g.keepAliveTimeout).
+				Msg("player didn't answer keep alive, disconnecting")
+			g.DisconnectWithReason(p, chat.Chat{
+				ChatFragment: chat.ChatFragment{
+					Text: "Timed out",
+				},
+			})
+			continue
+		}
+		if due {
+			g.WritePacket(p, packet.ClientboundKeepAlive{
+				KeepAliveID: keepAliveID,
+			})
+		}
+	}
+}
+
+// processServerboundKeepAlive records the round trip time of the answered keep alive
+// packet and notifies all players about the new latency of the source player.
+// Answers to keep alive packets that were not sent by the server are ignored.
+func (g *Game) processServerboundKeepAlive(source *Player, p *packet.ServerboundKeepAlive, now time.Time) {
+	if !source.keepAlive.pending || source.keepAlive.id != p.KeepAliveID {
+		g.log.Debug().
+			Stringer("uuid", source.UUID).
+			Int64("id", p.KeepAliveID).
+			Msg("ignoring unexpected keep alive")
+		return
+	}
+
+	rtt := now.Sub(source.keepAlive.sentAt)
+	source.keepAlive.pending = false
+	if source.keepAlive.latency == 0 {
+		source.keepAlive.latency = rtt
+	} else {
+		// smooth the latency like the vanilla server does, so that
+		// a single slow answer doesn't make the ping jump around
+		source.keepAlive.latency = (source.keepAlive.latency*3 + rtt) / 4
+	}
+
+	g.Broadcast(packet.ClientboundPlayerInfo{
+		Action: packet.PlayerInfoUpdateLatency,
+		Players: []packet.PlayerInfoPlayer{
+			{
+				UUID: source.UUID,
+				Ping: int(source.keepAlive.latency / time.Millisecond),
+			},
+		},
+	})
+}
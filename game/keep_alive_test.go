@@ -0,0 +1,104 @@
+package game
+
+import (
+	"bytes"
+	"io"
+	"io/ioutil"
+	"net"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/rs/zerolog"
+
+	"github.com/tsatke/mcserver/game/entity"
+	"github.com/tsatke/mcserver/network"
+	"github.com/tsatke/mcserver/network/packet"
+)
+
+func (suite *GameSuite) TestKeepAlive() {
+	g, err := New(suite.world, WithKeepAlive(time.Second, 2*time.Second))
+	suite.Require().NoError(err)
+
+	p, client := suite.connectedPlayer(g)
+	defer func() { _ = client.Close() }()
+
+	start := time.Now()
+	g.tickKeepAlive(start)
+	id, payload := suite.readFrame(client)
+	suite.Equal(packet.IDClientboundKeepAlive, id)
+	keepAliveID := packet.Decoder{Rd: payload}.ReadLong("keep alive id")
+
+	// nothing is sent while the keep alive is pending and not timed out
+	g.tickKeepAlive(start.Add(1500 * time.Millisecond))
+
+	g.processPacket(p, &packet.ServerboundKeepAlive{KeepAliveID: keepAliveID + 1})
+	suite.Zero(p.Latency())
+
+	p.Lock()
+	g.processServerboundKeepAlive(p, &packet.ServerboundKeepAlive{KeepAliveID: keepAliveID}, start.Add(40*time.Millisecond))
+	p.Unlock()
+	suite.Equal(40*time.Millisecond, p.Latency())
+
+	id, payload = suite.readFrame(client)
+	suite.Equal(packet.IDClientboundPlayerInfo, id)
+	dec := packet.Decoder{Rd: payload}
+	suite.EqualValues(packet.PlayerInfoUpdateLatency, dec.ReadVarInt("action"))
+	suite.EqualValues(1, dec.ReadVarInt("number of players"))
+	suite.Equal(p.UUID, dec.ReadUUID("uuid"))
+	suite.EqualValues(40, dec.ReadVarInt("ping"))
+}
+
+func (suite *GameSuite) TestKeepAliveTimeout() {
+	g, err := New(suite.world, WithKeepAlive(time.Second, 2*time.Second))
+	suite.Require().NoError(err)
+
+	_, client := suite.connectedPlayer(g)
+	defer func() { _ = client.Close() }()
+
+	start := time.Now()
+	g.tickKeepAlive(start)
+	id, _ := suite.readFrame(client)
+	suite.Equal(packet.IDClientboundKeepAlive, id)
+
+	g.tickKeepAlive(start.Add(2*time.Second + time.Millisecond))
+	id, _ = suite.readFrame(client)
+	suite.Equal(packet.IDClientboundDisconnectPlay, id)
+	suite.Zero(g.AmountOfConnectedPlayers())
+}
+
+// connectedPlayer creates a player that is connected to the given game via a local
+// tcp connection, without sending any of the join packets. The client end of the
+// connection is returned.
+func (suite *GameSuite) connectedPlayer(g *Game) (*Player, net.Conn) {
+	lis, err := net.Listen("tcp", "127.0.0.1:0")
+	suite.Require().NoError(err)
+	defer func() { _ = lis.Close() }()
+
+	client, err := net.Dial("tcp", lis.Addr().String())
+	suite.Require().NoError(err)
+	server, err := lis.Accept()
+	suite.Require().NoError(err)
+
+	playerUUID := uuid.New()
+	p := NewPlayer(playerUUID, "Notch", network.NewConn(zerolog.Nop(), server))
+	p.Player = &entity.Player{
+		Mob: entity.Mob{
+			Data: entity.Data{UUID: playerUUID},
+		},
+	}
+	g.connectedPlayers[p.UUID] = p
+	return p, client
+}
+
+// readFrame reads a single uncompressed packet from the given connection and returns
+// its ID and a decoder for the payload.
+func (suite *GameSuite) readFrame(conn net.Conn) (packet.ID, io.Reader) {
+	suite.Require().NoError(conn.SetReadDeadline(time.Now().Add(5 * time.Second)))
+	dec := packet.Decoder{Rd: conn}
+	length := dec.ReadVarInt("length")
+	frame, err := ioutil.ReadAll(io.LimitReader(conn, int64(length)))
+	suite.Require().NoError(err)
+	rd := bytes.NewReader(frame)
+	id := packet.Decoder{Rd: rd}.ReadVarInt("id")
+	return packet.ID(id), rd
+}
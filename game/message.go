@@ -0,0 +1,106 @@
+package game
+
+import (
+	"strings"
+
+	"github.com/google/uuid"
+
+	"github.com/tsatke/mcserver/game/chat"
+	"github.com/tsatke/mcserver/network/packet"
+)
+
+//go:generate stringer -trimprefix=ChatPosition -type=ChatPosition
+
+// ChatPosition is the position where a message is shown to a player.
+type ChatPosition int8
+
+// Available chat positions.
+const (
+	// ChatPositionChat is used for messages of players, which are hidden by clients that only
+	// want to see commands or nothing at all.
+	ChatPositionChat ChatPosition = iota
+	// ChatPositionSystem is used for feedback of commands and other messages of the server, which
+	// are only hidden by clients that don't want to see anything in the chat.
+	ChatPositionSystem
+	// ChatPositionActionBar shows the message above the hotbar, which is never hidden.
+	ChatPositionActionBar
+)
+
+// SendMessage sends the given message to the given player at the given position. If the player
+// disabled chat messages at the position in the client settings, nothing is sent, and if the
+// player disabled chat colors, the colors are removed from the message.
+func (g *Game) SendMessage(p *Player, message chat.Chat, position ChatPosition) {
+	g.sendMessage(p, message, position, uuid.Nil)
+}
+
+// BroadcastMessage sends the given message to all connected players, as with SendMessage.
+func (g *Game) BroadcastMessage(message chat.Chat, position ChatPosition) {
+	g.broadcastMessage(message, position, uuid.Nil)
+}
+
+func (g *Game) broadcastMessage(message chat.Chat, position ChatPosition, sender uuid.UUID) {
+	for _, p := range g.Players() {
+		g.sendMessage(p, message, position, sender)
+	}
+}
+
+func (g *Game) sendMessage(p *Player, message chat.Chat, position ChatPosition, sender uuid.UUID) {
+	p.Lock()
+	accepts := p.acceptsMessage(position)
+	colors := p.client.settings.chatColors
+	p.Unlock()
+
+	if !accepts {
+		return
+	}
+	if !colors {
+		message = message.WithoutColors()
+	}
+	g.WritePacket(p, packet.ClientboundChatMessage{
+		Message:  message,
+		Position: int8(position),
+		Sender:   sender,
+	})
+}
+
+// processServerboundChatMessage broadcasts the chat message of the given player. Unlike other
+// packets, this is called without holding the lock of the player, since the message is also
+// sent to the player itself.
+func (g *Game) processServerboundChatMessage(source *Player, p *packet.ServerboundChatMessage) {
+	message := strings.TrimSpace(p.Message)
+	isCommand := strings.HasPrefix(message, "/")
+
+	source.Lock()
+	allowed := source.acceptsMessage(ChatPositionChat) || (isCommand && source.acceptsMessage(ChatPositionSystem))
+	source.Unlock()
+
+	if !allowed {
+		// the vanilla client doesn't send chat messages if chat is disabled, so this is
+		// just a hint for modified clients, in the action bar, which they always show
+		g.SendMessage(source, chat.Chat{
+			ChatFragment: chat.ChatFragment{
+				Text:  "Cannot send chat message because of your chat settings.",
+				Color: "red",
+			},
+		}, ChatPositionActionBar)
+		return
+	}
+
+	if isCommand {
+		g.executePlayerCommand(source, strings.TrimPrefix(message, "/"))
+		return
+	}
+
+	g.log.Info().
+		Str("player", source.name).
+		Str("message", message).
+		Msg("chat")
+	g.broadcastMessage(chat.Chat{
+		ChatFragment: chat.ChatFragment{
+			Text: "<" + source.name + "> ",
+		},
+		Extra: []chat.ChatFragment{
+			{Text: message},
+		},
+	}, ChatPositionChat, source.UUID)
+}
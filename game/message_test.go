@@ -0,0 +1,83 @@
+package game
+
+import (
+	"net"
+
+	"github.com/google/uuid"
+
+	"github.com/tsatke/mcserver/game/chat"
+	"github.com/tsatke/mcserver/network/packet"
+)
+
+func (suite *GameSuite) TestChat() {
+	g, err := New(suite.world)
+	suite.Require().NoError(err)
+
+	sender, senderClient := suite.connectedPlayer(g)
+	defer func() { _ = senderClient.Close() }()
+	receiver, receiverClient := suite.connectedPlayer(g)
+	defer func() { _ = receiverClient.Close() }()
+
+	g.processPacket(sender, &packet.ServerboundChatMessage{Message: "hello "})
+	for _, msg := range []*packet.ClientboundChatMessage{
+		suite.readChatMessage(senderClient),
+		suite.readChatMessage(receiverClient),
+	} {
+		suite.Equal("<Notch> hello", msg.Message.PlainText())
+		suite.EqualValues(ChatPositionChat, msg.Position)
+		suite.Equal(sender.UUID, msg.Sender)
+	}
+
+	// the receiver only wants to see commands, and no colors
+	g.processPacket(receiver, &packet.ServerboundClientSettings{
+		ChatMode:   packet.ChatModeCommandsOnly,
+		ChatColors: false,
+	})
+	g.processPacket(sender, &packet.ServerboundChatMessage{Message: "not for you"})
+	suite.Equal("<Notch> not for you", suite.readChatMessage(senderClient).Message.PlainText())
+
+	g.BroadcastMessage(chat.Chat{
+		ChatFragment: chat.ChatFragment{Text: "system", Color: "red"},
+	}, ChatPositionSystem)
+	msg := suite.readChatMessage(senderClient)
+	suite.Equal("red", msg.Message.Color)
+	suite.Equal(uuid.Nil, msg.Sender)
+	msg = suite.readChatMessage(receiverClient)
+	suite.Equal("system", msg.Message.Text)
+	suite.Empty(msg.Message.Color)
+	suite.EqualValues(ChatPositionSystem, msg.Position)
+
+	// players can't chat when they only want to see commands
+	g.processPacket(receiver, &packet.ServerboundChatMessage{Message: "hello"})
+	msg = suite.readChatMessage(receiverClient)
+	suite.EqualValues(ChatPositionActionBar, msg.Position)
+	suite.Contains(msg.Message.Text, "chat settings")
+
+	// the action bar is shown even if the chat is hidden
+	g.processPacket(receiver, &packet.ServerboundClientSettings{
+		ChatMode: packet.ChatModeHidden,
+	})
+	g.SendMessage(receiver, chat.Chat{ChatFragment: chat.ChatFragment{Text: "hidden"}}, ChatPositionSystem)
+	g.SendMessage(receiver, chat.Chat{ChatFragment: chat.ChatFragment{Text: "action"}}, ChatPositionActionBar)
+	msg = suite.readChatMessage(receiverClient)
+	suite.Equal("action", msg.Message.Text)
+	suite.EqualValues(ChatPositionActionBar, msg.Position)
+
+	// players with hidden chat can neither chat nor use commands, and are told so
+	for _, message := range []string{"hello", "/help"} {
+		g.processPacket(receiver, &packet.ServerboundChatMessage{Message: message})
+		msg = suite.readChatMessage(receiverClient)
+		suite.EqualValues(ChatPositionActionBar, msg.Position, message)
+		suite.Contains(msg.Message.Text, "chat settings", message)
+	}
+}
+
+// readChatMessage reads the next packet from the given connection, which must be a chat
+// message.
+func (suite *GameSuite) readChatMessage(conn net.Conn) *packet.ClientboundChatMessage {
+	id, payload := suite.readFrame(conn)
+	suite.Require().Equal(packet.IDClientboundChatMessage, id)
+	var msg packet.ClientboundChatMessage
+	suite.Require().NoError(msg.DecodeFrom(payload))
+	return &msg
+}
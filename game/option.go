@@ -1,6 +1,10 @@
 package game
 
-import "github.com/rs/zerolog"
+import (
+	"time"
+
+	"github.com/rs/zerolog"
+)
 
 type Option func(*Game)
 
@@ -9,3 +13,21 @@ func WithLogger(log zerolog.Logger) Option {
 		g.log = log
 	}
 }
+
+// WithMaxPlayers sets the amount of players that can be connected at the same
+// time, which is shown to clients when they join. The default is DefaultMaxPlayers.
+func WithMaxPlayers(maxPlayers int) Option {
+	return func(g *Game) {
+		g.maxPlayers = maxPlayers
+	}
+}
+
+// WithKeepAlive sets the interval in which keep alive packets are sent to players,
+// and the timeout after which players that didn't answer are disconnected.
+// The defaults are DefaultKeepAliveInterval and DefaultKeepAliveTimeout.
+func WithKeepAlive(interval, timeout time.Duration) Option {
+	return func(g *Game) {
+		g.keepAliveInterval = interval
+		g.keepAliveTimeout = timeout
+	}
+}
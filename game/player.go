@@ -2,12 +2,15 @@ package game
 
 import (
 	"sync"
+	"time"
 
 	"github.com/google/uuid"
 
 	"github.com/tsatke/mcserver/game/entity"
+	"github.com/tsatke/mcserver/game/id"
 	"github.com/tsatke/mcserver/game/voxel"
 	"github.com/tsatke/mcserver/network"
+	"github.com/tsatke/mcserver/network/packet"
 )
 
 type Player struct {
@@ -21,6 +24,11 @@ type Player struct {
 
 	// client holds attributes regarding the player client, such as the brand, settings and others.
 	client playerClient
+	// keepAlive holds the state of the keep alive packets sent to this player.
+	keepAlive playerKeepAlive
+	// permissionLevel determines which commands the player can use. It is only set
+	// before the player joins, so it is not guarded by the lock.
+	permissionLevel int
 
 	*entity.Player
 }
@@ -32,11 +40,16 @@ type playerClient struct {
 	// as its brand.
 	brand    string
 	settings playerClientSettings
+	// channels are the plugin channels that the client registered with a plugin
+	// message on channel minecraft:register.
+	channels map[id.ID]struct{}
 }
 
 type playerClientSettings struct {
 	locale       string
 	viewDistance int
+	chatMode     packet.ChatMode
+	chatColors   bool
 }
 
 func NewPlayer(uuid uuid.UUID, name string, conn *network.Conn) *Player {
@@ -44,11 +57,81 @@ func NewPlayer(uuid uuid.UUID, name string, conn *network.Conn) *Player {
 		tempUUID: uuid,
 		name:     name,
 		conn:     conn,
+		client: playerClient{
+			// until the client sends its settings, it's assumed that it wants to
+			// see everything, as the vanilla client does by default
+			settings: playerClientSettings{
+				chatMode:   packet.ChatModeEnabled,
+				chatColors: true,
+			},
+		},
 	}
 }
 
+// SetPermissionLevel sets the permission level of the player, which determines which
+// commands the player can use, see command.Node.Requires. This must be called before
+// the player is added to the game.
+func (p *Player) SetPermissionLevel(level int) {
+	p.permissionLevel = level
+}
+
+// PermissionLevel returns the permission level of the player.
+func (p *Player) PermissionLevel() int {
+	return p.permissionLevel
+}
+
+// Disconnect closes the connection of this player. Closing waits for already written
+// packets to be sent, so this is done in the background to not block the game.
 func (p *Player) Disconnect() {
-	_ = p.conn.Close()
+	go func() {
+		_ = p.conn.Close()
+	}()
+}
+
+// Name returns the name of the player.
+func (p *Player) Name() string {
+	return p.name
+}
+
+// Latency returns the round trip time of this player's connection, as measured
+// with keep alive packets. This is zero until the player answered the first one.
+func (p *Player) Latency() time.Duration {
+	p.Lock()
+	defer p.Unlock()
+
+	return p.keepAlive.latency
+}
+
+// Brand returns the brand of the client that the player uses, or an empty string
+// if the client didn't send its brand yet.
+func (p *Player) Brand() string {
+	p.Lock()
+	defer p.Unlock()
+
+	return p.client.brand
+}
+
+// SupportsChannel determines whether the client of the player registered the given
+// plugin channel, which means that it understands plugin messages on it.
+func (p *Player) SupportsChannel(channel id.ID) bool {
+	p.Lock()
+	defer p.Unlock()
+
+	_, ok := p.client.channels[channel]
+	return ok
+}
+
+// acceptsMessage determines whether the chat mode of the player allows messages
+// at the given position. The caller must hold the lock of the player.
+func (p *Player) acceptsMessage(position ChatPosition) bool {
+	switch p.client.settings.chatMode {
+	case packet.ChatModeHidden:
+		return position == ChatPositionActionBar
+	case packet.ChatModeCommandsOnly:
+		return position != ChatPositionChat
+	default:
+		return true
+	}
 }
 
 func (p *Player) Chunk() voxel.V2 {
@@ -0,0 +1,126 @@
+package game
+
+import (
+	"bytes"
+	"strings"
+
+	"github.com/tsatke/mcserver/game/id"
+	"github.com/tsatke/mcserver/network/packet"
+)
+
+// Channels that are handled by the game itself.
+var (
+	ChannelBrand      = id.ParseID("minecraft:brand")
+	ChannelRegister   = id.ParseID("minecraft:register")
+	ChannelUnregister = id.ParseID("minecraft:unregister")
+)
+
+// PluginChannelHandler handles the plugin messages that players send on a channel.
+// Handlers are called by the message worker of the game, without holding the lock
+// of the player, so handlers can call methods of the player.
+type PluginChannelHandler func(source *Player, data []byte)
+
+// HandlePluginChannel registers the given handler for plugin messages on the given
+// channel. If there already is a handler for the channel, it is replaced.
+func (g *Game) HandlePluginChannel(channel id.ID, handler PluginChannelHandler) {
+	g.pluginChannelsLock.Lock()
+	defer g.pluginChannelsLock.Unlock()
+
+	g.pluginChannels[channel] = handler
+}
+
+// SendPluginMessage sends the given data on the given plugin channel to the given
+// player. Clients ignore messages on channels that they don't know, use
+// Player.SupportsChannel to find out which channels the client registered.
+func (g *Game) SendPluginMessage(p *Player, channel id.ID, data []byte) {
+	g.WritePacket(p, packet.ClientboundPluginMessage{
+		Channel: channel,
+		Data:    data,
+	})
+}
+
+func (g *Game) registerDefaultPluginChannels() {
+	g.HandlePluginChannel(ChannelBrand, g.handleBrand)
+	g.HandlePluginChannel(ChannelRegister, g.handleRegister)
+	g.HandlePluginChannel(ChannelUnregister, g.handleUnregister)
+}
+
+func (g *Game) processServerboundPluginMessage(source *Player, p *packet.ServerboundPluginMessage) {
+	g.pluginChannelsLock.RLock()
+	handler, ok := g.pluginChannels[p.Channel]
+	g.pluginChannelsLock.RUnlock()
+
+	if !ok {
+		g.log.Debug().
+			Stringer("channel", p.Channel).
+			Msg("ignoring plugin message")
+		return
+	}
+	handler(source, p.Data)
+}
+
+func (g *Game) handleBrand(source *Player, data []byte) {
+	rd := bytes.NewReader(data)
+	if err := func() (e error) {
+		defer func() {
+			if rec := recover(); rec != nil {
+				if recErr, ok := rec.(error); ok {
+					e = recErr
+				} else {
+					panic(rec)
+				}
+			}
+		}()
+		brand := packet.Decoder{Rd: rd}.ReadString("client brand")
+
+		source.Lock()
+		source.client.brand = brand
+		source.Unlock()
+		return
+	}(); err != nil {
+		g.log.Error().
+			Err(err).
+			Msg("client sent invalid brand, ignoring packet")
+	}
+}
+
+// handleRegister adds the channels in the given data, which are separated by null
+// bytes, to the channels that the client supports.
+func (g *Game) handleRegister(source *Player, data []byte) {
+	channels := parseChannels(data)
+
+	source.Lock()
+	defer source.Unlock()
+
+	if source.client.channels == nil {
+		source.client.channels = make(map[id.ID]struct{})
+	}
+	for _, channel := range channels {
+		source.client.channels[channel] = struct{}{}
+	}
+}
+
+// handleUnregister removes the channels in the given data, which are separated by
+// null bytes, from the channels that the client supports.
+func (g *Game) handleUnregister(source *Player, data []byte) {
+	channels := parseChannels(data)
+
+	source.Lock()
+	defer source.Unlock()
+
+	for _, channel := range channels {
+		delete(source.client.channels, channel)
+	}
+}
+
+// parseChannels parses the null separated channel names of a register or
+// unregister message.
+func parseChannels(data []byte) []id.ID {
+	var channels []id.ID
+	for _, name := range strings.Split(string(data), "\x00") {
+		if name != "" {
+			channels = append(channels, id.ParseID(name))
+		}
+	}
+	return channels
+}
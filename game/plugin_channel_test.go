@@ -0,0 +1,55 @@
+package game
+
+import (
+	"github.com/tsatke/mcserver/game/id"
+	"github.com/tsatke/mcserver/network/packet"
+)
+
+func (suite *GameSuite) TestPluginChannels() {
+	g, err := New(suite.world)
+	suite.Require().NoError(err)
+
+	p, client := suite.connectedPlayer(g)
+	defer func() { _ = client.Close() }()
+
+	custom := id.ParseID("test:custom")
+	received := make(chan []byte, 1)
+	g.HandlePluginChannel(custom, func(source *Player, data []byte) {
+		suite.Equal(p, source)
+		// the lock of the player is not held
+		suite.False(source.SupportsChannel(custom))
+		received <- data
+	})
+
+	g.processPacket(p, &packet.ServerboundPluginMessage{
+		Channel: custom,
+		Data:    []byte("hello"),
+	})
+	suite.Equal([]byte("hello"), <-received)
+
+	g.processPacket(p, &packet.ServerboundPluginMessage{
+		Channel: ChannelBrand,
+		Data:    []byte("\x07vanilla"),
+	})
+	suite.Equal("vanilla", p.Brand())
+
+	g.processPacket(p, &packet.ServerboundPluginMessage{
+		Channel: ChannelRegister,
+		Data:    []byte("test:custom\x00test:other"),
+	})
+	suite.True(p.SupportsChannel(custom))
+	suite.True(p.SupportsChannel(id.ParseID("test:other")))
+	g.processPacket(p, &packet.ServerboundPluginMessage{
+		Channel: ChannelUnregister,
+		Data:    []byte("test:other"),
+	})
+	suite.True(p.SupportsChannel(custom))
+	suite.False(p.SupportsChannel(id.ParseID("test:other")))
+
+	g.SendPluginMessage(p, custom, []byte("world"))
+	packetID, payload := suite.readFrame(client)
+	suite.Equal(packet.IDClientboundPluginMessage, packetID)
+	dec := packet.Decoder{Rd: payload}
+	suite.Equal(custom, dec.ReadID("channel"))
+	suite.Equal([]byte("world"), dec.ReadRemainingBytes("data"))
+}
@@ -1,4 +1,7 @@
 package game
 
+import "time"
+
 func (g *Game) tick() {
+	g.tickKeepAlive(time.Now())
 }
@@ -0,0 +1,92 @@
+package game
+
+import (
+	"sync"
+	"time"
+)
+
+// tickStatsWindow is the amount of recent ticks that TickStats are computed from,
+// which are 5 seconds if the game keeps up.
+const tickStatsWindow = 100
+
+// TickStats describe how well the tick loop keeps up with TickDuration.
+type TickStats struct {
+	// TPS is the amount of ticks per second over the recent ticks. This is 20 if
+	// the game keeps up, and less if ticks take too long.
+	TPS float64
+	// MeanTickDuration is the mean time that processing the recent ticks took.
+	MeanTickDuration time.Duration
+	// MaxTickDuration is the longest time that processing one of the recent ticks took.
+	MaxTickDuration time.Duration
+	// SkippedTicks is the amount of ticks that were skipped since the game started,
+	// because the game couldn't keep up.
+	SkippedTicks int
+}
+
+// tickRecord is the start and duration of a single tick.
+type tickRecord struct {
+	start    time.Time
+	duration time.Duration
+}
+
+// tickStats records the recent ticks in a ring buffer.
+type tickStats struct {
+	mu      sync.Mutex
+	records [tickStatsWindow]tickRecord
+	// next is the index in records that the next tick is recorded at.
+	next    int
+	count   int
+	skipped int
+}
+
+// record records a tick that started at the given time and took the given duration,
+// and the given amount of ticks that were skipped before it.
+func (s *tickStats) record(start time.Time, duration time.Duration, skipped int) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.records[s.next] = tickRecord{
+		start:    start,
+		duration: duration,
+	}
+	s.next = (s.next + 1) % tickStatsWindow
+	if s.count < tickStatsWindow {
+		s.count++
+	}
+	s.skipped += skipped
+}
+
+func (s *tickStats) stats() TickStats {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	stats := TickStats{
+		SkippedTicks: s.skipped,
+	}
+	if s.count == 0 {
+		return stats
+	}
+
+	var total time.Duration
+	oldest := s.records[(s.next-s.count+tickStatsWindow)%tickStatsWindow]
+	newest := s.records[(s.next-1+tickStatsWindow)%tickStatsWindow]
+	for i := 0; i < s.count; i++ {
+		duration := s.records[i].duration
+		total += duration
+		if duration > stats.MaxTickDuration {
+			stats.MaxTickDuration = duration
+		}
+	}
+	stats.MeanTickDuration = total / time.Duration(s.count)
+
+	// the last tick is not over yet, so it doesn't count
+	if elapsed := newest.start.Sub(oldest.start); elapsed > 0 {
+		stats.TPS = float64(s.count-1) / elapsed.Seconds()
+	}
+	return stats
+}
+
+// TickStats returns statistics about the recent ticks of the game.
+func (g *Game) TickStats() TickStats {
+	return g.tickStats.stats()
+}
@@ -0,0 +1,29 @@
+package game
+
+import (
+	"time"
+)
+
+func (suite *GameSuite) TestTickStats() {
+	var s tickStats
+	suite.Equal(TickStats{}, s.stats())
+
+	start := time.Now()
+	for i := 0; i < tickStatsWindow+10; i++ {
+		// every tenth tick takes long, all others keep up
+		duration := time.Millisecond
+		if i%10 == 0 {
+			duration = 20 * time.Millisecond
+		}
+		s.record(start.Add(time.Duration(i)*TickDuration), duration, 0)
+	}
+	s.record(start.Add(time.Duration(tickStatsWindow+12)*TickDuration), time.Millisecond, 2)
+
+	stats := s.stats()
+	suite.Equal(2, stats.SkippedTicks)
+	suite.Equal(20*time.Millisecond, stats.MaxTickDuration)
+	// the window holds ticks 11 to 109 and the last one, 9 of which took long
+	suite.Equal((9*20+91)*time.Millisecond/100, stats.MeanTickDuration)
+	// 99 ticks in 101 tick durations, since 2 ticks were skipped
+	suite.InDelta(99/(101*TickDuration.Seconds()), stats.TPS, 0.001)
+}
@@ -1,12 +1,16 @@
 package world
 
 import (
+	"compress/gzip"
+	"encoding/binary"
 	"errors"
 	"fmt"
+	"os"
 	"path/filepath"
 	"sync"
 
 	"github.com/spf13/afero"
+	"github.com/tsatke/nbt"
 
 	"github.com/tsatke/mcserver/game/voxel"
 )
@@ -14,6 +18,8 @@ import (
 type vanillaWorld struct {
 	fs afero.Fs
 
+	seed int64
+
 	regionsLock sync.Mutex
 	regions     map[voxel.V2]*vanillaRegion
 
@@ -27,6 +33,9 @@ func LoadVanilla(fs afero.Fs) (World, error) {
 	if err := w.validate(); err != nil {
 		return nil, fmt.Errorf("validate: %w", err)
 	}
+	if err := w.loadLevel(); err != nil {
+		return nil, fmt.Errorf("load level: %w", err)
+	}
 
 	return w, nil
 }
@@ -71,7 +80,35 @@ func (w *vanillaWorld) Unload(v2 voxel.V2) {
 }
 
 func (w *vanillaWorld) Seed() int64 {
-	panic("implement me")
+	return w.seed
+}
+
+// loadLevel reads the world properties from the level.dat file. If there is
+// no such file, the world keeps its zero values.
+func (w *vanillaWorld) loadLevel() error {
+	f, err := w.fs.Open("level.dat")
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return nil
+		}
+		return fmt.Errorf("open level.dat: %w", err)
+	}
+	defer func() { _ = f.Close() }()
+
+	decompressorReader, err := gzip.NewReader(f)
+	if err != nil {
+		return fmt.Errorf("create decompressor: %w", err)
+	}
+	tag, err := nbt.NewDecoder(decompressorReader, binary.BigEndian).ReadTag()
+	if err != nil {
+		return fmt.Errorf("decode nbt: %w", err)
+	}
+
+	mapper := nbt.NewSimpleMapper(tag)
+	if err := mapper.MapLong("Data.WorldGenSettings.seed", &w.seed); err != nil {
+		return fmt.Errorf("map seed: %w", err)
+	}
+	return nil
 }
 
 func (w *vanillaWorld) validate() error {
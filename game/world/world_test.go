@@ -3,6 +3,7 @@ package world
 import (
 	"testing"
 
+	"github.com/spf13/afero"
 	"github.com/stretchr/testify/suite"
 )
 
@@ -13,3 +14,9 @@ func TestWorldSuite(t *testing.T) {
 type WorldSuite struct {
 	suite.Suite
 }
+
+func (suite *WorldSuite) TestVanillaSeed() {
+	w, err := LoadVanilla(afero.NewBasePathFs(afero.NewOsFs(), "../testdata/maps/world01"))
+	suite.Require().NoError(err)
+	suite.NotZero(w.Seed())
+}
@@ -0,0 +1,105 @@
+package mcserver
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/binary"
+	"net"
+	"strconv"
+	"strings"
+	"unicode/utf16"
+
+	"github.com/tsatke/mcserver/network"
+	"github.com/tsatke/mcserver/network/packet"
+)
+
+const (
+	// legacyPingID is the first byte that pre-netty clients (1.6 and older) send
+	// when they ping the server. A modern client would start with the packet length
+	// of the handshake, which realistically is never 0xFE.
+	legacyPingID = 0xFE
+	// legacyKickID is the ID of the kick packet, which is used to answer a
+	// legacy ping.
+	legacyKickID = 0xFF
+)
+
+// bufferedConn is a net.Conn whose reads go through a bufio.Reader, so that incoming
+// data can be peeked at before it is consumed.
+type bufferedConn struct {
+	net.Conn
+	rd *bufio.Reader
+	// remote is the address of the client, if it differs from the remote address
+	// of the connection, e.g. because the client connected through a proxy.
+	remote net.Addr
+}
+
+func newBufferedConn(c net.Conn) bufferedConn {
+	return bufferedConn{
+		Conn: c,
+		rd:   bufio.NewReader(c),
+	}
+}
+
+func (c bufferedConn) Read(p []byte) (int, error) {
+	return c.rd.Read(p)
+}
+
+func (c bufferedConn) RemoteAddr() net.Addr {
+	if c.remote != nil {
+		return c.remote
+	}
+	return c.Conn.RemoteAddr()
+}
+
+// isLegacyPing peeks at the first byte of the given connection and determines whether
+// a pre-netty client is trying to ping the server.
+func isLegacyPing(c bufferedConn) (bool, error) {
+	first, err := c.rd.Peek(1)
+	if err != nil {
+		return false, err
+	}
+	return first[0] == legacyPingID, nil
+}
+
+// handleLegacyPing answers a legacy server list ping with a kick packet, which contains
+// the §1-delimited server information that clients from 1.4 to 1.6 expect, built from
+// the same data as the status response. Older clients will display the response as
+// garbled MOTD, but the vanilla server answers them the same way.
+func (s *MCServer) handleLegacyPing(c bufferedConn) {
+	defer func() { _ = c.Close() }()
+
+	// the client sends a few more bytes (0x01, a plugin message with the host, ...),
+	// which we don't need to answer, so discard whatever arrived already
+	_, _ = c.rd.Discard(c.rd.Buffered())
+
+	resp := s.statusResponse(network.AddrIP(c.RemoteAddr()), packet.LatestVersion)
+	kickMessage := strings.Join([]string{
+		"§1",
+		strconv.Itoa(resp.Version.Protocol),
+		resp.Version.Name,
+		resp.Description.PlainText(),
+		strconv.Itoa(resp.Players.Online),
+		strconv.Itoa(resp.Players.Max),
+	}, "\x00")
+
+	if _, err := c.Write(encodeLegacyKick(kickMessage)); err != nil {
+		s.log.Debug().
+			Err(err).
+			Msg("write legacy ping response failed")
+		return
+	}
+	s.log.Debug().
+		Msg("answered legacy ping")
+}
+
+// encodeLegacyKick encodes a legacy kick packet with the given message, which is
+// encoded as UTF-16BE string, prefixed with its length in code units.
+func encodeLegacyKick(message string) []byte {
+	encoded := utf16.Encode([]rune(message))
+
+	var buf bytes.Buffer
+	buf.WriteByte(legacyKickID)
+	_ = binary.Write(&buf, binary.BigEndian, uint16(len(encoded)))
+	_ = binary.Write(&buf, binary.BigEndian, encoded)
+	return buf.Bytes()
+}
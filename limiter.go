@@ -0,0 +1,218 @@
+package mcserver
+
+import (
+	"math"
+	"net"
+	"sync"
+	"time"
+)
+
+const (
+	// limiterPruneInterval is the interval in which login buckets that are full
+	// again are removed from the limiter, so that it doesn't grow indefinitely.
+	limiterPruneInterval = time.Minute
+)
+
+// ConnectionStats are the current numbers of the connection limiter of the server,
+// see MCServer.ConnectionStats.
+type ConnectionStats struct {
+	// Connections is the amount of connections that are currently open.
+	Connections int
+	// RejectedConnections is the amount of connections that were closed right
+	// after they were accepted, because the server had too many connections.
+	RejectedConnections uint64
+	// RejectedPerIP is the amount of connections that were rejected, because
+	// there were too many connections from the same IP.
+	RejectedPerIP uint64
+	// ThrottledLogins is the amount of login attempts that were rejected, because
+	// the client tried to log in too often.
+	ThrottledLogins uint64
+}
+
+// limiter limits the amount of open connections, globally and per IP, as well as
+// the rate of login attempts per IP, using a token bucket for every IP. A limit that
+// is zero or negative is disabled.
+type limiter struct {
+	maxConnections      int
+	maxConnectionsPerIP int
+	// loginRate is the amount of logins per second that one IP can attempt.
+	loginRate float64
+	// loginBurst is the amount of logins that one IP can attempt at once.
+	loginBurst int
+
+	// mu guards all fields below.
+	mu sync.Mutex
+	// connectionsPerIP holds the amount of open connections of every IP that has
+	// open connections.
+	connectionsPerIP map[string]int
+	// loginBuckets holds the token buckets of all IPs that attempted to log in
+	// since the last prune.
+	loginBuckets map[string]*tokenBucket
+	lastPrune    time.Time
+	stats        ConnectionStats
+}
+
+func newLimiter(maxConnections, maxConnectionsPerIP int, loginRate float64, loginBurst int) *limiter {
+	return &limiter{
+		maxConnections:      maxConnections,
+		maxConnectionsPerIP: maxConnectionsPerIP,
+		loginRate:           loginRate,
+		loginBurst:          loginBurst,
+		connectionsPerIP:    make(map[string]int),
+		loginBuckets:        make(map[string]*tokenBucket),
+	}
+}
+
+// Stats returns the current numbers of this limiter.
+func (l *limiter) Stats() ConnectionStats {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	return l.stats
+}
+
+// acquireConnection reserves a slot for a new connection. If the server already has
+// the maximum amount of connections, false is returned. Otherwise, releaseConnection
+// must be called when the connection is closed.
+func (l *limiter) acquireConnection() bool {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if l.maxConnections > 0 && l.stats.Connections >= l.maxConnections {
+		l.stats.RejectedConnections++
+		return false
+	}
+	l.stats.Connections++
+	return true
+}
+
+func (l *limiter) releaseConnection() {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	l.stats.Connections--
+}
+
+// acquireIP reserves a slot for a new connection from the given IP. If there already
+// are the maximum amount of connections from the IP, false is returned. Otherwise,
+// the returned function must be called when the connection is closed.
+func (l *limiter) acquireIP(ip net.IP) (func(), bool) {
+	if l.maxConnectionsPerIP <= 0 {
+		return func() {}, true
+	}
+
+	key := ip.String()
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if l.connectionsPerIP[key] >= l.maxConnectionsPerIP {
+		l.stats.RejectedPerIP++
+		return nil, false
+	}
+	l.connectionsPerIP[key]++
+	return func() {
+		l.mu.Lock()
+		defer l.mu.Unlock()
+
+		l.connectionsPerIP[key]--
+		if l.connectionsPerIP[key] <= 0 {
+			delete(l.connectionsPerIP, key)
+		}
+	}, true
+}
+
+// allowLogin determines whether the given IP may attempt to log in at the given time,
+// and takes a token from the bucket of the IP if so.
+func (l *limiter) allowLogin(ip net.IP, now time.Time) bool {
+	if l.loginRate <= 0 {
+		return true
+	}
+
+	key := ip.String()
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if now.Sub(l.lastPrune) >= limiterPruneInterval {
+		l.prune(now)
+	}
+
+	bucket, ok := l.loginBuckets[key]
+	if !ok {
+		bucket = newTokenBucket(l.loginRate, l.loginBurst, now)
+		l.loginBuckets[key] = bucket
+	}
+	if !bucket.take(now) {
+		l.stats.ThrottledLogins++
+		return false
+	}
+	return true
+}
+
+// prune removes all login buckets that are full at the given time, since they
+// behave the same as a new bucket. l.mu must be held.
+func (l *limiter) prune(now time.Time) {
+	for key, bucket := range l.loginBuckets {
+		if bucket.full(now) {
+			delete(l.loginBuckets, key)
+		}
+	}
+	l.lastPrune = now
+}
+
+// tokenBucket is a bucket that holds up to burst tokens, and is refilled with rate
+// tokens per second.
+type tokenBucket struct {
+	rate   float64
+	burst  float64
+	tokens float64
+	last   time.Time
+}
+
+func newTokenBucket(rate float64, burst int, now time.Time) *tokenBucket {
+	if burst < 1 {
+		burst = 1
+	}
+	return &tokenBucket{
+		rate:   rate,
+		burst:  float64(burst),
+		tokens: float64(burst),
+		last:   now,
+	}
+}
+
+func (b *tokenBucket) refill(now time.Time) {
+	if elapsed := now.Sub(b.last); elapsed > 0 {
+		b.tokens = math.Min(b.burst, b.tokens+elapsed.Seconds()*b.rate)
+		b.last = now
+	}
+}
+
+// take takes a token from this bucket, if there is one.
+func (b *tokenBucket) take(now time.Time) bool {
+	b.refill(now)
+	if b.tokens < 1 {
+		return false
+	}
+	b.tokens--
+	return true
+}
+
+func (b *tokenBucket) full(now time.Time) bool {
+	b.refill(now)
+	return b.tokens >= b.burst
+}
+
+// limitedConn is a net.Conn that calls a release function once it is closed for
+// the first time.
+type limitedConn struct {
+	net.Conn
+	release func()
+	once    sync.Once
+}
+
+func (c *limitedConn) Close() error {
+	c.once.Do(c.release)
+	return c.Conn.Close()
+}
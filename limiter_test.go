@@ -0,0 +1,65 @@
+package mcserver
+
+import (
+	"net"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestLimiterConnections(t *testing.T) {
+	assert := assert.New(t)
+
+	l := newLimiter(2, 0, 0, 0)
+	assert.True(l.acquireConnection())
+	assert.True(l.acquireConnection())
+	assert.False(l.acquireConnection())
+	l.releaseConnection()
+	assert.True(l.acquireConnection())
+
+	assert.Equal(ConnectionStats{
+		Connections:         2,
+		RejectedConnections: 1,
+	}, l.Stats())
+}
+
+func TestLimiterConnectionsPerIP(t *testing.T) {
+	assert := assert.New(t)
+
+	ip1 := net.ParseIP("203.0.113.7")
+	ip2 := net.ParseIP("2001:db8::1")
+
+	l := newLimiter(0, 1, 0, 0)
+	release, ok := l.acquireIP(ip1)
+	assert.True(ok)
+	_, ok = l.acquireIP(ip1)
+	assert.False(ok)
+	_, ok = l.acquireIP(ip2)
+	assert.True(ok)
+
+	release()
+	_, ok = l.acquireIP(ip1)
+	assert.True(ok)
+	assert.EqualValues(1, l.Stats().RejectedPerIP)
+}
+
+func TestLimiterLoginRate(t *testing.T) {
+	assert := assert.New(t)
+
+	ip := net.ParseIP("203.0.113.7")
+	now := time.Now()
+
+	l := newLimiter(0, 0, 0.5, 2)
+	assert.True(l.allowLogin(ip, now))
+	assert.True(l.allowLogin(ip, now))
+	assert.False(l.allowLogin(ip, now))
+	assert.True(l.allowLogin(net.ParseIP("203.0.113.8"), now), "other IPs must not be throttled")
+	assert.False(l.allowLogin(ip, now.Add(time.Second)))
+	assert.True(l.allowLogin(ip, now.Add(2*time.Second)))
+	assert.EqualValues(2, l.Stats().ThrottledLogins)
+
+	// full buckets are removed
+	l.prune(now.Add(time.Hour))
+	assert.Empty(l.loginBuckets)
+}
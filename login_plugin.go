@@ -0,0 +1,301 @@
+package mcserver
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"sync"
+	"time"
+
+	"github.com/tsatke/mcserver/auth"
+	"github.com/tsatke/mcserver/game/id"
+	"github.com/tsatke/mcserver/network"
+	"github.com/tsatke/mcserver/network/packet"
+)
+
+// LoginPluginHandler is called for every player that logs in, after the player was
+// authenticated and before the login succeeds. The handler can ask the client
+// questions on its login plugin channel with the given requester. The login only
+// succeeds after all handlers returned. If a handler returns an error, the login
+// is rejected and the error is logged. The player is only shown a generic message,
+// so that errors don't leak details of the server. The given context is cancelled
+// when the login plugin timeout is exceeded or the login failed.
+type LoginPluginHandler func(ctx context.Context, requester LoginPluginRequester) error
+
+// LoginPluginResponse is the answer of a client to a login plugin request.
+type LoginPluginResponse struct {
+	// Understood is false if the client doesn't know the channel of the request.
+	// Vanilla clients don't understand any channel.
+	Understood bool
+	// Data is the payload of the response, whose format depends on the channel.
+	Data []byte
+}
+
+// LoginPluginRequester sends login plugin requests on the channel of a
+// LoginPluginHandler to a client that is logging in.
+type LoginPluginRequester struct {
+	// Profile is the profile of the player that is logging in.
+	Profile auth.Profile
+	// IP is the IP address of the player that is logging in.
+	IP net.IP
+
+	ctx     context.Context
+	channel id.ID
+	session *loginPluginSession
+}
+
+// Request sends a login plugin request with the given data to the client, and
+// returns a channel that receives the response of the client as soon as it arrives.
+// If the login failed in the meantime, the channel never receives a response.
+func (r LoginPluginRequester) Request(data []byte) (<-chan LoginPluginResponse, error) {
+	return r.session.request(r.channel, data)
+}
+
+// RequestAndWait sends a login plugin request with the given data to the client,
+// and waits for its response or the cancellation of the context of the handler.
+func (r LoginPluginRequester) RequestAndWait(data []byte) (LoginPluginResponse, error) {
+	responses, err := r.Request(data)
+	if err != nil {
+		return LoginPluginResponse{}, err
+	}
+	select {
+	case response := <-responses:
+		return response, nil
+	case <-r.ctx.Done():
+		return LoginPluginResponse{}, fmt.Errorf("wait for response on %s: %w", r.channel, r.ctx.Err())
+	}
+}
+
+// HandleLoginPlugin registers the given handler for the given login plugin channel.
+// If there already is a handler for the channel, it is replaced. The handler is
+// used for all logins that happen after this call.
+func (s *MCServer) HandleLoginPlugin(channel id.ID, handler LoginPluginHandler) {
+	s.loginPluginsLock.Lock()
+	defer s.loginPluginsLock.Unlock()
+
+	s.loginPlugins[channel] = handler
+}
+
+// loginPluginHandlers returns a copy of the registered login plugin handlers.
+func (s *MCServer) loginPluginHandlers() map[id.ID]LoginPluginHandler {
+	s.loginPluginsLock.Lock()
+	defer s.loginPluginsLock.Unlock()
+
+	handlers := make(map[id.ID]LoginPluginHandler, len(s.loginPlugins))
+	for channel, handler := range s.loginPlugins {
+		handlers[channel] = handler
+	}
+	return handlers
+}
+
+// loginPluginSession sends the login plugin requests of one connection, and reads
+// and distributes the responses. Message IDs are unique within the session.
+type loginPluginSession struct {
+	conn *network.Conn
+	// sent receives a value for every request that was sent, so that exactly one
+	// response is read for it.
+	sent chan struct{}
+	// stop is closed when run returns, so that no more responses are read.
+	stop chan struct{}
+
+	// timeout is the time that the client has to answer a single request.
+	timeout time.Duration
+
+	// mu guards nextMessageID and pending.
+	mu            sync.Mutex
+	nextMessageID int
+	// pending holds the requests that were not answered yet by their message ID.
+	pending map[int]pendingRequest
+}
+
+// pendingRequest is a login plugin request that was not answered yet.
+type pendingRequest struct {
+	// responses receives the response to the request.
+	responses chan LoginPluginResponse
+	// deadline is the time until which the client has to answer the request.
+	deadline time.Time
+}
+
+func newLoginPluginSession(conn *network.Conn) *loginPluginSession {
+	return &loginPluginSession{
+		conn:    conn,
+		sent:    make(chan struct{}),
+		pending: make(map[int]pendingRequest),
+	}
+}
+
+func (l *loginPluginSession) request(channel id.ID, data []byte) (<-chan LoginPluginResponse, error) {
+	responses := make(chan LoginPluginResponse, 1)
+
+	l.mu.Lock()
+	messageID := l.nextMessageID
+	l.nextMessageID++
+	l.pending[messageID] = pendingRequest{
+		responses: responses,
+		deadline:  time.Now().Add(l.timeout),
+	}
+	l.mu.Unlock()
+
+	if err := l.conn.WritePacket(packet.ClientboundLoginPluginRequest{
+		MessageID: messageID,
+		Channel:   channel,
+		Data:      data,
+	}); err != nil {
+		l.mu.Lock()
+		delete(l.pending, messageID)
+		l.mu.Unlock()
+		return nil, fmt.Errorf("write login plugin request: %w", err)
+	}
+
+	select {
+	case l.sent <- struct{}{}:
+	case <-l.stop:
+		return nil, fmt.Errorf("login plugin requests are not read anymore")
+	}
+	return responses, nil
+}
+
+func (l *loginPluginSession) pendingCount() int {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	return len(l.pending)
+}
+
+// nextDeadline returns the earliest deadline of all pending requests, and false
+// if there are no pending requests.
+func (l *loginPluginSession) nextDeadline() (time.Time, bool) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	var next time.Time
+	for _, request := range l.pending {
+		if next.IsZero() || request.deadline.Before(next) {
+			next = request.deadline
+		}
+	}
+	return next, !next.IsZero()
+}
+
+// run calls all given handlers concurrently for the player with the given profile,
+// and reads the responses to their requests until all handlers returned and all
+// requests were answered. The first error of a handler is returned. The handlers
+// have the given timeout to complete, and the client has the same time to answer
+// each request.
+func (l *loginPluginSession) run(ctx context.Context, timeout time.Duration, profile auth.Profile, handlers map[id.ID]LoginPluginHandler) error {
+	if len(handlers) == 0 {
+		return nil
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+	l.timeout = timeout
+	l.stop = make(chan struct{})
+	defer close(l.stop)
+
+	done := make(chan error, len(handlers))
+	for channel, handler := range handlers {
+		requester := LoginPluginRequester{
+			Profile: profile,
+			IP:      l.conn.IP(),
+			ctx:     ctx,
+			channel: channel,
+			session: l,
+		}
+		go func(channel id.ID, handler LoginPluginHandler) {
+			if err := handler(ctx, requester); err != nil {
+				done <- fmt.Errorf("%s: %w", channel, err)
+				return
+			}
+			done <- nil
+		}(channel, handler)
+	}
+
+	responses := make(chan *packet.ServerboundLoginPluginResponse)
+	readErrs := make(chan error, 1)
+	go l.readResponses(l.stop, responses, readErrs)
+
+	requestTimer := time.NewTimer(timeout)
+	defer requestTimer.Stop()
+
+	// also wait for the responses of requests whose handler gave up waiting, so
+	// that they are not read as play packets
+	for remaining := len(handlers); remaining > 0 || l.pendingCount() > 0; {
+		if !requestTimer.Stop() {
+			select {
+			case <-requestTimer.C:
+			default:
+			}
+		}
+		var requestTimeout <-chan time.Time
+		if deadline, ok := l.nextDeadline(); ok {
+			requestTimer.Reset(time.Until(deadline))
+			requestTimeout = requestTimer.C
+		}
+
+		select {
+		case err := <-done:
+			if err != nil {
+				return err
+			}
+			remaining--
+		case response := <-responses:
+			if err := l.dispatch(response); err != nil {
+				return err
+			}
+		case err := <-readErrs:
+			return err
+		case <-requestTimeout:
+			return fmt.Errorf("login plugin response not received within %s", timeout)
+		case <-ctx.Done():
+			return fmt.Errorf("wait for login plugins: %w", ctx.Err())
+		}
+	}
+	return nil
+}
+
+// readResponses reads one response for every sent request and sends it to the
+// given channel, until stop is closed or reading fails.
+func (l *loginPluginSession) readResponses(stop <-chan struct{}, responses chan<- *packet.ServerboundLoginPluginResponse, errs chan<- error) {
+	for {
+		select {
+		case <-l.sent:
+		case <-stop:
+			return
+		}
+
+		p, err := l.conn.ReadPacket()
+		if err != nil {
+			errs <- fmt.Errorf("read login plugin response: %w", err)
+			return
+		}
+		response, ok := p.(*packet.ServerboundLoginPluginResponse)
+		if !ok {
+			errs <- fmt.Errorf("require login plugin response, but got %s", p.Name())
+			return
+		}
+
+		select {
+		case responses <- response:
+		case <-stop:
+			return
+		}
+	}
+}
+
+// dispatch sends the given response to the request that it answers.
+func (l *loginPluginSession) dispatch(response *packet.ServerboundLoginPluginResponse) error {
+	l.mu.Lock()
+	request, ok := l.pending[response.MessageID]
+	delete(l.pending, response.MessageID)
+	l.mu.Unlock()
+
+	if !ok {
+		return fmt.Errorf("unexpected message id %d", response.MessageID)
+	}
+	request.responses <- LoginPluginResponse{
+		Understood: response.Successful,
+		Data:       response.Data,
+	}
+	return nil
+}
@@ -2,27 +2,41 @@ package mcserver
 
 import (
 	"context"
+	"crypto/rsa"
 	"fmt"
 	"net"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
 	"time"
 
-	"github.com/google/uuid"
 	"github.com/rs/zerolog"
 	"github.com/spf13/afero"
 
+	"github.com/tsatke/mcserver/auth"
 	"github.com/tsatke/mcserver/config"
 	"github.com/tsatke/mcserver/game"
 	"github.com/tsatke/mcserver/game/chat"
+	"github.com/tsatke/mcserver/game/command"
+	"github.com/tsatke/mcserver/game/id"
 	"github.com/tsatke/mcserver/game/world"
 	"github.com/tsatke/mcserver/network"
+	"github.com/tsatke/mcserver/network/capture"
 	"github.com/tsatke/mcserver/network/packet"
 )
 
 const (
 	// ServerVersion is the vanilla server version that is mostly implemented by this server.
-	ServerVersion = "1.16.5"
+	ServerVersion = packet.LatestVersionName
 	// ProtocolVersion is the minecraft protocol version that this server implements.
-	ProtocolVersion = 754
+	// Clients with other versions can connect if their version is supported by
+	// the packet package, see packet.SupportedVersions.
+	ProtocolVersion = packet.LatestVersionNumber
+
+	// proxyHeaderTimeout is the time that a proxy has to send the PROXY protocol
+	// header after the connection was accepted.
+	proxyHeaderTimeout = 5 * time.Second
 )
 
 // MCServer is a minecraft server. It holds things like a logger, a net.Listener, a game.Game and a config.Config,
@@ -31,9 +45,46 @@ type MCServer struct {
 	log      zerolog.Logger
 	addr     string
 	listener net.Listener
+	// queryConn is the connection that queries are answered on, or nil if
+	// queries are disabled.
+	queryConn net.PacketConn
+	// rconListener is the listener that remote console connections are accepted
+	// on, or nil if the remote console is disabled.
+	rconListener net.Listener
+
+	game          *game.Game
+	config        config.Config
+	authenticator auth.Authenticator
+
+	// privateKey is the key that is used for the encryption handshake
+	// if the server is in online mode.
+	privateKey *rsa.PrivateKey
+	// publicKey is the ASN.1 DER encoded public key of privateKey.
+	publicKey []byte
+
+	// proxyProtocol indicates whether incoming connections start with a
+	// PROXY protocol header.
+	proxyProtocol bool
+
+	// limiter limits the amount of connections and the rate of login attempts.
+	limiter *limiter
 
-	game   *game.Game
-	config config.Config
+	// favicon is the data URL of the server icon, or empty if there is none.
+	favicon string
+	// statusHook modifies the status response, if not nil.
+	statusHook StatusHook
+
+	// ready is closed as soon as the game is ready, so that commands can be executed.
+	ready chan struct{}
+	// commandsLock guards commands, and game while it is set.
+	commandsLock sync.Mutex
+	// commands are registered with the game when it is created, see RegisterCommand.
+	commands []*command.Node
+
+	// loginPluginsLock guards loginPlugins.
+	loginPluginsLock sync.Mutex
+	// loginPlugins are the handlers of login plugin channels, see HandleLoginPlugin.
+	loginPlugins map[id.ID]LoginPluginHandler
 }
 
 // New creates a new MCServer with the given config. This server will not use a logger. Use WithLogger if you
@@ -43,12 +94,48 @@ func New(config config.Config, opts ...Option) (*MCServer, error) {
 		log:    zerolog.Nop(),
 		addr:   config.ServerAddr(),
 		config: config,
+		limiter: newLimiter(
+			config.MaxConnections(),
+			config.MaxConnectionsPerIP(),
+			config.LoginRate(),
+			config.LoginBurst(),
+		),
+		loginPlugins: make(map[id.ID]LoginPluginHandler),
+		ready:        make(chan struct{}),
 	}
+	srv.registerDefaultCommands()
 
 	for _, opt := range opts {
 		opt(srv)
 	}
 
+	if (config.RCONEnabled() || srv.rconListener != nil) && config.RCONPassword() == "" {
+		return nil, ErrNoRCONPassword
+	}
+	if level := config.OpPermissionLevel(); level < command.PermissionLevelModerator || level > command.PermissionLevelOwner {
+		return nil, ErrInvalidOpPermissionLevel
+	}
+
+	favicon, err := loadFavicon(config.Icon())
+	if err != nil {
+		return nil, fmt.Errorf("load server icon: %w", err)
+	}
+	srv.favicon = favicon
+
+	if config.OnlineMode() {
+		if err := srv.generateKey(); err != nil {
+			return nil, fmt.Errorf("generate key: %w", err)
+		}
+	}
+
+	if srv.authenticator == nil {
+		if config.OnlineMode() {
+			srv.authenticator = auth.NewSessionServer(config.SessionServer())
+		} else {
+			srv.authenticator = auth.Offline{}
+		}
+	}
+
 	if srv.listener == nil {
 		lis, err := net.Listen("tcp", srv.addr)
 		if err != nil {
@@ -57,6 +144,29 @@ func New(config config.Config, opts ...Option) (*MCServer, error) {
 		srv.listener = lis
 	}
 
+	if config.QueryEnabled() && srv.queryConn == nil {
+		conn, err := net.ListenPacket("udp", config.QueryAddr())
+		if err != nil {
+			// the listener must be closed even if it was given, since the server
+			// can't be started anymore
+			_ = srv.listener.Close()
+			return nil, fmt.Errorf("listen for queries: %w", err)
+		}
+		srv.queryConn = conn
+	}
+
+	if config.RCONEnabled() && srv.rconListener == nil {
+		lis, err := net.Listen("tcp", config.RCONAddr())
+		if err != nil {
+			_ = srv.listener.Close()
+			if srv.queryConn != nil {
+				_ = srv.queryConn.Close()
+			}
+			return nil, fmt.Errorf("listen for rcon: %w", err)
+		}
+		srv.rconListener = lis
+	}
+
 	return srv, nil
 }
 
@@ -75,7 +185,21 @@ func (s *MCServer) Start(ctx context.Context) error {
 	if err := s.prepareGame(ctx); err != nil {
 		return fmt.Errorf("prepare game: %w", err)
 	}
+	close(s.ready)
+
+	if s.queryConn != nil {
+		go s.serveQueries(ctx)
+	}
+	if s.rconListener != nil {
+		go s.serveRCON(ctx)
+	}
 
+	s.log.Info().
+		Int("maxConnections", s.limiter.maxConnections).
+		Int("maxConnectionsPerIP", s.limiter.maxConnectionsPerIP).
+		Float64("loginRate", s.limiter.loginRate).
+		Int("loginBurst", s.limiter.loginBurst).
+		Msg("connection limits")
 	s.log.Info().
 		Str("addr", s.addr).
 		Msg("waiting for incoming connection")
@@ -95,10 +219,23 @@ func (s *MCServer) Start(ctx context.Context) error {
 				return fmt.Errorf("accept: %w", err)
 			}
 		}
+		if !s.limiter.acquireConnection() {
+			stats := s.limiter.Stats()
+			s.log.Warn().
+				IPAddr("from", network.AddrIP(conn.RemoteAddr())).
+				Int("connections", stats.Connections).
+				Uint64("rejected", stats.RejectedConnections).
+				Msg("too many connections, closing connection")
+			_ = conn.Close()
+			continue
+		}
 		s.log.Info().
-			IPAddr("from", conn.RemoteAddr().(*net.TCPAddr).IP).
+			IPAddr("from", network.AddrIP(conn.RemoteAddr())).
 			Msg("incoming connection")
-		go s.handleRequest(conn)
+		go s.handleRequest(ctx, &limitedConn{
+			Conn:    conn,
+			release: s.limiter.releaseConnection,
+		})
 	}
 }
 
@@ -117,12 +254,20 @@ func (s *MCServer) prepareGame(ctx context.Context) error {
 		game.WithLogger(s.log.With().
 			Str("component", "game").
 			Logger()),
+		game.WithMaxPlayers(s.config.MaxPlayers()),
 	)
 	if err != nil {
 		return fmt.Errorf("create game: %w", err)
 	}
 
+	s.commandsLock.Lock()
+	for _, node := range s.commands {
+		g.RegisterCommand(node)
+	}
+	s.commands = nil
 	s.game = g
+	s.commandsLock.Unlock()
+
 	go s.game.Start(ctx)
 	s.log.Debug().
 		Msg("wait for game to be ready")
@@ -137,19 +282,102 @@ func (s *MCServer) prepareGame(ctx context.Context) error {
 	return nil
 }
 
-func (s *MCServer) handleRequest(c net.Conn) {
+func (s *MCServer) handleRequest(ctx context.Context, netConn net.Conn) {
+	c := newBufferedConn(netConn)
+	if s.proxyProtocol {
+		remote, err := s.readProxyHeader(c)
+		if err != nil {
+			s.log.Debug().
+				Err(err).
+				Msg("read proxy header failed, closing connection")
+			_ = c.Close()
+			return
+		}
+		c.remote = remote
+	}
+
+	// all players that connect through a forwarding proxy have the IP of the proxy
+	tooManyConnections := false
+	if s.config.Forwarding() == config.ForwardingNone {
+		release, ok := s.limiter.acquireIP(network.AddrIP(c.RemoteAddr()))
+		if ok {
+			c.Conn = &limitedConn{
+				Conn:    c.Conn,
+				release: release,
+			}
+		} else {
+			s.log.Info().
+				IPAddr("from", network.AddrIP(c.RemoteAddr())).
+				Uint64("rejected", s.limiter.Stats().RejectedPerIP).
+				Msg("too many connections from ip")
+			tooManyConnections = true
+		}
+	}
+
+	// the first byte is read before the connection is created, so the handshake
+	// timeout starts here and is carried into the connection
+	handshakeStart := time.Now()
+	if timeout := s.config.HandshakeTimeout(); timeout > 0 {
+		_ = c.SetReadDeadline(handshakeStart.Add(timeout))
+	}
+	legacy, err := isLegacyPing(c)
+	if netErr, ok := err.(net.Error); ok && netErr.Timeout() {
+		s.log.Info().
+			IPAddr("from", network.AddrIP(c.RemoteAddr())).
+			Stringer("timeout", s.config.HandshakeTimeout()).
+			Msg("client stalled before handshake, closing connection")
+		_ = c.Close()
+		return
+	}
+	if err != nil {
+		s.log.Debug().
+			Err(err).
+			Msg("read first byte failed, closing connection")
+		_ = c.Close()
+		return
+	}
+	if legacy {
+		if tooManyConnections {
+			_ = c.Close()
+			return
+		}
+		s.handleLegacyPing(c)
+		return
+	}
+
+	opts := []network.Option{
+		network.WithQueueSize(s.config.QueueSize()),
+		network.WithOverflowPolicy(s.config.OverflowPolicy()),
+		network.WithPhaseTimeout(packet.PhaseHandshaking, s.config.HandshakeTimeout()),
+		network.WithStartTime(handshakeStart),
+		network.WithPhaseTimeout(packet.PhaseStatus, s.config.StatusTimeout()),
+		network.WithPhaseTimeout(packet.PhaseLogin, s.config.LoginTimeout()),
+		network.WithIdleTimeout(s.config.IdleTimeout()),
+	}
+	if dir := s.config.CaptureDir(); dir != "" {
+		w, err := openCapture(dir, network.AddrIP(c.RemoteAddr()))
+		if err != nil {
+			s.log.Error().
+				Err(err).
+				Msg("open capture failed, packets of this connection are not recorded")
+		} else {
+			opts = append(opts, network.WithCapture(w))
+		}
+	}
+
 	conn := network.NewConn(
 		s.log.With().
-			IPAddr("remote", c.RemoteAddr().(*net.TCPAddr).IP).
+			IPAddr("remote", network.AddrIP(c.RemoteAddr())).
 			Logger(),
 		c,
+		opts...,
 	)
 	p, err := conn.ReadPacket()
 	if err != nil {
 		s.log.Debug().
 			Err(err).
 			Msg("read packet failed, closing connection")
-		_ = c.Close()
+		_ = conn.Close()
 		return
 	}
 
@@ -159,26 +387,144 @@ func (s *MCServer) handleRequest(c net.Conn) {
 			Int("gotID", int(p.ID())).
 			Int("wantID", int(packet.IDServerboundHandshake)).
 			Msg("require handshake packet")
-		_ = c.Close()
+		_ = conn.Close()
 		return
 	}
 
+	version, supported := packet.LookupVersion(handshake.ProtocolVersion)
+	if supported {
+		conn.SetProtocol(version.Protocol)
+	} else {
+		version = packet.LatestVersion
+	}
+
 	switch handshake.NextState {
 	case packet.NextStateStatus:
+		if tooManyConnections {
+			_ = conn.Close()
+			return
+		}
 		conn.TransitionTo(packet.PhaseStatus)
-		s.handleStatusRequest(conn)
+		s.handleStatusRequest(conn, version)
 	case packet.NextStateLogin:
 		conn.TransitionTo(packet.PhaseLogin)
-		s.handleLoginRequest(conn)
+		if tooManyConnections {
+			s.rejectLogin(conn, "Too many connections from your IP address!")
+			return
+		}
+		if !supported {
+			s.rejectVersion(conn, handshake.ProtocolVersion)
+			return
+		}
+		s.handleLoginRequest(ctx, conn, handshake)
 	default:
 		s.log.Error().
 			Stringer("nextstate", handshake.NextState).
 			Msg("invalid next state")
-		_ = c.Close()
+		_ = conn.Close()
 	}
 }
 
-func (s *MCServer) handleStatusRequest(conn *network.Conn) {
+// openCapture creates a new capture file in the given directory for a connection
+// from the given IP. The name of the file is the current time and the IP.
+func openCapture(dir string, ip net.IP) (*capture.Writer, error) {
+	if err := os.MkdirAll(dir, 0750); err != nil {
+		return nil, fmt.Errorf("create directory: %w", err)
+	}
+
+	name := fmt.Sprintf("%s_%s.mccap",
+		time.Now().UTC().Format("20060102-150405.000000000"),
+		strings.ReplaceAll(ip.String(), ":", "-"), // IPv6 addresses are not valid file names on all systems
+	)
+	f, err := os.Create(filepath.Join(dir, filepath.Clean(name)))
+	if err != nil {
+		return nil, fmt.Errorf("create file: %w", err)
+	}
+	w, err := capture.NewWriter(f)
+	if err != nil {
+		_ = f.Close()
+		return nil, err
+	}
+	return w, nil
+}
+
+// readProxyHeader reads the PROXY protocol header from the given connection and
+// returns the address of the client. If the header doesn't contain an address,
+// the remote address of the connection is returned.
+func (s *MCServer) readProxyHeader(c bufferedConn) (net.Addr, error) {
+	if err := c.SetReadDeadline(time.Now().Add(proxyHeaderTimeout)); err != nil {
+		return nil, fmt.Errorf("set deadline: %w", err)
+	}
+	remote, err := network.ReadProxyHeader(c.rd)
+	if err != nil {
+		return nil, err
+	}
+	if err := c.SetReadDeadline(time.Time{}); err != nil {
+		return nil, fmt.Errorf("reset deadline: %w", err)
+	}
+
+	if remote == nil {
+		return c.Conn.RemoteAddr(), nil
+	}
+	s.log.Debug().
+		Stringer("proxy", c.Conn.RemoteAddr()).
+		Stringer("client", remote).
+		Msg("read proxy header")
+	return remote, nil
+}
+
+// rejectVersion rejects the login of a client whose protocol version is not
+// supported. The ID of the disconnect packet is the same in all versions, so
+// the client can display the message.
+func (s *MCServer) rejectVersion(conn *network.Conn, number int) {
+	reason := fmt.Sprintf("Outdated server! I'm still on %s", ServerVersion)
+	if number < packet.SupportedVersions()[0].Number {
+		reason = fmt.Sprintf("Outdated client! Please use %s", ServerVersion)
+	}
+
+	s.log.Info().
+		Int("version", number).
+		Msg("unsupported protocol version, closing connection")
+	s.rejectLogin(conn, reason)
+}
+
+// rejectLogin sends a disconnect message with the given reason to a client in the
+// login phase, which the client displays, and closes the connection.
+func (s *MCServer) rejectLogin(conn *network.Conn, reason string) {
+	_ = conn.WritePacket(packet.ClientboundDisconnectLogin{
+		Reason: chat.Chat{
+			ChatFragment: chat.ChatFragment{
+				Text: reason,
+			},
+		},
+	})
+	_ = conn.Close()
+}
+
+// isBanned determines whether the player with the given profile is banned, either
+// by name or by UUID. Names are compared case insensitively.
+func (s *MCServer) isBanned(profile auth.Profile) bool {
+	for _, banned := range s.config.BannedPlayers() {
+		if strings.EqualFold(banned, profile.Name) || strings.EqualFold(banned, profile.UUID.String()) {
+			return true
+		}
+	}
+	return false
+}
+
+// isOperator determines whether the player with the given profile is an operator,
+// either by name or by UUID. Names are compared case insensitively.
+func (s *MCServer) isOperator(profile auth.Profile) bool {
+	for _, operator := range s.config.Operators() {
+		if strings.EqualFold(operator, profile.Name) || strings.EqualFold(operator, profile.UUID.String()) {
+			return true
+		}
+	}
+	return false
+}
+
+// handleStatusRequest answers the status request of a client with the given version.
+func (s *MCServer) handleStatusRequest(conn *network.Conn, version packet.Version) {
 	_, err := conn.ReadPacket()
 	if err != nil {
 		s.log.Debug().
@@ -189,28 +535,7 @@ func (s *MCServer) handleStatusRequest(conn *network.Conn) {
 	}
 
 	if err := conn.WritePacket(packet.ClientboundResponse{
-		JSONResponse: packet.Response{
-			Version: packet.ResponseVersion{
-				Name:     ServerVersion,
-				Protocol: ProtocolVersion,
-			},
-			Players: packet.ResponsePlayers{
-				Max:    100,
-				Online: s.game.AmountOfConnectedPlayers(),
-				Sample: []packet.ResponsePlayersSample{},
-			},
-			Description: chat.Chat{
-				ChatFragment: chat.ChatFragment{
-					Text: "Timi loves Tanni ",
-				},
-				Extra: []chat.ChatFragment{
-					{
-						Text:  "❤",
-						Color: "red",
-					},
-				},
-			},
-		},
+		JSONResponse: s.statusResponse(conn.IP(), version),
 	}); err != nil {
 		s.log.Debug().
 			Err(err).
@@ -252,7 +577,7 @@ func (s *MCServer) handleStatusRequest(conn *network.Conn) {
 	_ = conn.Close()
 }
 
-func (s *MCServer) handleLoginRequest(conn *network.Conn) {
+func (s *MCServer) handleLoginRequest(ctx context.Context, conn *network.Conn, handshake *packet.ServerboundHandshake) {
 	p, err := conn.ReadPacket()
 	if err != nil {
 		s.log.Debug().
@@ -268,6 +593,7 @@ func (s *MCServer) handleLoginRequest(conn *network.Conn) {
 			Int("gotID", int(p.ID())).
 			Int("wantID", int(packet.IDServerboundLoginStart)).
 			Msg("require login start packet")
+		_ = conn.Close()
 		return
 	}
 
@@ -276,10 +602,97 @@ func (s *MCServer) handleLoginRequest(conn *network.Conn) {
 		Str("username", username).
 		Msg("player trying to connect")
 
-	uid := uuid.New()
+	plugins := newLoginPluginSession(conn)
+
+	var profile auth.Profile
+	switch s.config.Forwarding() {
+	case config.ForwardingBungeeCord:
+		forwarded, err := parseBungeeCordForwarding(handshake.ServerAddress)
+		if err != nil {
+			s.log.Info().
+				Err(err).
+				Str("username", username).
+				Msg("bungeecord forwarding failed, closing connection")
+			s.rejectLogin(conn, "If you wish to use IP forwarding, please enable it in your BungeeCord config as well!")
+			return
+		}
+		forwarded.Profile.Name = username
+		conn.SetIP(forwarded.IP)
+		if s.throttleLogin(conn) {
+			return
+		}
+		profile = forwarded.Profile
+	case config.ForwardingVelocity:
+		forwarded, err := s.requestVelocityForwarding(ctx, plugins)
+		if err != nil {
+			s.log.Info().
+				Err(err).
+				Str("username", username).
+				Msg("velocity forwarding failed, closing connection")
+			s.rejectLogin(conn, "This server requires you to connect with Velocity.")
+			return
+		}
+		conn.SetIP(forwarded.IP)
+		if s.throttleLogin(conn) {
+			return
+		}
+		profile = forwarded.Profile
+	default:
+		if s.throttleLogin(conn) {
+			return
+		}
+		authenticated, err := s.authenticate(ctx, conn, username)
+		if err != nil {
+			s.log.Info().
+				Err(err).
+				Str("username", username).
+				Msg("authentication failed, closing connection")
+			s.rejectLogin(conn, "Failed to verify username!")
+			return
+		}
+		profile = authenticated
+	}
+
+	if s.isBanned(profile) {
+		s.log.Info().
+			Str("username", profile.Name).
+			Msg("player is banned, closing connection")
+		s.rejectLogin(conn, "You are banned from this server.")
+		return
+	}
+	if s.game.AmountOfConnectedPlayers() >= s.config.MaxPlayers() {
+		s.log.Info().
+			Str("username", profile.Name).
+			Msg("server full, closing connection")
+		s.rejectLogin(conn, "Server full! Please try again later.")
+		return
+	}
+
+	if err := plugins.run(ctx, s.config.LoginPluginTimeout(), profile, s.loginPluginHandlers()); err != nil {
+		s.log.Info().
+			Err(err).
+			Str("username", profile.Name).
+			Msg("login plugin failed, closing connection")
+		s.rejectLogin(conn, "Login plugin negotiation failed")
+		return
+	}
+
+	if threshold := s.config.CompressionThreshold(); threshold >= 0 {
+		if err := conn.WritePacket(packet.ClientboundSetCompression{
+			Threshold: threshold,
+		}); err != nil {
+			s.log.Debug().
+				Err(err).
+				Msg("write packet failed, closing connection")
+			_ = conn.Close()
+			return
+		}
+		conn.EnableCompression(threshold)
+	}
+
 	if err := conn.WritePacket(packet.ClientboundLoginSuccess{
-		UUID:     uid, // TODO: get uuid from game data
-		Username: username,
+		UUID:     profile.UUID,
+		Username: profile.Name,
 	}); err != nil {
 		s.log.Debug().
 			Err(err).
@@ -289,6 +702,50 @@ func (s *MCServer) handleLoginRequest(conn *network.Conn) {
 	}
 
 	conn.TransitionTo(packet.PhasePlay)
-	s.game.AddPlayer(game.NewPlayer(uid, username, conn))
+	player := game.NewPlayer(profile.UUID, profile.Name, conn)
+	if s.isOperator(profile) {
+		player.SetPermissionLevel(s.config.OpPermissionLevel())
+	}
+	s.game.AddPlayer(player)
 	// game handles the connection as of here, nothing more to do
 }
+
+// throttleLogin rejects the login of the given connection and returns true, if
+// the IP of the client attempted to log in too often.
+func (s *MCServer) throttleLogin(conn *network.Conn) bool {
+	if s.limiter.allowLogin(conn.IP(), time.Now()) {
+		return false
+	}
+
+	s.log.Info().
+		IPAddr("from", conn.IP()).
+		Uint64("throttled", s.limiter.Stats().ThrottledLogins).
+		Msg("login throttled, closing connection")
+	s.rejectLogin(conn, "Connection throttled! Please wait before reconnecting.")
+	return true
+}
+
+// ConnectionStats returns the current numbers of the connection limits, like the
+// amount of open connections and the amount of rejected connections.
+func (s *MCServer) ConnectionStats() ConnectionStats {
+	return s.limiter.Stats()
+}
+
+// authenticate authenticates the player with the given username with the server's
+// authenticator. In online mode, the connection is encrypted first.
+func (s *MCServer) authenticate(ctx context.Context, conn *network.Conn, username string) (auth.Profile, error) {
+	var serverHash string
+	if s.config.OnlineMode() {
+		sharedSecret, err := s.enableEncryption(conn)
+		if err != nil {
+			return auth.Profile{}, fmt.Errorf("enable encryption: %w", err)
+		}
+		serverHash = auth.ServerHash("", sharedSecret, s.publicKey)
+	}
+
+	return s.authenticator.Authenticate(ctx, auth.Request{
+		Username:   username,
+		ServerHash: serverHash,
+		IP:         conn.IP(),
+	})
+}
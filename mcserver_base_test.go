@@ -2,6 +2,7 @@ package mcserver
 
 import (
 	"bytes"
+	"compress/zlib"
 	"context"
 	"errors"
 	"io"
@@ -45,23 +46,45 @@ type ServerSuite struct {
 	cancelFn func()
 }
 
-func testConfig() config.Config {
+func testViper() *viper.Viper {
 	vp := viper.New()
 	vp.Set(config.KeyGameWorld, "game/testdata/maps/world01")
+	vp.Set(config.KeyNetworkCompressionThreshold, 256)
+	return vp
+}
 
-	cfg := config.New(vp)
+func testConfig() config.Config {
+	cfg := config.New(testViper())
 	return cfg
 }
 
 func (suite *ServerSuite) SetupTest() {
+	suite.startServer(testConfig())
+}
+
+// RestartServer stops the test server and starts a new one, whose config is the
+// test config modified by the given function, with the given additional options.
+// Connections to the old server are not closed until the test finishes.
+func (suite *ServerSuite) RestartServer(configure func(*viper.Viper), opts ...Option) {
+	suite.cancelFn()
+	_ = suite.listener.Close()
+
+	vp := testViper()
+	configure(vp)
+	suite.startServer(config.New(vp), opts...)
+}
+
+func (suite *ServerSuite) startServer(cfg config.Config, opts ...Option) {
+	cfg.ApplyDefaults()
+
 	lis, err := nettest.NewLocalListener("tcp")
 	suite.Require().NoError(err)
 	suite.listener = lis
 
-	srv, err := New(testConfig(),
+	srv, err := New(cfg, append([]Option{
 		WithListener(lis),
 		WithLogger(zerolog.New(zerolog.ConsoleWriter{Out: os.Stdout}).Level(zerolog.TraceLevel).With().Timestamp().Logger()),
-	)
+	}, opts...)...)
 	suite.Require().NoError(err)
 	suite.server = srv
 
@@ -124,6 +147,38 @@ func (suite *ServerSuite) DoSend(to io.Writer, id packet.ID, fn func(packet.Enco
 // DoReceive attempts to receive a message from the server. It attempts to read and
 // call the given function with a timeout of 5 seconds.
 func (suite *ServerSuite) DoReceive(from io.Reader, fn func(packet.ID, packet.Decoder)) {
+	suite.doReceive(func() {
+		dec := packet.Decoder{from}
+		packetLength := dec.ReadVarInt("packet length")
+		packetID := packet.ID(dec.ReadVarInt("packet id"))
+		rd := io.LimitReader(from, int64(packetLength-1))
+
+		fn(packetID, packet.Decoder{rd})
+	})
+}
+
+// DoReceiveCompressed works like DoReceive, but expects the message to be in
+// the compressed packet format.
+func (suite *ServerSuite) DoReceiveCompressed(from io.Reader, fn func(packet.ID, packet.Decoder)) {
+	suite.doReceive(func() {
+		packetLength := packet.Decoder{Rd: from}.ReadVarInt("packet length")
+		frame := io.LimitReader(from, int64(packetLength))
+		var data io.Reader = frame
+		if dataLength := (packet.Decoder{Rd: frame}).ReadVarInt("data length"); dataLength != 0 {
+			zr, err := zlib.NewReader(frame)
+			if err != nil {
+				panic(err)
+			}
+			data = zr
+		}
+		dec := packet.Decoder{Rd: data}
+		packetID := packet.ID(dec.ReadVarInt("packet id"))
+
+		fn(packetID, dec)
+	})
+}
+
+func (suite *ServerSuite) doReceive(fn func()) {
 	ch := make(chan struct{})
 	var err error
 	go func() {
@@ -137,12 +192,7 @@ func (suite *ServerSuite) DoReceive(from io.Reader, fn func(packet.ID, packet.De
 			}
 			close(ch)
 		}()
-		dec := packet.Decoder{from}
-		packetLength := dec.ReadVarInt("packet length")
-		packetID := packet.ID(dec.ReadVarInt("packet id"))
-		rd := io.LimitReader(from, int64(packetLength-1))
-
-		fn(packetID, packet.Decoder{rd})
+		fn()
 	}()
 
 	select {
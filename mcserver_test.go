@@ -1,11 +1,44 @@
 package mcserver
 
 import (
+	"bytes"
+	"context"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/binary"
 	"encoding/json"
+	"fmt"
+	"io/ioutil"
 	"net"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strconv"
+	"strings"
 	"sync"
+	"time"
+	"unicode/utf16"
 
+	"github.com/google/uuid"
+	"github.com/rs/zerolog"
+	"github.com/spf13/viper"
+
+	"github.com/tsatke/mcserver/auth"
+	"github.com/tsatke/mcserver/config"
+	"github.com/tsatke/mcserver/game"
+	"github.com/tsatke/mcserver/game/chat"
+	"github.com/tsatke/mcserver/game/command"
+	"github.com/tsatke/mcserver/game/id"
+	"github.com/tsatke/mcserver/network"
+	"github.com/tsatke/mcserver/network/capture"
+	"github.com/tsatke/mcserver/network/client"
 	"github.com/tsatke/mcserver/network/packet"
+	"github.com/tsatke/mcserver/network/rcon"
 )
 
 func (suite *ServerSuite) TestStatus() {
@@ -38,6 +71,110 @@ func (suite *ServerSuite) TestStatus() {
 	suite.ClosedOrEOF(netConn)
 }
 
+func (suite *ServerSuite) TestStatusOlderVersion() {
+	netConn := suite.DialServer()
+	suite.DoSend(netConn, packet.IDServerboundHandshake, func(enc packet.Encoder) {
+		enc.WriteVarInt("protocol version", 753)
+		enc.WriteString("server address", "localhost")
+		enc.WriteUshort("server port", 12345)
+		enc.WriteVarInt("next state", int(packet.NextStateStatus))
+	})
+	suite.DoSend(netConn, packet.IDServerboundRequest, func(enc packet.Encoder) {
+		// request packet has no fields
+	})
+	suite.DoReceive(netConn, func(id packet.ID, dec packet.Decoder) {
+		suite.Equal(packet.IDClientboundResponse, id)
+		jsonResponse := dec.ReadString("json response")
+
+		resp := packet.Response{}
+		suite.NoError(json.Unmarshal([]byte(jsonResponse), &resp))
+		// the client shows the server as incompatible
+		suite.Equal("1.16.5", resp.Version.Name)
+		suite.Equal(754, resp.Version.Protocol)
+	})
+}
+
+func (suite *ServerSuite) TestLoginUnsupportedVersion() {
+	netConn := suite.DialServer()
+	suite.DoSend(netConn, packet.IDServerboundHandshake, func(enc packet.Encoder) {
+		enc.WriteVarInt("protocol version", 47) // 1.8
+		enc.WriteString("server address", "localhost")
+		enc.WriteUshort("server port", 12345)
+		enc.WriteVarInt("next state", int(packet.NextStateLogin))
+	})
+	suite.DoReceive(netConn, func(id packet.ID, dec packet.Decoder) {
+		suite.Equal(packet.IDClientboundDisconnectLogin, id)
+		suite.Equal(`{"text":"Outdated client! Please use 1.16.5"}`, dec.ReadString("reason"))
+	})
+	suite.ClosedOrEOF(netConn)
+}
+
+func (suite *ServerSuite) TestLogin() {
+	netConn := suite.DialServer()
+	suite.DoSend(netConn, packet.IDServerboundHandshake, func(enc packet.Encoder) {
+		enc.WriteVarInt("protocol version", 754)
+		enc.WriteString("server address", "localhost")
+		enc.WriteUshort("server port", 12345)
+		enc.WriteVarInt("next state", int(packet.NextStateLogin))
+	})
+	suite.DoSend(netConn, packet.IDServerboundLoginStart, func(enc packet.Encoder) {
+		enc.WriteString("username", "aUsername")
+	})
+	suite.DoReceive(netConn, func(id packet.ID, dec packet.Decoder) {
+		suite.Equal(packet.IDClientboundSetCompression, id)
+		suite.EqualValues(256, dec.ReadVarInt("threshold"))
+	})
+	suite.DoReceiveCompressed(netConn, func(id packet.ID, dec packet.Decoder) {
+		suite.Equal(packet.IDClientboundLoginSuccess, id)
+		suite.Equal(auth.OfflineUUID("aUsername"), dec.ReadUUID("uuid"))
+		suite.Equal("aUsername", dec.ReadString("username"))
+	})
+}
+
+func (suite *ServerSuite) TestLoginBanned() {
+	suite.RestartServer(func(vp *viper.Viper) {
+		vp.Set(config.KeyServerBannedPlayers, []string{"AUSERNAME"})
+	})
+
+	netConn := suite.DialServer()
+	suite.DoSend(netConn, packet.IDServerboundHandshake, func(enc packet.Encoder) {
+		enc.WriteVarInt("protocol version", 754)
+		enc.WriteString("server address", "localhost")
+		enc.WriteUshort("server port", 12345)
+		enc.WriteVarInt("next state", int(packet.NextStateLogin))
+	})
+	suite.DoSend(netConn, packet.IDServerboundLoginStart, func(enc packet.Encoder) {
+		enc.WriteString("username", "aUsername")
+	})
+	suite.DoReceive(netConn, func(id packet.ID, dec packet.Decoder) {
+		suite.Equal(packet.IDClientboundDisconnectLogin, id)
+		suite.Equal(`{"text":"You are banned from this server."}`, dec.ReadString("reason"))
+	})
+	suite.ClosedOrEOF(netConn)
+}
+
+func (suite *ServerSuite) TestLoginServerFull() {
+	suite.RestartServer(func(vp *viper.Viper) {
+		vp.Set(config.KeyServerMaxPlayers, 0)
+	})
+
+	netConn := suite.DialServer()
+	suite.DoSend(netConn, packet.IDServerboundHandshake, func(enc packet.Encoder) {
+		enc.WriteVarInt("protocol version", 754)
+		enc.WriteString("server address", "localhost")
+		enc.WriteUshort("server port", 12345)
+		enc.WriteVarInt("next state", int(packet.NextStateLogin))
+	})
+	suite.DoSend(netConn, packet.IDServerboundLoginStart, func(enc packet.Encoder) {
+		enc.WriteString("username", "aUsername")
+	})
+	suite.DoReceive(netConn, func(id packet.ID, dec packet.Decoder) {
+		suite.Equal(packet.IDClientboundDisconnectLogin, id)
+		suite.Equal(`{"text":"Server full! Please try again later."}`, dec.ReadString("reason"))
+	})
+	suite.ClosedOrEOF(netConn)
+}
+
 func (suite *ServerSuite) TestSendInvalidHandshake() {
 	netConn := suite.DialServer()
 	suite.DoSend(netConn, packet.IDServerboundHandshake, func(enc packet.Encoder) {
@@ -148,3 +285,1016 @@ func (suite *ServerSuite) TestContextCancelStopsServer() {
 		_ = conn.Close()
 	}
 }
+
+func (suite *ServerSuite) TestLoginEncrypted() {
+	playerUUID := uuid.New()
+	var wantServerHash string
+	sessionServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/session/minecraft/hasJoined" ||
+			r.URL.Query().Get("username") != "aUsername" ||
+			r.URL.Query().Get("serverId") != wantServerHash {
+			w.WriteHeader(http.StatusNoContent)
+			return
+		}
+		_, _ = fmt.Fprintf(w, `{"id":%q,"name":"aUsername","properties":[]}`, strings.ReplaceAll(playerUUID.String(), "-", ""))
+	}))
+	defer sessionServer.Close()
+
+	suite.RestartServer(func(vp *viper.Viper) {
+		vp.Set(config.KeyServerOnlineMode, true)
+		vp.Set(config.KeyServerSessionServer, sessionServer.URL)
+	})
+
+	netConn := suite.DialServer()
+	suite.DoSend(netConn, packet.IDServerboundHandshake, func(enc packet.Encoder) {
+		enc.WriteVarInt("protocol version", 754)
+		enc.WriteString("server address", "localhost")
+		enc.WriteUshort("server port", 12345)
+		enc.WriteVarInt("next state", int(packet.NextStateLogin))
+	})
+	suite.DoSend(netConn, packet.IDServerboundLoginStart, func(enc packet.Encoder) {
+		enc.WriteString("username", "aUsername")
+	})
+
+	var (
+		publicKeyDER []byte
+		publicKey    *rsa.PublicKey
+		verifyToken  []byte
+	)
+	suite.DoReceive(netConn, func(id packet.ID, dec packet.Decoder) {
+		suite.Equal(packet.IDClientboundEncryptionRequest, id)
+		suite.Equal("", dec.ReadString("server id"))
+		publicKeyDER = dec.ReadPrefixedByteArray("public key")
+		key, err := x509.ParsePKIXPublicKey(publicKeyDER)
+		suite.Require().NoError(err)
+		publicKey = key.(*rsa.PublicKey)
+		verifyToken = dec.ReadPrefixedByteArray("verify token")
+	})
+
+	sharedSecret := make([]byte, 16)
+	_, _ = rand.Read(sharedSecret)
+	// the client would send this to the session server in the join request
+	wantServerHash = auth.ServerHash("", sharedSecret, publicKeyDER)
+	encryptedSecret, err := rsa.EncryptPKCS1v15(rand.Reader, publicKey, sharedSecret)
+	suite.Require().NoError(err)
+	encryptedToken, err := rsa.EncryptPKCS1v15(rand.Reader, publicKey, verifyToken)
+	suite.Require().NoError(err)
+	suite.DoSend(netConn, packet.IDServerboundEncryptionResponse, func(enc packet.Encoder) {
+		enc.WritePrefixedByteArray("shared secret", encryptedSecret)
+		enc.WritePrefixedByteArray("verify token", encryptedToken)
+	})
+
+	block, err := aes.NewCipher(sharedSecret)
+	suite.Require().NoError(err)
+	encryptedConn := cipher.StreamReader{
+		S: network.NewCFB8Decrypter(block, sharedSecret),
+		R: netConn,
+	}
+	suite.DoReceive(encryptedConn, func(id packet.ID, dec packet.Decoder) {
+		suite.Equal(packet.IDClientboundSetCompression, id)
+		suite.EqualValues(256, dec.ReadVarInt("threshold"))
+	})
+	suite.DoReceiveCompressed(encryptedConn, func(id packet.ID, dec packet.Decoder) {
+		suite.Equal(packet.IDClientboundLoginSuccess, id)
+		suite.Equal(playerUUID, dec.ReadUUID("uuid"))
+		suite.Equal("aUsername", dec.ReadString("username"))
+	})
+}
+
+func (suite *ServerSuite) TestLoginEncryptedWrongVerifyToken() {
+	suite.RestartServer(func(vp *viper.Viper) {
+		vp.Set(config.KeyServerOnlineMode, true)
+	})
+
+	netConn := suite.DialServer()
+	suite.DoSend(netConn, packet.IDServerboundHandshake, func(enc packet.Encoder) {
+		enc.WriteVarInt("protocol version", 754)
+		enc.WriteString("server address", "localhost")
+		enc.WriteUshort("server port", 12345)
+		enc.WriteVarInt("next state", int(packet.NextStateLogin))
+	})
+	suite.DoSend(netConn, packet.IDServerboundLoginStart, func(enc packet.Encoder) {
+		enc.WriteString("username", "aUsername")
+	})
+
+	var publicKey *rsa.PublicKey
+	suite.DoReceive(netConn, func(id packet.ID, dec packet.Decoder) {
+		suite.Equal(packet.IDClientboundEncryptionRequest, id)
+		_ = dec.ReadString("server id")
+		key, err := x509.ParsePKIXPublicKey(dec.ReadPrefixedByteArray("public key"))
+		suite.Require().NoError(err)
+		publicKey = key.(*rsa.PublicKey)
+		_ = dec.ReadPrefixedByteArray("verify token")
+	})
+
+	encryptedSecret, err := rsa.EncryptPKCS1v15(rand.Reader, publicKey, make([]byte, 16))
+	suite.Require().NoError(err)
+	encryptedToken, err := rsa.EncryptPKCS1v15(rand.Reader, publicKey, []byte("nope"))
+	suite.Require().NoError(err)
+	suite.DoSend(netConn, packet.IDServerboundEncryptionResponse, func(enc packet.Encoder) {
+		enc.WritePrefixedByteArray("shared secret", encryptedSecret)
+		enc.WritePrefixedByteArray("verify token", encryptedToken)
+	})
+	// server must reject the login, since the verify token doesn't match
+	suite.DoReceive(netConn, func(id packet.ID, dec packet.Decoder) {
+		suite.Equal(packet.IDClientboundDisconnectLogin, id)
+		suite.Equal("Failed to verify username!", dec.ReadChat("reason").Text)
+	})
+	suite.ClosedOrEOF(netConn)
+}
+
+func (suite *ServerSuite) TestLoginBungeeCordForwarding() {
+	suite.RestartServer(func(vp *viper.Viper) {
+		vp.Set(config.KeyServerForwarding, config.ForwardingBungeeCord)
+	})
+
+	netConn := suite.DialServer()
+	suite.DoSend(netConn, packet.IDServerboundHandshake, func(enc packet.Encoder) {
+		enc.WriteVarInt("protocol version", 754)
+		enc.WriteString("server address", "localhost\x00203.0.113.7\x00069a79f444e94726a5befca90e38aaf5\x00[]")
+		enc.WriteUshort("server port", 12345)
+		enc.WriteVarInt("next state", int(packet.NextStateLogin))
+	})
+	suite.DoSend(netConn, packet.IDServerboundLoginStart, func(enc packet.Encoder) {
+		enc.WriteString("username", "Notch")
+	})
+	suite.DoReceive(netConn, func(id packet.ID, dec packet.Decoder) {
+		suite.Equal(packet.IDClientboundSetCompression, id)
+		suite.EqualValues(256, dec.ReadVarInt("threshold"))
+	})
+	suite.DoReceiveCompressed(netConn, func(id packet.ID, dec packet.Decoder) {
+		suite.Equal(packet.IDClientboundLoginSuccess, id)
+		suite.Equal(uuid.MustParse("069a79f4-44e9-4726-a5be-fca90e38aaf5"), dec.ReadUUID("uuid"))
+		suite.Equal("Notch", dec.ReadString("username"))
+	})
+}
+
+func (suite *ServerSuite) TestLoginBungeeCordForwardingMissing() {
+	suite.RestartServer(func(vp *viper.Viper) {
+		vp.Set(config.KeyServerForwarding, config.ForwardingBungeeCord)
+	})
+
+	netConn := suite.DialServer()
+	suite.DoSend(netConn, packet.IDServerboundHandshake, func(enc packet.Encoder) {
+		enc.WriteVarInt("protocol version", 754)
+		enc.WriteString("server address", "localhost")
+		enc.WriteUshort("server port", 12345)
+		enc.WriteVarInt("next state", int(packet.NextStateLogin))
+	})
+	suite.DoSend(netConn, packet.IDServerboundLoginStart, func(enc packet.Encoder) {
+		enc.WriteString("username", "Notch")
+	})
+	suite.DoReceive(netConn, func(id packet.ID, dec packet.Decoder) {
+		suite.Equal(packet.IDClientboundDisconnectLogin, id)
+		suite.Equal(`{"text":"If you wish to use IP forwarding, please enable it in your BungeeCord config as well!"}`, dec.ReadString("reason"))
+	})
+	suite.ClosedOrEOF(netConn)
+}
+
+func (suite *ServerSuite) TestLoginVelocityForwarding() {
+	suite.RestartServer(func(vp *viper.Viper) {
+		vp.Set(config.KeyServerForwarding, config.ForwardingVelocity)
+		vp.Set(config.KeyServerForwardingSecret, "secret")
+	})
+	playerUUID := uuid.MustParse("069a79f4-44e9-4726-a5be-fca90e38aaf5")
+
+	netConn := suite.DialServer()
+	suite.DoSend(netConn, packet.IDServerboundHandshake, func(enc packet.Encoder) {
+		enc.WriteVarInt("protocol version", 754)
+		enc.WriteString("server address", "localhost")
+		enc.WriteUshort("server port", 12345)
+		enc.WriteVarInt("next state", int(packet.NextStateLogin))
+	})
+	suite.DoSend(netConn, packet.IDServerboundLoginStart, func(enc packet.Encoder) {
+		enc.WriteString("username", "Notch")
+	})
+	var messageID int
+	suite.DoReceive(netConn, func(id packet.ID, dec packet.Decoder) {
+		suite.Equal(packet.IDClientboundLoginPluginRequest, id)
+		messageID = dec.ReadVarInt("message id")
+		suite.Equal("velocity:player_info", dec.ReadID("channel").String())
+		suite.EqualValues(1, dec.ReadUbyte("version"))
+	})
+	suite.DoSend(netConn, packet.IDServerboundLoginPluginResponse, func(enc packet.Encoder) {
+		enc.WriteVarInt("message id", messageID)
+		enc.WriteBoolean("successful", true)
+		enc.WriteByteArray("data", velocityForwardingData("secret", playerUUID, "Notch"))
+	})
+	suite.DoReceive(netConn, func(id packet.ID, dec packet.Decoder) {
+		suite.Equal(packet.IDClientboundSetCompression, id)
+		suite.EqualValues(256, dec.ReadVarInt("threshold"))
+	})
+	suite.DoReceiveCompressed(netConn, func(id packet.ID, dec packet.Decoder) {
+		suite.Equal(packet.IDClientboundLoginSuccess, id)
+		suite.Equal(playerUUID, dec.ReadUUID("uuid"))
+		suite.Equal("Notch", dec.ReadString("username"))
+	})
+}
+
+func (suite *ServerSuite) TestLoginVelocityForwardingWrongSecret() {
+	suite.RestartServer(func(vp *viper.Viper) {
+		vp.Set(config.KeyServerForwarding, config.ForwardingVelocity)
+		vp.Set(config.KeyServerForwardingSecret, "secret")
+	})
+
+	netConn := suite.DialServer()
+	suite.DoSend(netConn, packet.IDServerboundHandshake, func(enc packet.Encoder) {
+		enc.WriteVarInt("protocol version", 754)
+		enc.WriteString("server address", "localhost")
+		enc.WriteUshort("server port", 12345)
+		enc.WriteVarInt("next state", int(packet.NextStateLogin))
+	})
+	suite.DoSend(netConn, packet.IDServerboundLoginStart, func(enc packet.Encoder) {
+		enc.WriteString("username", "Notch")
+	})
+	var messageID int
+	suite.DoReceive(netConn, func(id packet.ID, dec packet.Decoder) {
+		suite.Equal(packet.IDClientboundLoginPluginRequest, id)
+		messageID = dec.ReadVarInt("message id")
+		_ = dec.ReadID("channel")
+		_ = dec.ReadUbyte("version")
+	})
+	suite.DoSend(netConn, packet.IDServerboundLoginPluginResponse, func(enc packet.Encoder) {
+		enc.WriteVarInt("message id", messageID)
+		enc.WriteBoolean("successful", true)
+		enc.WriteByteArray("data", velocityForwardingData("forged", uuid.New(), "Notch"))
+	})
+	suite.DoReceive(netConn, func(id packet.ID, dec packet.Decoder) {
+		suite.Equal(packet.IDClientboundDisconnectLogin, id)
+		suite.Equal(`{"text":"This server requires you to connect with Velocity."}`, dec.ReadString("reason"))
+	})
+	suite.ClosedOrEOF(netConn)
+}
+
+func (suite *ServerSuite) TestCaptureAndReplay() {
+	dir, err := ioutil.TempDir("", "mcserver-capture")
+	suite.Require().NoError(err)
+	defer func() { _ = os.RemoveAll(dir) }()
+
+	suite.RestartServer(func(vp *viper.Viper) {
+		vp.Set(config.KeyNetworkCaptureDir, dir)
+	})
+	login := func(netConn net.Conn) {
+		suite.DoReceive(netConn, func(id packet.ID, dec packet.Decoder) {
+			suite.Equal(packet.IDClientboundSetCompression, id)
+			suite.EqualValues(256, dec.ReadVarInt("threshold"))
+		})
+		suite.DoReceiveCompressed(netConn, func(id packet.ID, dec packet.Decoder) {
+			suite.Equal(packet.IDClientboundLoginSuccess, id)
+			suite.Equal(auth.OfflineUUID("aUsername"), dec.ReadUUID("uuid"))
+			suite.Equal("aUsername", dec.ReadString("username"))
+		})
+	}
+
+	netConn := suite.DialServer()
+	suite.DoSend(netConn, packet.IDServerboundHandshake, func(enc packet.Encoder) {
+		enc.WriteVarInt("protocol version", 754)
+		enc.WriteString("server address", "localhost")
+		enc.WriteUshort("server port", 12345)
+		enc.WriteVarInt("next state", int(packet.NextStateLogin))
+	})
+	suite.DoSend(netConn, packet.IDServerboundLoginStart, func(enc packet.Encoder) {
+		enc.WriteString("username", "aUsername")
+	})
+	login(netConn)
+
+	files, err := ioutil.ReadDir(dir)
+	suite.Require().NoError(err)
+	suite.Require().Len(files, 1)
+	captured, err := ioutil.ReadFile(filepath.Join(dir, files[0].Name()))
+	suite.Require().NoError(err)
+
+	// the connection is still open, so only the login records are guaranteed to be
+	// completely written
+	rd, err := capture.NewReader(bytes.NewReader(captured))
+	suite.Require().NoError(err)
+	var loginCapture bytes.Buffer
+	w, err := capture.NewWriter(&loginCapture)
+	suite.Require().NoError(err)
+	var recorded []string
+	for i := 0; i < 4; i++ {
+		r, err := rd.Read()
+		suite.Require().NoError(err)
+		id, err := r.ID()
+		suite.Require().NoError(err)
+		recorded = append(recorded, fmt.Sprintf("%s %s %s", r.Direction, r.Phase, id))
+		suite.Require().NoError(w.Write(r))
+	}
+	suite.Equal([]string{
+		"serverbound Handshaking 0x00",
+		"serverbound Login 0x00",
+		"clientbound Login 0x03",
+		"clientbound Login 0x02",
+	}, recorded)
+
+	// replaying the session into a fresh server logs in the same player
+	suite.RestartServer(func(*viper.Viper) {})
+	rd, err = capture.NewReader(&loginCapture)
+	suite.Require().NoError(err)
+	replayConn := suite.DialServer()
+	suite.Require().NoError(capture.Replay(replayConn, rd, 0))
+	login(replayConn)
+}
+
+// requestRecorder is an authenticator that authenticates players in offline mode
+// and records the requests.
+type requestRecorder chan auth.Request
+
+func (r requestRecorder) Authenticate(ctx context.Context, req auth.Request) (auth.Profile, error) {
+	r <- req
+	return auth.Offline{}.Authenticate(ctx, req)
+}
+
+func (suite *ServerSuite) TestProxyProtocol() {
+	requests := make(requestRecorder, 1)
+	suite.RestartServer(func(*viper.Viper) {}, WithProxyProtocol(), WithAuthenticator(requests))
+
+	netConn := suite.DialServer()
+	_, err := netConn.Write([]byte("PROXY TCP4 203.0.113.7 127.0.0.1 56324 25565\r\n"))
+	suite.Require().NoError(err)
+	suite.DoSend(netConn, packet.IDServerboundHandshake, func(enc packet.Encoder) {
+		enc.WriteVarInt("protocol version", 754)
+		enc.WriteString("server address", "localhost")
+		enc.WriteUshort("server port", 12345)
+		enc.WriteVarInt("next state", int(packet.NextStateLogin))
+	})
+	suite.DoSend(netConn, packet.IDServerboundLoginStart, func(enc packet.Encoder) {
+		enc.WriteString("username", "aUsername")
+	})
+	suite.DoReceive(netConn, func(id packet.ID, dec packet.Decoder) {
+		suite.Equal(packet.IDClientboundSetCompression, id)
+	})
+
+	select {
+	case req := <-requests:
+		suite.Equal("203.0.113.7", req.IP.String())
+	case <-time.After(5 * time.Second):
+		suite.FailNow("player was not authenticated")
+	}
+}
+
+func (suite *ServerSuite) TestProxyProtocolMissingHeader() {
+	suite.RestartServer(func(*viper.Viper) {}, WithProxyProtocol())
+
+	netConn := suite.DialServer()
+	suite.DoSend(netConn, packet.IDServerboundHandshake, func(enc packet.Encoder) {
+		enc.WriteVarInt("protocol version", 754)
+		enc.WriteString("server address", "localhost")
+		enc.WriteUshort("server port", 12345)
+		enc.WriteVarInt("next state", int(packet.NextStateStatus))
+	})
+	suite.ClosedOrEOF(netConn)
+}
+
+func (suite *ServerSuite) TestLegacyPing() {
+	netConn := suite.DialServer()
+	_, err := netConn.Write([]byte{0xFE, 0x01, 0xFA})
+	suite.Require().NoError(err)
+
+	suite.Require().NoError(netConn.SetReadDeadline(time.Now().Add(5 * time.Second)))
+	var header struct {
+		ID     byte
+		Length uint16
+	}
+	suite.Require().NoError(binary.Read(netConn, binary.BigEndian, &header))
+	suite.EqualValues(0xFF, header.ID)
+	encoded := make([]uint16, header.Length)
+	suite.Require().NoError(binary.Read(netConn, binary.BigEndian, encoded))
+
+	fields := strings.Split(string(utf16.Decode(encoded)), "\x00")
+	suite.Equal([]string{
+		"§1",
+		"754",
+		"1.16.5",
+		"Timi loves Tanni ❤",
+		"0",
+		"100",
+	}, fields)
+	suite.ClosedOrEOF(netConn)
+}
+
+func (suite *ServerSuite) TestLoginThrottled() {
+	suite.RestartServer(func(vp *viper.Viper) {
+		vp.Set(config.KeyNetworkLoginRate, 0.001)
+		vp.Set(config.KeyNetworkLoginBurst, 1)
+	})
+
+	login := func(username string) net.Conn {
+		netConn := suite.DialServer()
+		suite.DoSend(netConn, packet.IDServerboundHandshake, func(enc packet.Encoder) {
+			enc.WriteVarInt("protocol version", 754)
+			enc.WriteString("server address", "localhost")
+			enc.WriteUshort("server port", 12345)
+			enc.WriteVarInt("next state", int(packet.NextStateLogin))
+		})
+		suite.DoSend(netConn, packet.IDServerboundLoginStart, func(enc packet.Encoder) {
+			enc.WriteString("username", username)
+		})
+		return netConn
+	}
+
+	netConn := login("aUsername")
+	suite.DoReceive(netConn, func(id packet.ID, dec packet.Decoder) {
+		suite.Equal(packet.IDClientboundSetCompression, id)
+		suite.EqualValues(256, dec.ReadVarInt("threshold"))
+	})
+
+	netConn = login("anotherUsername")
+	suite.DoReceive(netConn, func(id packet.ID, dec packet.Decoder) {
+		suite.Equal(packet.IDClientboundDisconnectLogin, id)
+		suite.Equal(`{"text":"Connection throttled! Please wait before reconnecting."}`, dec.ReadString("reason"))
+	})
+	suite.ClosedOrEOF(netConn)
+	suite.EqualValues(1, suite.server.ConnectionStats().ThrottledLogins)
+}
+
+func (suite *ServerSuite) TestTooManyConnectionsPerIP() {
+	suite.RestartServer(func(vp *viper.Viper) {
+		vp.Set(config.KeyNetworkMaxConnectionsPerIP, 1)
+	})
+
+	first := suite.DialServer()
+	// make sure that the server handles the first connection before the second is dialed
+	suite.DoSend(first, packet.IDServerboundHandshake, func(enc packet.Encoder) {
+		enc.WriteVarInt("protocol version", 754)
+		enc.WriteString("server address", "localhost")
+		enc.WriteUshort("server port", 12345)
+		enc.WriteVarInt("next state", int(packet.NextStateStatus))
+	})
+	suite.DoSend(first, packet.IDServerboundRequest, func(enc packet.Encoder) {})
+	suite.DoReceive(first, func(id packet.ID, dec packet.Decoder) {
+		suite.Equal(packet.IDClientboundResponse, id)
+		_ = dec.ReadString("json response")
+	})
+
+	netConn := suite.DialServer()
+	suite.DoSend(netConn, packet.IDServerboundHandshake, func(enc packet.Encoder) {
+		enc.WriteVarInt("protocol version", 754)
+		enc.WriteString("server address", "localhost")
+		enc.WriteUshort("server port", 12345)
+		enc.WriteVarInt("next state", int(packet.NextStateLogin))
+	})
+	suite.DoReceive(netConn, func(id packet.ID, dec packet.Decoder) {
+		suite.Equal(packet.IDClientboundDisconnectLogin, id)
+		suite.Equal(`{"text":"Too many connections from your IP address!"}`, dec.ReadString("reason"))
+	})
+	suite.ClosedOrEOF(netConn)
+}
+
+func (suite *ServerSuite) TestHandshakeTimeout() {
+	suite.RestartServer(func(vp *viper.Viper) {
+		vp.Set(config.KeyNetworkHandshakeTimeout, 50*time.Millisecond)
+	})
+
+	suite.Run("nothing sent", func() {
+		netConn := suite.DialServer()
+		suite.ClosedOrEOF(netConn)
+	})
+	suite.Run("incomplete handshake", func() {
+		netConn := suite.DialServer()
+		_, err := netConn.Write([]byte{0x10, 0x00})
+		suite.NoError(err)
+		suite.ClosedOrEOF(netConn)
+	})
+}
+
+func (suite *ServerSuite) TestLoginTimeout() {
+	suite.RestartServer(func(vp *viper.Viper) {
+		vp.Set(config.KeyNetworkLoginTimeout, 50*time.Millisecond)
+	})
+
+	netConn := suite.DialServer()
+	suite.DoSend(netConn, packet.IDServerboundHandshake, func(enc packet.Encoder) {
+		enc.WriteVarInt("protocol version", 754)
+		enc.WriteString("server address", "localhost")
+		enc.WriteUshort("server port", 12345)
+		enc.WriteVarInt("next state", int(packet.NextStateLogin))
+	})
+	// no login start
+	suite.ClosedOrEOF(netConn)
+}
+
+func (suite *ServerSuite) TestLoginPlugin() {
+	responses := make(chan string, 2)
+	suite.server.HandleLoginPlugin(id.ParseID("test:hello"), func(ctx context.Context, requester LoginPluginRequester) error {
+		suite.Equal("aUsername", requester.Profile.Name)
+
+		first, err := requester.Request([]byte("first"))
+		if err != nil {
+			return err
+		}
+		second, err := requester.Request([]byte("second"))
+		if err != nil {
+			return err
+		}
+		for _, ch := range []<-chan LoginPluginResponse{first, second} {
+			select {
+			case response := <-ch:
+				suite.True(response.Understood)
+				responses <- string(response.Data)
+			case <-ctx.Done():
+				return ctx.Err()
+			}
+		}
+		return nil
+	})
+
+	netConn := suite.DialServer()
+	suite.DoSend(netConn, packet.IDServerboundHandshake, func(enc packet.Encoder) {
+		enc.WriteVarInt("protocol version", 754)
+		enc.WriteString("server address", "localhost")
+		enc.WriteUshort("server port", 12345)
+		enc.WriteVarInt("next state", int(packet.NextStateLogin))
+	})
+	suite.DoSend(netConn, packet.IDServerboundLoginStart, func(enc packet.Encoder) {
+		enc.WriteString("username", "aUsername")
+	})
+	messageIDs := make(map[string]int)
+	for i := 0; i < 2; i++ {
+		suite.DoReceive(netConn, func(id packet.ID, dec packet.Decoder) {
+			suite.Equal(packet.IDClientboundLoginPluginRequest, id)
+			messageID := dec.ReadVarInt("message id")
+			suite.Equal("test:hello", dec.ReadID("channel").String())
+			messageIDs[string(dec.ReadRemainingBytes("data"))] = messageID
+		})
+	}
+	// answer in reverse order
+	for _, question := range []string{"second", "first"} {
+		question := question
+		suite.DoSend(netConn, packet.IDServerboundLoginPluginResponse, func(enc packet.Encoder) {
+			enc.WriteVarInt("message id", messageIDs[question])
+			enc.WriteBoolean("successful", true)
+			enc.WriteByteArray("data", []byte(question+" answer"))
+		})
+	}
+	suite.DoReceive(netConn, func(id packet.ID, dec packet.Decoder) {
+		suite.Equal(packet.IDClientboundSetCompression, id)
+		suite.EqualValues(256, dec.ReadVarInt("threshold"))
+	})
+	suite.DoReceiveCompressed(netConn, func(id packet.ID, dec packet.Decoder) {
+		suite.Equal(packet.IDClientboundLoginSuccess, id)
+		suite.Equal(auth.OfflineUUID("aUsername"), dec.ReadUUID("uuid"))
+		suite.Equal("aUsername", dec.ReadString("username"))
+	})
+	suite.Equal("first answer", <-responses)
+	suite.Equal("second answer", <-responses)
+}
+
+func (suite *ServerSuite) TestLoginPluginNotUnderstood() {
+	suite.server.HandleLoginPlugin(id.ParseID("test:hello"), func(ctx context.Context, requester LoginPluginRequester) error {
+		response, err := requester.RequestAndWait(nil)
+		if err != nil {
+			return err
+		}
+		if !response.Understood {
+			return fmt.Errorf("please install the hello mod")
+		}
+		return nil
+	})
+
+	netConn := suite.DialServer()
+	suite.DoSend(netConn, packet.IDServerboundHandshake, func(enc packet.Encoder) {
+		enc.WriteVarInt("protocol version", 754)
+		enc.WriteString("server address", "localhost")
+		enc.WriteUshort("server port", 12345)
+		enc.WriteVarInt("next state", int(packet.NextStateLogin))
+	})
+	suite.DoSend(netConn, packet.IDServerboundLoginStart, func(enc packet.Encoder) {
+		enc.WriteString("username", "aUsername")
+	})
+	var messageID int
+	suite.DoReceive(netConn, func(id packet.ID, dec packet.Decoder) {
+		suite.Equal(packet.IDClientboundLoginPluginRequest, id)
+		messageID = dec.ReadVarInt("message id")
+		suite.Equal("test:hello", dec.ReadID("channel").String())
+	})
+	suite.DoSend(netConn, packet.IDServerboundLoginPluginResponse, func(enc packet.Encoder) {
+		enc.WriteVarInt("message id", messageID)
+		enc.WriteBoolean("successful", false)
+	})
+	suite.DoReceive(netConn, func(id packet.ID, dec packet.Decoder) {
+		suite.Equal(packet.IDClientboundDisconnectLogin, id)
+		suite.Equal(`{"text":"Login plugin negotiation failed"}`, dec.ReadString("reason"))
+	})
+	suite.ClosedOrEOF(netConn)
+}
+
+func (suite *ServerSuite) TestLoginPluginTimeout() {
+	suite.RestartServer(func(vp *viper.Viper) {
+		vp.Set(config.KeyNetworkLoginPluginTimeout, 100*time.Millisecond)
+	})
+	suite.server.HandleLoginPlugin(id.ParseID("test:hello"), func(ctx context.Context, requester LoginPluginRequester) error {
+		// don't wait for the response, the session still has to
+		_, err := requester.Request(nil)
+		return err
+	})
+
+	netConn := suite.DialServer()
+	suite.DoSend(netConn, packet.IDServerboundHandshake, func(enc packet.Encoder) {
+		enc.WriteVarInt("protocol version", 754)
+		enc.WriteString("server address", "localhost")
+		enc.WriteUshort("server port", 12345)
+		enc.WriteVarInt("next state", int(packet.NextStateLogin))
+	})
+	suite.DoSend(netConn, packet.IDServerboundLoginStart, func(enc packet.Encoder) {
+		enc.WriteString("username", "aUsername")
+	})
+	suite.DoReceive(netConn, func(id packet.ID, dec packet.Decoder) {
+		suite.Equal(packet.IDClientboundLoginPluginRequest, id)
+		dec.ReadVarInt("message id")
+		suite.Equal("test:hello", dec.ReadID("channel").String())
+	})
+	// no response
+	suite.DoReceive(netConn, func(id packet.ID, dec packet.Decoder) {
+		suite.Equal(packet.IDClientboundDisconnectLogin, id)
+		suite.Equal(`{"text":"Login plugin negotiation failed"}`, dec.ReadString("reason"))
+	})
+	suite.ClosedOrEOF(netConn)
+}
+
+func (suite *ServerSuite) TestStatusConfigured() {
+	hookedIPs := make(chan net.IP, 1)
+	suite.RestartServer(func(vp *viper.Viper) {
+		vp.Set(config.KeyServerMOTD, "§6Welcome")
+		vp.Set(config.KeyServerMaxPlayers, 20)
+	}, WithStatusHook(func(ip net.IP, version packet.Version, response packet.Response) packet.Response {
+		hookedIPs <- ip
+		response.Version.Name = "mcserver " + version.Name
+		return response
+	}))
+
+	player := suite.DialServer()
+	suite.DoSend(player, packet.IDServerboundHandshake, func(enc packet.Encoder) {
+		enc.WriteVarInt("protocol version", 754)
+		enc.WriteString("server address", "localhost")
+		enc.WriteUshort("server port", 12345)
+		enc.WriteVarInt("next state", int(packet.NextStateLogin))
+	})
+	suite.DoSend(player, packet.IDServerboundLoginStart, func(enc packet.Encoder) {
+		enc.WriteString("username", "aUsername")
+	})
+	suite.DoReceive(player, func(id packet.ID, dec packet.Decoder) {
+		suite.Equal(packet.IDClientboundSetCompression, id)
+		suite.EqualValues(256, dec.ReadVarInt("threshold"))
+	})
+	suite.DoReceiveCompressed(player, func(id packet.ID, dec packet.Decoder) {
+		suite.Equal(packet.IDClientboundLoginSuccess, id)
+		suite.Equal(auth.OfflineUUID("aUsername"), dec.ReadUUID("uuid"))
+		suite.Equal("aUsername", dec.ReadString("username"))
+	})
+
+	netConn := suite.DialServer()
+	suite.DoSend(netConn, packet.IDServerboundHandshake, func(enc packet.Encoder) {
+		enc.WriteVarInt("protocol version", 754)
+		enc.WriteString("server address", "localhost")
+		enc.WriteUshort("server port", 12345)
+		enc.WriteVarInt("next state", int(packet.NextStateStatus))
+	})
+	suite.DoSend(netConn, packet.IDServerboundRequest, func(enc packet.Encoder) {})
+	suite.DoReceive(netConn, func(id packet.ID, dec packet.Decoder) {
+		suite.Equal(packet.IDClientboundResponse, id)
+		resp := packet.Response{}
+		suite.NoError(json.Unmarshal([]byte(dec.ReadString("json response")), &resp))
+		suite.Equal("mcserver 1.16.5", resp.Version.Name)
+		suite.Equal(20, resp.Players.Max)
+		suite.Equal(1, resp.Players.Online)
+		suite.Equal([]packet.ResponsePlayersSample{
+			{Name: "aUsername", ID: auth.OfflineUUID("aUsername").String()},
+		}, resp.Players.Sample)
+		suite.Equal(chat.Chat{ChatFragment: chat.ChatFragment{Text: "Welcome", Color: "gold"}}, resp.Description)
+		suite.Empty(resp.Favicon)
+	})
+	suite.Equal("127.0.0.1", (<-hookedIPs).String())
+}
+
+func (suite *ServerSuite) TestQuery() {
+	queryConn, err := net.ListenPacket("udp", "127.0.0.1:0")
+	suite.Require().NoError(err)
+	suite.RestartServer(func(vp *viper.Viper) {
+		vp.Set(config.KeyServerMOTD, "§6Welcome")
+	}, WithQueryListener(queryConn))
+
+	client, err := net.Dial("udp", queryConn.LocalAddr().String())
+	suite.Require().NoError(err)
+	defer func() { _ = client.Close() }()
+	suite.Require().NoError(client.SetDeadline(time.Now().Add(5 * time.Second)))
+
+	// handshake
+	_, err = client.Write([]byte{0xFE, 0xFD, 0x09, 0x00, 0x00, 0x00, 0x01})
+	suite.Require().NoError(err)
+	response := make([]byte, 1460)
+	n, err := client.Read(response)
+	suite.Require().NoError(err)
+	suite.Equal([]byte{0x09, 0x00, 0x00, 0x00, 0x01}, response[:5])
+	token, err := strconv.Atoi(strings.TrimSuffix(string(response[5:n]), "\x00"))
+	suite.Require().NoError(err)
+
+	// basic stat
+	request := []byte{0xFE, 0xFD, 0x00, 0x00, 0x00, 0x00, 0x01, 0, 0, 0, 0}
+	binary.BigEndian.PutUint32(request[7:], uint32(int32(token)))
+	_, err = client.Write(request)
+	suite.Require().NoError(err)
+	n, err = client.Read(response)
+	suite.Require().NoError(err)
+	suite.Equal([]byte{0x00, 0x00, 0x00, 0x00, 0x01}, response[:5])
+	fields := strings.SplitN(string(response[5:n]), "\x00", 6)
+	suite.Equal([]string{"Welcome", "SMP", "game/testdata/maps/world01", "0", "100"}, fields[:5])
+}
+
+func (suite *ServerSuite) TestRCON() {
+	rconListener, err := net.Listen("tcp", "127.0.0.1:0")
+	suite.Require().NoError(err)
+	suite.RestartServer(func(vp *viper.Viper) {
+		vp.Set(config.KeyRCONPassword, "secret")
+	}, WithRCONListener(rconListener))
+
+	client, err := net.Dial("tcp", rconListener.Addr().String())
+	suite.Require().NoError(err)
+	defer func() { _ = client.Close() }()
+	suite.Require().NoError(client.SetDeadline(time.Now().Add(5 * time.Second)))
+
+	suite.Require().NoError(rcon.WritePacket(client, rcon.Packet{RequestID: 1, Type: rcon.TypeLogin, Body: "secret"}))
+	response, err := rcon.ReadPacket(client)
+	suite.Require().NoError(err)
+	suite.Equal(rcon.Packet{RequestID: 1, Type: rcon.TypeAuthResponse}, response)
+
+	suite.Require().NoError(rcon.WritePacket(client, rcon.Packet{RequestID: 2, Type: rcon.TypeCommand, Body: "list"}))
+	response, err = rcon.ReadPacket(client)
+	suite.Require().NoError(err)
+	suite.Equal(rcon.Packet{
+		RequestID: 2,
+		Type:      rcon.TypeResponse,
+		Body:      "There are 0 of a max of 100 players online: ",
+	}, response)
+
+	// errors are sent to the client instead of being logged
+	suite.Require().NoError(rcon.WritePacket(client, rcon.Packet{RequestID: 3, Type: rcon.TypeCommand, Body: "unknown"}))
+	response, err = rcon.ReadPacket(client)
+	suite.Require().NoError(err)
+	suite.Equal(rcon.Packet{
+		RequestID: 3,
+		Type:      rcon.TypeResponse,
+		Body:      "unknown command at position 7: unknown<--[HERE]",
+	}, response)
+}
+
+func (suite *ServerSuite) TestRCONWithoutPassword() {
+	cfg := testConfig()
+	cfg.ApplyDefaults()
+	rconListener, err := net.Listen("tcp", "127.0.0.1:0")
+	suite.Require().NoError(err)
+	defer func() { _ = rconListener.Close() }()
+
+	_, err = New(cfg, WithListener(suite.listener), WithRCONListener(rconListener))
+	suite.ErrorIs(err, ErrNoRCONPassword)
+}
+
+func (suite *ServerSuite) TestClient() {
+	suite.RestartServer(func(vp *viper.Viper) {
+		vp.Set(config.KeyServerMaxPlayers, 42)
+	})
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	statusClient := client.New(zerolog.Nop(), suite.DialServer())
+	response, _, err := statusClient.Status(ctx)
+	suite.Require().NoError(err)
+	suite.Equal(ServerVersion, response.Version.Name)
+	suite.Equal(42, response.Players.Max)
+
+	c := client.New(zerolog.Nop(), suite.DialServer())
+	defer func() { _ = c.Close() }()
+	profile, err := c.Login(ctx, "Notch")
+	suite.Require().NoError(err)
+	suite.Equal("Notch", profile.Name)
+
+	var joined bool
+	for event := range c.Events() {
+		switch e := event.(type) {
+		case client.JoinGameEvent:
+			joined = true
+			suite.Equal(42, e.MaxPlayers)
+		case client.PositionEvent:
+			suite.True(joined, "position before join game")
+			suite.Equal(client.PositionEvent{Y: 69}, e)
+			return
+		case client.DisconnectEvent:
+			suite.FailNow("disconnected", "%v %v", e.Reason, e.Err)
+		}
+	}
+}
+
+func (suite *ServerSuite) TestChat() {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	c := client.New(zerolog.Nop(), suite.DialServer())
+	defer func() { _ = c.Close() }()
+	_, err := c.Login(ctx, "Notch")
+	suite.Require().NoError(err)
+
+	nextChatMessage := func() *packet.ClientboundChatMessage {
+		for event := range c.Events() {
+			switch e := event.(type) {
+			case client.PositionEvent:
+				// the player is in the game, so chat can start
+				suite.Require().NoError(c.WritePacket(packet.ServerboundChatMessage{Message: "hello"}))
+			case client.PacketEvent:
+				if msg, ok := e.Packet.(*packet.ClientboundChatMessage); ok {
+					return msg
+				}
+			case client.DisconnectEvent:
+				suite.FailNow("disconnected", "%v %v", e.Reason, e.Err)
+			}
+		}
+		suite.FailNow("no chat message received")
+		return nil
+	}
+
+	msg := nextChatMessage()
+	suite.Equal("<Notch> hello", msg.Message.PlainText())
+	suite.EqualValues(game.ChatPositionChat, msg.Position)
+
+	output, err := suite.server.ExecuteCommand(ctx, "say hi there")
+	suite.Require().NoError(err)
+	suite.Equal("[Server] hi there", output)
+	msg = nextChatMessage()
+	suite.Equal("[Server] hi there", msg.Message.PlainText())
+	suite.EqualValues(game.ChatPositionSystem, msg.Position)
+}
+
+func (suite *ServerSuite) TestLoginTwice() {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	login := func() *client.Client {
+		c := client.New(zerolog.Nop(), suite.DialServer())
+		_, err := c.Login(ctx, "Notch")
+		suite.Require().NoError(err)
+		for event := range c.Events() {
+			if _, ok := event.(client.PositionEvent); ok {
+				return c
+			}
+		}
+		suite.FailNow("not spawned")
+		return nil
+	}
+
+	first := login()
+	defer func() { _ = first.Close() }()
+	second := login()
+	defer func() { _ = second.Close() }()
+
+	// the first session is kicked
+	for event := range first.Events() {
+		if e, ok := event.(client.DisconnectEvent); ok {
+			suite.Equal("You logged in from another location", e.Reason.PlainText())
+			break
+		}
+	}
+
+	// closing the first session must not remove the second one
+	suite.Eventually(func() bool {
+		output, err := suite.server.ExecuteCommand(ctx, "list")
+		return err == nil && output == "There are 1 of a max of 100 players online: Notch"
+	}, time.Second, 10*time.Millisecond)
+	_, err := suite.server.ExecuteCommand(ctx, "say still here")
+	suite.Require().NoError(err)
+	for event := range second.Events() {
+		switch e := event.(type) {
+		case client.PacketEvent:
+			if msg, ok := e.Packet.(*packet.ClientboundChatMessage); ok {
+				suite.Equal("[Server] still here", msg.Message.PlainText())
+				return
+			}
+		case client.DisconnectEvent:
+			suite.FailNow("disconnected", "%v %v", e.Reason, e.Err)
+		}
+	}
+	suite.Fail("no chat message received")
+}
+
+func (suite *ServerSuite) TestCommands() {
+	suite.RestartServer(func(vp *viper.Viper) {
+		vp.Set(config.KeyServerOperators, []string{"Notch"})
+	})
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	login := func(name string) *client.Client {
+		c := client.New(zerolog.Nop(), suite.DialServer())
+		_, err := c.Login(ctx, name)
+		suite.Require().NoError(err)
+		return c
+	}
+	// nextPacket returns the next packet of the given type that the client receives
+	nextPacket := func(c *client.Client, typ packet.Packet) packet.Packet {
+		for event := range c.Events() {
+			switch e := event.(type) {
+			case client.PacketEvent:
+				if reflect.TypeOf(e.Packet) == reflect.TypeOf(typ) {
+					return e.Packet
+				}
+			case client.DisconnectEvent:
+				suite.FailNow("disconnected", "%v %v", e.Reason, e.Err)
+			}
+		}
+		suite.FailNow("no packet received")
+		return nil
+	}
+
+	// the permission level is sent with an entity status after the one that disables
+	// reduced debug info
+	permissionLevelStatus := func(c *client.Client) int8 {
+		suite.EqualValues(23, nextPacket(c, &packet.ClientboundEntityStatus{}).(*packet.ClientboundEntityStatus).Status)
+		return nextPacket(c, &packet.ClientboundEntityStatus{}).(*packet.ClientboundEntityStatus).Status
+	}
+
+	operator := login("Notch")
+	defer func() { _ = operator.Close() }()
+	suite.EqualValues(28, permissionLevelStatus(operator))
+
+	player := login("jeb_")
+	defer func() { _ = player.Close() }()
+	suite.EqualValues(24, permissionLevelStatus(player))
+
+	suite.Require().NoError(operator.WritePacket(packet.ServerboundTabComplete{TransactionID: 7, Text: "/kick j"}))
+	suite.Equal(&packet.ClientboundTabComplete{
+		TransactionID: 7,
+		Start:         6,
+		Length:        1,
+		Matches:       []packet.TabCompleteMatch{{Match: "jeb_"}},
+	}, nextPacket(operator, &packet.ClientboundTabComplete{}))
+	// players can't see commands that they may not use
+	suite.Require().NoError(player.WritePacket(packet.ServerboundTabComplete{TransactionID: 8, Text: "/k"}))
+	suite.Empty(nextPacket(player, &packet.ClientboundTabComplete{}).(*packet.ClientboundTabComplete).Matches)
+
+	suite.Require().NoError(player.WritePacket(packet.ServerboundChatMessage{Message: "/kick Notch"}))
+	msg := nextPacket(player, &packet.ClientboundChatMessage{}).(*packet.ClientboundChatMessage)
+	suite.Equal("unknown command at position 4: kick<--[HERE]", msg.Message.PlainText())
+	suite.EqualValues(game.ChatPositionSystem, msg.Position)
+
+	suite.Require().NoError(operator.WritePacket(packet.ServerboundChatMessage{Message: "/kick jeb_ bye"}))
+	msg = nextPacket(operator, &packet.ClientboundChatMessage{}).(*packet.ClientboundChatMessage)
+	suite.Equal("Kicked jeb_: bye", msg.Message.PlainText())
+	for event := range player.Events() {
+		if e, ok := event.(client.DisconnectEvent); ok {
+			suite.Equal("bye", e.Reason.PlainText())
+			break
+		}
+	}
+}
+
+func (suite *ServerSuite) TestOpPermissionLevel() {
+	suite.RestartServer(func(vp *viper.Viper) {
+		vp.Set(config.KeyServerOperators, []string{"Notch"})
+		vp.Set(config.KeyServerOpPermissionLevel, command.PermissionLevelGamemaster)
+	})
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	operator := client.New(zerolog.Nop(), suite.DialServer())
+	defer func() { _ = operator.Close() }()
+	_, err := operator.Login(ctx, "Notch")
+	suite.Require().NoError(err)
+
+	var statuses []int8
+	var messages []string
+	for event := range operator.Events() {
+		switch e := event.(type) {
+		case client.PacketEvent:
+			switch p := e.Packet.(type) {
+			case *packet.ClientboundEntityStatus:
+				statuses = append(statuses, p.Status)
+			case *packet.ClientboundChatMessage:
+				messages = append(messages, p.Message.PlainText())
+			}
+		case client.PositionEvent:
+			// gamemasters may say, but not kick
+			suite.Require().NoError(operator.WritePacket(packet.ServerboundChatMessage{Message: "/kick Notch"}))
+			suite.Require().NoError(operator.WritePacket(packet.ServerboundChatMessage{Message: "/say hi"}))
+		case client.DisconnectEvent:
+			suite.FailNow("disconnected", "%v %v", e.Reason, e.Err)
+		}
+		if len(messages) == 2 {
+			break
+		}
+	}
+	suite.Equal([]int8{23, 26}, statuses)
+	suite.Equal([]string{"unknown command at position 4: kick<--[HERE]", "[Notch] hi"}, messages)
+}
+
+func (suite *ServerSuite) TestInvalidOpPermissionLevel() {
+	for _, level := range []int{0, 5} {
+		vp := testViper()
+		vp.Set(config.KeyServerOpPermissionLevel, level)
+		cfg := config.New(vp)
+		cfg.ApplyDefaults()
+
+		_, err := New(cfg, WithListener(suite.listener))
+		suite.ErrorIs(err, ErrInvalidOpPermissionLevel, level)
+	}
+}
@@ -0,0 +1,171 @@
+// Package capture implements a file format for recorded packets of a connection,
+// which can be used to debug client incompatibilities and to replay client
+// sessions against a server.
+//
+// A capture file starts with a header, followed by any amount of records. Every
+// record holds the direction, phase, time and data of a single packet, where the
+// data is the packet ID and payload, after decryption and decompression.
+package capture
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"sync"
+	"time"
+
+	"github.com/tsatke/mcserver/network/packet"
+)
+
+//go:generate stringer -linecomment -output=direction_string.go -type=Direction
+
+// Direction is the direction in which a recorded packet was sent.
+type Direction uint8
+
+// Known directions.
+const (
+	DirectionServerbound Direction = iota + 1 // serverbound
+	DirectionClientbound                      // clientbound
+)
+
+const (
+	magic   = "MCCAPTURE"
+	version = 1
+)
+
+// Record is a single recorded packet.
+type Record struct {
+	// Direction is the direction in which the packet was sent.
+	Direction Direction
+	// Phase is the phase of the connection when the packet was sent.
+	Phase packet.Phase
+	// Time is the point in time when the packet was read or written.
+	Time time.Time
+	// Data is the packet ID and payload.
+	Data []byte
+}
+
+// ID returns the packet ID of the recorded packet. If the data doesn't start
+// with a valid VarInt, an error is returned.
+func (r Record) ID() (packet.ID, error) {
+	id, err := binary.ReadUvarint(bytes.NewReader(r.Data))
+	if err != nil {
+		return 0, fmt.Errorf("%w: read packet id: %v", ErrInvalidCapture, err)
+	}
+	return packet.ID(id), nil
+}
+
+// recordHeader is the fixed size part of an encoded record.
+type recordHeader struct {
+	Direction Direction
+	Phase     packet.Phase
+	Time      int64 // unix nanoseconds
+	Length    uint32
+}
+
+// Writer writes records into a capture file. It is safe for concurrent use.
+type Writer struct {
+	mu sync.Mutex
+	w  io.Writer
+}
+
+// NewWriter writes the capture file header into the given writer and returns a
+// Writer that writes records into it. If the given writer is an io.Closer, it is
+// closed when the Writer is closed.
+func NewWriter(w io.Writer) (*Writer, error) {
+	if _, err := io.WriteString(w, magic); err != nil {
+		return nil, fmt.Errorf("write magic: %w", err)
+	}
+	if _, err := w.Write([]byte{version}); err != nil {
+		return nil, fmt.Errorf("write version: %w", err)
+	}
+	return &Writer{
+		w: w,
+	}, nil
+}
+
+// Write appends the given record to the capture file.
+func (w *Writer) Write(r Record) error {
+	var buf bytes.Buffer
+	_ = binary.Write(&buf, binary.BigEndian, recordHeader{
+		Direction: r.Direction,
+		Phase:     r.Phase,
+		Time:      r.Time.UnixNano(),
+		Length:    uint32(len(r.Data)),
+	})
+	buf.Write(r.Data)
+
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	if _, err := buf.WriteTo(w.w); err != nil {
+		return fmt.Errorf("write record: %w", err)
+	}
+	return nil
+}
+
+// Close closes the underlying writer, if it is an io.Closer.
+func (w *Writer) Close() error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	if closer, ok := w.w.(io.Closer); ok {
+		return closer.Close()
+	}
+	return nil
+}
+
+// Reader reads records from a capture file.
+type Reader struct {
+	rd *bufio.Reader
+}
+
+// NewReader reads the capture file header from the given reader and returns a
+// Reader that reads records from it. If the header is invalid, an error wrapping
+// ErrInvalidCapture is returned.
+func NewReader(rd io.Reader) (*Reader, error) {
+	br := bufio.NewReader(rd)
+	header := make([]byte, len(magic)+1)
+	if _, err := io.ReadFull(br, header); err != nil {
+		return nil, fmt.Errorf("%w: read header: %v", ErrInvalidCapture, err)
+	}
+	if string(header[:len(magic)]) != magic {
+		return nil, fmt.Errorf("%w: not a capture file", ErrInvalidCapture)
+	}
+	if v := header[len(magic)]; v != version {
+		return nil, fmt.Errorf("%w: unsupported version %d", ErrInvalidCapture, v)
+	}
+	return &Reader{
+		rd: br,
+	}, nil
+}
+
+// Read reads the next record. If there are no more records, io.EOF is returned.
+// If the capture file ends in the middle of a record, e.g. because the server was
+// killed while recording, an error wrapping ErrInvalidCapture is returned.
+func (r *Reader) Read() (Record, error) {
+	var header recordHeader
+	if err := binary.Read(r.rd, binary.BigEndian, &header); err != nil {
+		if errors.Is(err, io.EOF) {
+			return Record{}, io.EOF
+		}
+		return Record{}, fmt.Errorf("%w: read record: %v", ErrInvalidCapture, err)
+	}
+	if header.Length > packet.MaxFrameLength {
+		return Record{}, fmt.Errorf("%w: record too large: %d", ErrInvalidCapture, header.Length)
+	}
+
+	data := make([]byte, header.Length)
+	if _, err := io.ReadFull(r.rd, data); err != nil {
+		return Record{}, fmt.Errorf("%w: read data: %v", ErrInvalidCapture, err)
+	}
+	return Record{
+		Direction: header.Direction,
+		Phase:     header.Phase,
+		Time:      time.Unix(0, header.Time),
+		Data:      data,
+	}, nil
+}
@@ -0,0 +1,111 @@
+package capture
+
+import (
+	"bytes"
+	"io"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/tsatke/mcserver/network/packet"
+)
+
+func TestWriterReader(t *testing.T) {
+	assert := assert.New(t)
+
+	records := []Record{
+		{
+			Direction: DirectionServerbound,
+			Phase:     packet.PhaseHandshaking,
+			Time:      time.Unix(1600000000, 123),
+			Data:      []byte{0x00, 0x01, 0x02},
+		},
+		{
+			Direction: DirectionClientbound,
+			Phase:     packet.PhasePlay,
+			Time:      time.Unix(1600000001, 456),
+			Data:      []byte{0x1F, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x01},
+		},
+	}
+
+	var buf bytes.Buffer
+	w, err := NewWriter(&buf)
+	assert.NoError(err)
+	for _, r := range records {
+		assert.NoError(w.Write(r))
+	}
+	assert.NoError(w.Close())
+
+	rd, err := NewReader(&buf)
+	assert.NoError(err)
+	for _, want := range records {
+		got, err := rd.Read()
+		assert.NoError(err)
+		assert.Equal(want.Direction, got.Direction)
+		assert.Equal(want.Phase, got.Phase)
+		assert.True(want.Time.Equal(got.Time))
+		assert.Equal(want.Data, got.Data)
+	}
+	_, err = rd.Read()
+	assert.Equal(io.EOF, err)
+
+	id, err := records[1].ID()
+	assert.NoError(err)
+	assert.Equal(packet.IDClientboundKeepAlive, id)
+}
+
+func TestReaderInvalid(t *testing.T) {
+	assert := assert.New(t)
+
+	_, err := NewReader(bytes.NewReader([]byte("not a capture file")))
+	assert.ErrorIs(err, ErrInvalidCapture)
+
+	var buf bytes.Buffer
+	w, _ := NewWriter(&buf)
+	_ = w.Write(Record{Direction: DirectionServerbound, Data: []byte{1, 2, 3, 4}})
+	truncated := buf.Bytes()[:buf.Len()-2]
+
+	rd, err := NewReader(bytes.NewReader(truncated))
+	assert.NoError(err)
+	_, err = rd.Read()
+	assert.ErrorIs(err, ErrInvalidCapture)
+}
+
+func TestReplay(t *testing.T) {
+	assert := assert.New(t)
+
+	encode := func(pkg packet.Clientbound) []byte {
+		data, err := packet.Latest.EncodeData(pkg)
+		assert.NoError(err)
+		return data
+	}
+	handshake := []byte{0x00, 0xF2, 0x05, 0x01, 'a', 0x30, 0x39, 0x02}
+	loginStart := []byte{0x00, 0x01, 'a'}
+	keepAlive := []byte{0x10, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x05}
+
+	var capture bytes.Buffer
+	w, _ := NewWriter(&capture)
+	_ = w.Write(Record{Direction: DirectionServerbound, Phase: packet.PhaseHandshaking, Data: handshake})
+	_ = w.Write(Record{Direction: DirectionServerbound, Phase: packet.PhaseLogin, Data: loginStart})
+	_ = w.Write(Record{Direction: DirectionClientbound, Phase: packet.PhaseLogin, Data: encode(packet.ClientboundSetCompression{Threshold: 256})})
+	_ = w.Write(Record{Direction: DirectionClientbound, Phase: packet.PhaseLogin, Data: encode(packet.ClientboundLoginSuccess{Username: "a"})})
+	_ = w.Write(Record{Direction: DirectionServerbound, Phase: packet.PhasePlay, Data: keepAlive})
+
+	rd, err := NewReader(&capture)
+	assert.NoError(err)
+	var sent bytes.Buffer
+	assert.NoError(Replay(&sent, rd, 0))
+
+	// only serverbound packets are sent, compressed after set compression
+	data, err := packet.ReadFrame(&sent, packet.PhaseHandshaking, false)
+	assert.NoError(err)
+	assert.Equal(handshake, data)
+	data, err = packet.ReadFrame(&sent, packet.PhaseLogin, false)
+	assert.NoError(err)
+	assert.Equal(loginStart, data)
+	data, err = packet.ReadFrame(&sent, packet.PhasePlay, true)
+	assert.NoError(err)
+	assert.Equal(keepAlive, data)
+	assert.Zero(sent.Len())
+}
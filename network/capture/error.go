@@ -0,0 +1,10 @@
+package capture
+
+type Error string
+
+func (e Error) Error() string { return string(e) }
+
+const (
+	// ErrInvalidCapture indicates, that a capture file is malformed.
+	ErrInvalidCapture Error = "invalid capture"
+)
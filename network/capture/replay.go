@@ -0,0 +1,72 @@
+package capture
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"time"
+
+	"github.com/tsatke/mcserver/network/packet"
+)
+
+// Replay reads all records from the given reader and sends the serverbound packets
+// to the given writer, which usually is a connection to a fresh server, so that the
+// recorded client session is reproduced. The packets are sent with the delays in
+// between them that were recorded, divided by the given speed. A speed of zero or
+// less sends all packets without delay.
+//
+// After the recorded server enabled compression, the packets are sent in the
+// compressed packet format, so the server must use the same compression setting
+// as the recorded server. Encryption can't be replayed, since the shared secret is
+// different for every connection, so the server must not be in online mode.
+// Responses of the server are not read, so the caller has to read them, otherwise
+// the server may block or disconnect the client.
+func Replay(w io.Writer, rd *Reader, speed float64) error {
+	threshold := packet.CompressionDisabled
+	var last time.Time
+	for {
+		r, err := rd.Read()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+
+		if r.Direction == DirectionClientbound {
+			if enabled, t := compressionThreshold(r); enabled {
+				threshold = t
+			}
+			continue
+		}
+
+		if speed > 0 && !last.IsZero() {
+			time.Sleep(time.Duration(float64(r.Time.Sub(last)) / speed))
+		}
+		last = r.Time
+
+		if err := packet.WriteFrame(w, r.Data, threshold); err != nil {
+			return fmt.Errorf("write frame: %w", err)
+		}
+	}
+}
+
+// compressionThreshold returns the threshold of the given record, if it is a
+// clientbound set compression packet.
+func compressionThreshold(r Record) (bool, int) {
+	if r.Phase != packet.PhaseLogin {
+		return false, 0
+	}
+	rd := bytes.NewReader(r.Data)
+	id, err := binary.ReadUvarint(rd)
+	if err != nil || packet.ID(id) != packet.IDClientboundSetCompression {
+		return false, 0
+	}
+
+	var p packet.ClientboundSetCompression
+	if err := p.DecodeFrom(rd); err != nil {
+		return false, 0
+	}
+	return true, p.Threshold
+}
@@ -0,0 +1,56 @@
+package network
+
+import "crypto/cipher"
+
+type cfb8 struct {
+	block   cipher.Block
+	iv      []byte
+	tmp     []byte
+	decrypt bool
+}
+
+// NewCFB8Encrypter returns a cipher.Stream which encrypts with cipher feedback mode
+// with a segment size of 8 bits (CFB8), using the given cipher.Block. The iv must
+// be the same length as the block's block size. This is the mode that minecraft uses
+// to encrypt connections.
+func NewCFB8Encrypter(block cipher.Block, iv []byte) cipher.Stream {
+	return newCFB8(block, iv, false)
+}
+
+// NewCFB8Decrypter returns a cipher.Stream which decrypts with cipher feedback mode
+// with a segment size of 8 bits (CFB8), using the given cipher.Block. The iv must
+// be the same length as the block's block size.
+func NewCFB8Decrypter(block cipher.Block, iv []byte) cipher.Stream {
+	return newCFB8(block, iv, true)
+}
+
+func newCFB8(block cipher.Block, iv []byte, decrypt bool) *cfb8 {
+	if len(iv) != block.BlockSize() {
+		panic("cfb8: IV length must equal block size")
+	}
+	return &cfb8{
+		block:   block,
+		iv:      append([]byte(nil), iv...),
+		tmp:     make([]byte, block.BlockSize()),
+		decrypt: decrypt,
+	}
+}
+
+func (x *cfb8) XORKeyStream(dst, src []byte) {
+	if len(dst) < len(src) {
+		panic("cfb8: output smaller than input")
+	}
+
+	for i, val := range src {
+		x.block.Encrypt(x.tmp, x.iv)
+		dst[i] = val ^ x.tmp[0]
+
+		// shift the feedback register by one byte and append the cipher text byte
+		copy(x.iv, x.iv[1:])
+		if x.decrypt {
+			x.iv[len(x.iv)-1] = val
+		} else {
+			x.iv[len(x.iv)-1] = dst[i]
+		}
+	}
+}
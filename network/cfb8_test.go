@@ -0,0 +1,34 @@
+package network
+
+import (
+	"crypto/aes"
+	"encoding/hex"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// Test vectors from NIST SP 800-38A, F.3.7 and F.3.8.
+func TestCFB8(t *testing.T) {
+	assert := assert.New(t)
+
+	key, _ := hex.DecodeString("2b7e151628aed2a6abf7158809cf4f3c")
+	iv, _ := hex.DecodeString("000102030405060708090a0b0c0d0e0f")
+	plaintext, _ := hex.DecodeString("6bc1bee22e409f96e93d7e117393172aae2d")
+	ciphertext, _ := hex.DecodeString("3b79424c9c0dd436bace9e0ed4586a4f32b9")
+
+	block, err := aes.NewCipher(key)
+	assert.NoError(err)
+
+	encrypted := make([]byte, len(plaintext))
+	enc := NewCFB8Encrypter(block, iv)
+	// encrypt in two steps to make sure that the stream keeps its state
+	enc.XORKeyStream(encrypted[:5], plaintext[:5])
+	enc.XORKeyStream(encrypted[5:], plaintext[5:])
+	assert.Equal(ciphertext, encrypted)
+
+	decrypted := make([]byte, len(ciphertext))
+	copy(decrypted, ciphertext)
+	NewCFB8Decrypter(block, iv).XORKeyStream(decrypted, decrypted) // decrypt in place
+	assert.Equal(plaintext, decrypted)
+}
@@ -0,0 +1,550 @@
+// Package client implements a headless minecraft client, which can request the
+// status of a server, log in and stay in the play phase, for bots and integration
+// tests.
+//
+// A client only supports offline mode servers, since it can't authenticate with the
+// session server. Once logged in, keep alives and teleports are answered
+// automatically, and everything else that the server sends is reported as Event.
+//
+//	c, err := client.Dial(ctx, log, "localhost:25565")
+//	...
+//	profile, err := c.Login(ctx, "Notch")
+//	...
+//	for event := range c.Events() {
+//		switch e := event.(type) {
+//		case client.PositionEvent:
+//			...
+//		}
+//	}
+package client
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"strconv"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/rs/zerolog"
+
+	"github.com/tsatke/mcserver/auth"
+	"github.com/tsatke/mcserver/game/chat"
+	"github.com/tsatke/mcserver/game/id"
+	"github.com/tsatke/mcserver/network/packet"
+)
+
+// DefaultEventQueueSize is the amount of events that are queued until they are read,
+// before further events are dropped.
+const DefaultEventQueueSize = 256
+
+// Position flags of packet.ClientboundPlayerPositionAndLook, which indicate that a
+// value is relative to the current one.
+const (
+	relativeX int8 = 1 << iota
+	relativeY
+	relativeZ
+	relativeYaw
+	relativePitch
+)
+
+// LoginPluginHandler answers a login plugin request with the given data. If the
+// client doesn't understand the request, understood must be false.
+type LoginPluginHandler func(data []byte) (response []byte, understood bool)
+
+// Stats are counters of the packets that a client read and wrote. Bytes are the
+// lengths of the packet IDs and payloads, before compression.
+type Stats struct {
+	PacketsRead    int64
+	PacketsWritten int64
+	BytesRead      int64
+	BytesWritten   int64
+}
+
+// Client is a connection to a server. Status and Login must only be called once,
+// and only one of them. After Login, Events must be read until it is closed, or
+// the client must be closed with Close.
+type Client struct {
+	// stats are updated atomically, and must be the first field to be aligned
+	// on 32 bit platforms.
+	stats Stats
+
+	log     zerolog.Logger
+	conn    net.Conn
+	rd      io.Reader
+	version packet.Version
+	// host and port are sent in the handshake.
+	host string
+	port int
+
+	loginPlugins   map[id.ID]LoginPluginHandler
+	eventQueueSize int
+	// events are emitted by the goroutine that reads packets in the play phase,
+	// which is the only one that sends on the channel, except for the final
+	// DisconnectEvent if that goroutine never started.
+	events chan Event
+
+	// mu guards phase, threshold, position, closed and reading.
+	mu    sync.Mutex
+	phase packet.Phase
+	// threshold is the compression threshold, or packet.CompressionDisabled.
+	threshold int
+	position  PositionEvent
+	closed    bool
+	// reading indicates, that the goroutine that reads packets in the play
+	// phase was started.
+	reading bool
+
+	// writeLock serializes writing packets.
+	writeLock sync.Mutex
+	// finishOnce guards finish.
+	finishOnce sync.Once
+}
+
+// Dial connects to the server with the given address, and creates a new client
+// on top of the connection. See New.
+func Dial(ctx context.Context, log zerolog.Logger, addr string, opts ...Option) (*Client, error) {
+	host, portString, err := net.SplitHostPort(addr)
+	if err != nil {
+		return nil, fmt.Errorf("split host and port: %w", err)
+	}
+	port, err := strconv.Atoi(portString)
+	if err != nil {
+		return nil, fmt.Errorf("parse port: %w", err)
+	}
+
+	var dialer net.Dialer
+	conn, err := dialer.DialContext(ctx, "tcp", addr)
+	if err != nil {
+		return nil, fmt.Errorf("dial: %w", err)
+	}
+	return New(log, conn, append([]Option{WithServerAddress(host, port)}, opts...)...), nil
+}
+
+// New creates a new client on top of the given connection to a server, which is
+// in the handshaking phase. The client takes ownership of the connection.
+func New(log zerolog.Logger, conn net.Conn, opts ...Option) *Client {
+	c := &Client{
+		log:            log,
+		conn:           conn,
+		rd:             bufio.NewReader(conn),
+		version:        packet.LatestVersion,
+		loginPlugins:   make(map[id.ID]LoginPluginHandler),
+		eventQueueSize: DefaultEventQueueSize,
+		phase:          packet.PhaseHandshaking,
+		threshold:      packet.CompressionDisabled,
+	}
+	if host, port, err := net.SplitHostPort(conn.RemoteAddr().String()); err == nil {
+		c.host = host
+		c.port, _ = strconv.Atoi(port)
+	}
+
+	for _, opt := range opts {
+		opt(c)
+	}
+
+	// one more than the queue size, so that the DisconnectEvent always fits
+	c.events = make(chan Event, c.eventQueueSize+1)
+	return c
+}
+
+// Events returns the events that happen in the play phase. The last event is a
+// DisconnectEvent, after which the channel is closed.
+func (c *Client) Events() <-chan Event {
+	return c.events
+}
+
+// Phase returns the phase that the client is currently in.
+func (c *Client) Phase() packet.Phase {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	return c.phase
+}
+
+// Position returns the position that the server teleported the player to most recently.
+func (c *Client) Position() PositionEvent {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	return c.position
+}
+
+// Status requests the status of the server, as it is displayed in the server list,
+// and measures the latency to the server with a ping. The client is closed afterwards.
+func (c *Client) Status(ctx context.Context) (response packet.Response, latency time.Duration, err error) {
+	defer func() { _ = c.Close() }()
+	defer c.interruptOnDone(ctx, &err)()
+
+	if err := c.handshake(packet.NextStateStatus, packet.PhaseStatus); err != nil {
+		return packet.Response{}, 0, err
+	}
+
+	if err := c.WritePacket(packet.ServerboundRequest{}); err != nil {
+		return packet.Response{}, 0, err
+	}
+	p, err := c.readPacket()
+	if err != nil {
+		return packet.Response{}, 0, err
+	}
+	statusResponse, ok := p.(*packet.ClientboundResponse)
+	if !ok {
+		return packet.Response{}, 0, fmt.Errorf("%w: %s", ErrUnexpectedPacket, p.Name())
+	}
+
+	start := time.Now()
+	if err := c.WritePacket(packet.ServerboundPing{Payload: start.UnixNano()}); err != nil {
+		return packet.Response{}, 0, err
+	}
+	p, err = c.readPacket()
+	if err != nil {
+		return packet.Response{}, 0, err
+	}
+	if pong, ok := p.(*packet.ClientboundPong); !ok || pong.Payload != start.UnixNano() {
+		return packet.Response{}, 0, fmt.Errorf("%w: %s", ErrUnexpectedPacket, p.Name())
+	}
+	return statusResponse.JSONResponse, time.Since(start), nil
+}
+
+// Login logs in with the given username, and returns the profile that the server
+// assigned to the player. Compression and login plugin requests are handled by the
+// client. After Login returned successfully, the client is in the play phase, and
+// events are emitted. If Login fails, the client is closed. If the server
+// disconnects the client, an error wrapping ErrDisconnected is returned.
+func (c *Client) Login(ctx context.Context, username string) (profile auth.Profile, err error) {
+	defer func() {
+		if err != nil {
+			_ = c.Close()
+		}
+	}()
+	defer c.interruptOnDone(ctx, &err)()
+
+	if err := c.handshake(packet.NextStateLogin, packet.PhaseLogin); err != nil {
+		return auth.Profile{}, err
+	}
+	if err := c.WritePacket(packet.ServerboundLoginStart{Username: username}); err != nil {
+		return auth.Profile{}, err
+	}
+
+	for {
+		p, err := c.readPacket()
+		if err != nil {
+			return auth.Profile{}, err
+		}
+
+		switch p := p.(type) {
+		case *packet.ClientboundSetCompression:
+			c.mu.Lock()
+			c.threshold = p.Threshold
+			c.mu.Unlock()
+		case *packet.ClientboundLoginPluginRequest:
+			if err := c.answerLoginPlugin(p); err != nil {
+				return auth.Profile{}, err
+			}
+		case *packet.ClientboundEncryptionRequest:
+			return auth.Profile{}, ErrEncryptionRequired
+		case *packet.ClientboundDisconnectLogin:
+			return auth.Profile{}, fmt.Errorf("%w: %s", ErrDisconnected, p.Reason.PlainText())
+		case *packet.ClientboundLoginSuccess:
+			c.mu.Lock()
+			if c.closed {
+				c.mu.Unlock()
+				return auth.Profile{}, ErrClosed
+			}
+			c.phase = packet.PhasePlay
+			c.reading = true
+			c.mu.Unlock()
+
+			go c.readLoop()
+			return auth.Profile{
+				UUID: p.UUID,
+				Name: p.Username,
+			}, nil
+		default:
+			return auth.Profile{}, fmt.Errorf("%w: %s", ErrUnexpectedPacket, p.Name())
+		}
+	}
+}
+
+// WritePacket sends the given serverbound packet to the server. This is safe for
+// concurrent use.
+func (c *Client) WritePacket(p packet.Encodable) error {
+	c.mu.Lock()
+	closed := c.closed
+	threshold := c.threshold
+	c.mu.Unlock()
+	if closed {
+		return ErrClosed
+	}
+
+	data, err := c.version.Protocol.EncodeServerboundData(p)
+	if err != nil {
+		return fmt.Errorf("encode: %w", err)
+	}
+	var buf bytes.Buffer
+	if err := packet.WriteFrame(&buf, data, threshold); err != nil {
+		return fmt.Errorf("encode: %s: %w", p.Name(), err)
+	}
+
+	c.writeLock.Lock()
+	defer c.writeLock.Unlock()
+
+	if _, err := buf.WriteTo(c.conn); err != nil {
+		return fmt.Errorf("write %s: %w", p.Name(), err)
+	}
+	atomic.AddInt64(&c.stats.PacketsWritten, 1)
+	atomic.AddInt64(&c.stats.BytesWritten, int64(len(data)))
+	c.log.Trace().
+		Str("name", p.Name()).
+		Msg("sent packet")
+	return nil
+}
+
+// Stats returns the counters of the packets that this client read and wrote.
+// Packets that were skipped, because they are unknown or can't be decoded,
+// are counted as well.
+func (c *Client) Stats() Stats {
+	return Stats{
+		PacketsRead:    atomic.LoadInt64(&c.stats.PacketsRead),
+		PacketsWritten: atomic.LoadInt64(&c.stats.PacketsWritten),
+		BytesRead:      atomic.LoadInt64(&c.stats.BytesRead),
+		BytesWritten:   atomic.LoadInt64(&c.stats.BytesWritten),
+	}
+}
+
+// Close closes the connection to the server. This method is idempotent.
+func (c *Client) Close() error {
+	c.mu.Lock()
+	if c.closed {
+		c.mu.Unlock()
+		return nil
+	}
+	c.closed = true
+	reading := c.reading
+	c.mu.Unlock()
+
+	err := c.conn.Close()
+	if !reading {
+		// otherwise, the reading goroutine finishes as soon as reading fails
+		c.finish(chat.Chat{}, ErrClosed)
+	}
+	return err
+}
+
+// interruptOnDone closes the client as soon as the given context is done, until the
+// returned function is called, which replaces the error with the context's error
+// if the context is done.
+func (c *Client) interruptOnDone(ctx context.Context, err *error) func() {
+	stop := make(chan struct{})
+	go func() {
+		select {
+		case <-ctx.Done():
+			_ = c.Close()
+		case <-stop:
+		}
+	}()
+	return func() {
+		close(stop)
+		if *err != nil && ctx.Err() != nil {
+			*err = ctx.Err()
+		}
+	}
+}
+
+// handshake sends the handshake with the given next state, and transitions to the
+// given phase.
+func (c *Client) handshake(nextState packet.NextState, phase packet.Phase) error {
+	c.mu.Lock()
+	if c.phase != packet.PhaseHandshaking {
+		c.mu.Unlock()
+		return fmt.Errorf("%w: handshake in phase %s", ErrInvalidPhase, c.phase)
+	}
+	c.mu.Unlock()
+
+	if err := c.WritePacket(packet.ServerboundHandshake{
+		ProtocolVersion: c.version.Number,
+		ServerAddress:   c.host,
+		ServerPort:      c.port,
+		NextState:       nextState,
+	}); err != nil {
+		return err
+	}
+
+	c.mu.Lock()
+	c.phase = phase
+	c.mu.Unlock()
+	return nil
+}
+
+// answerLoginPlugin answers the given login plugin request with the handler of
+// its channel.
+func (c *Client) answerLoginPlugin(p *packet.ClientboundLoginPluginRequest) error {
+	response := packet.ServerboundLoginPluginResponse{
+		MessageID: p.MessageID,
+	}
+	if handler, ok := c.loginPlugins[p.Channel]; ok {
+		response.Data, response.Successful = handler(p.Data)
+	}
+	if !response.Successful {
+		response.Data = nil
+	}
+	return c.WritePacket(response)
+}
+
+// readPacket reads the next packet that can be decoded. Packets that are unknown or
+// can't be decoded are skipped.
+func (c *Client) readPacket() (packet.Packet, error) {
+	c.mu.Lock()
+	phase := c.phase
+	threshold := c.threshold
+	c.mu.Unlock()
+
+	for {
+		data, err := packet.ReadClientboundFrame(c.rd, threshold >= 0)
+		if err != nil {
+			return nil, err
+		}
+		atomic.AddInt64(&c.stats.PacketsRead, 1)
+		atomic.AddInt64(&c.stats.BytesRead, int64(len(data)))
+
+		p, err := c.version.Protocol.DecodeClientboundData(data, phase)
+		if errors.Is(err, packet.ErrUnknownPacketID) || errors.Is(err, packet.ErrUndecodablePacket) {
+			c.log.Trace().
+				Err(err).
+				Msg("skipping packet")
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		c.log.Trace().
+			Str("name", p.Name()).
+			Msg("received packet")
+		return p, nil
+	}
+}
+
+// readLoop reads and handles packets in the play phase, until reading fails or the
+// server disconnects the client.
+func (c *Client) readLoop() {
+	for {
+		p, err := c.readPacket()
+		if err != nil {
+			c.mu.Lock()
+			if c.closed {
+				err = ErrClosed
+			}
+			c.mu.Unlock()
+			c.finish(chat.Chat{}, err)
+			return
+		}
+
+		if disconnect, ok := p.(*packet.ClientboundDisconnectPlay); ok {
+			c.finish(disconnect.Reason, nil)
+			return
+		}
+		if err := c.handlePlay(p); err != nil {
+			c.finish(chat.Chat{}, err)
+			return
+		}
+	}
+}
+
+// handlePlay handles the given packet in the play phase, answers it if necessary,
+// and emits the respective event.
+func (c *Client) handlePlay(p packet.Packet) error {
+	switch p := p.(type) {
+	case *packet.ClientboundKeepAlive:
+		if err := c.WritePacket(packet.ServerboundKeepAlive{
+			KeepAliveID: p.KeepAliveID,
+		}); err != nil {
+			return err
+		}
+		c.emit(PacketEvent{Packet: p})
+	case *packet.ClientboundPlayerPositionAndLook:
+		position := c.teleport(p)
+		if err := c.WritePacket(packet.ServerboundTeleportConfirm{
+			TeleportID: p.TeleportID,
+		}); err != nil {
+			return err
+		}
+		c.emit(position)
+	case *packet.ClientboundJoinGame:
+		c.emit(JoinGameEvent{ClientboundJoinGame: *p})
+	case *packet.ClientboundPluginMessage:
+		c.emit(PluginMessageEvent{
+			Channel: p.Channel,
+			Data:    p.Data,
+		})
+	default:
+		c.emit(PacketEvent{Packet: p})
+	}
+	return nil
+}
+
+// teleport applies the given teleport to the position of the client, and returns
+// the new position.
+func (c *Client) teleport(p *packet.ClientboundPlayerPositionAndLook) PositionEvent {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	relative := func(flag int8) bool {
+		return p.Flags&flag != 0
+	}
+	next := PositionEvent{
+		X:     p.X,
+		Y:     p.Y,
+		Z:     p.Z,
+		Yaw:   p.Yaw,
+		Pitch: p.Pitch,
+	}
+	if relative(relativeX) {
+		next.X += c.position.X
+	}
+	if relative(relativeY) {
+		next.Y += c.position.Y
+	}
+	if relative(relativeZ) {
+		next.Z += c.position.Z
+	}
+	if relative(relativeYaw) {
+		next.Yaw += c.position.Yaw
+	}
+	if relative(relativePitch) {
+		next.Pitch += c.position.Pitch
+	}
+	c.position = next
+	return next
+}
+
+// emit queues the given event, or drops it if the queue is full.
+func (c *Client) emit(e Event) {
+	if len(c.events) >= c.eventQueueSize {
+		c.log.Warn().
+			Str("event", fmt.Sprintf("%T", e)).
+			Msg("event queue full, dropping event")
+		return
+	}
+	c.events <- e
+}
+
+// finish closes the connection, emits the DisconnectEvent and closes the events.
+func (c *Client) finish(reason chat.Chat, err error) {
+	c.finishOnce.Do(func() {
+		c.mu.Lock()
+		c.closed = true
+		c.mu.Unlock()
+		_ = c.conn.Close()
+
+		c.events <- DisconnectEvent{
+			Reason: reason,
+			Err:    err,
+		}
+		close(c.events)
+	})
+}
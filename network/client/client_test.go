@@ -0,0 +1,249 @@
+package client
+
+import (
+	"bufio"
+	"context"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/rs/zerolog"
+	"github.com/stretchr/testify/suite"
+
+	"github.com/tsatke/mcserver/game/chat"
+	"github.com/tsatke/mcserver/game/id"
+	"github.com/tsatke/mcserver/network/packet"
+)
+
+func TestClientSuite(t *testing.T) {
+	suite.Run(t, new(ClientSuite))
+}
+
+type ClientSuite struct {
+	suite.Suite
+
+	ctx    context.Context
+	cancel func()
+	client *Client
+	// server is the server side of the connection of the client.
+	server net.Conn
+	rd     *bufio.Reader
+	// threshold is the compression threshold of the server side.
+	threshold int
+}
+
+func (suite *ClientSuite) SetupTest() {
+	suite.start()
+}
+
+// start creates a new client with the given options, which is connected to the
+// server side of this suite.
+func (suite *ClientSuite) start(opts ...Option) {
+	lis, err := net.Listen("tcp", "127.0.0.1:0")
+	suite.Require().NoError(err)
+	defer func() { _ = lis.Close() }()
+
+	suite.ctx, suite.cancel = context.WithTimeout(context.Background(), 5*time.Second)
+	client, err := Dial(suite.ctx, zerolog.Nop(), lis.Addr().String(), opts...)
+	suite.Require().NoError(err)
+	suite.client = client
+
+	server, err := lis.Accept()
+	suite.Require().NoError(err)
+	suite.Require().NoError(server.SetDeadline(time.Now().Add(5 * time.Second)))
+	suite.server = server
+	suite.rd = bufio.NewReader(server)
+	suite.threshold = packet.CompressionDisabled
+}
+
+func (suite *ClientSuite) TearDownTest() {
+	suite.cancel()
+	_ = suite.client.Close()
+	_ = suite.server.Close()
+}
+
+func (suite *ClientSuite) send(p packet.Clientbound) {
+	suite.Require().NoError(packet.EncodeCompressed(p, suite.server, suite.threshold))
+}
+
+func (suite *ClientSuite) receive(phase packet.Phase) packet.Serverbound {
+	data, err := packet.ReadFrame(suite.rd, phase, suite.threshold >= 0)
+	suite.Require().NoError(err)
+	p, err := packet.Latest.DecodeData(data, phase)
+	suite.Require().NoError(err)
+	return p
+}
+
+// event returns the next event of the client.
+func (suite *ClientSuite) event() Event {
+	select {
+	case e, ok := <-suite.client.Events():
+		suite.Require().True(ok, "events closed")
+		return e
+	case <-suite.ctx.Done():
+		suite.FailNow("no event received")
+		return nil
+	}
+}
+
+// login performs the server side of the login, until the client is in the play phase.
+func (suite *ClientSuite) login() {
+	suite.Equal(packet.NextStateLogin, suite.receive(packet.PhaseHandshaking).(*packet.ServerboundHandshake).NextState)
+	suite.Equal("Notch", suite.receive(packet.PhaseLogin).(*packet.ServerboundLoginStart).Username)
+	suite.send(packet.ClientboundLoginSuccess{UUID: uuid.Nil, Username: "Notch"})
+}
+
+func (suite *ClientSuite) TestStatus() {
+	go func() {
+		handshake := suite.receive(packet.PhaseHandshaking).(*packet.ServerboundHandshake)
+		suite.Equal(packet.NextStateStatus, handshake.NextState)
+		suite.Equal(packet.LatestVersionNumber, handshake.ProtocolVersion)
+		suite.Equal("127.0.0.1", handshake.ServerAddress)
+		suite.IsType(&packet.ServerboundRequest{}, suite.receive(packet.PhaseStatus))
+		suite.send(packet.ClientboundResponse{JSONResponse: packet.Response{
+			Version: packet.ResponseVersion{Name: "1.16.5", Protocol: 754},
+			Players: packet.ResponsePlayers{Max: 20, Online: 1},
+		}})
+		ping := suite.receive(packet.PhaseStatus).(*packet.ServerboundPing)
+		suite.send(packet.ClientboundPong{Payload: ping.Payload})
+	}()
+
+	response, latency, err := suite.client.Status(suite.ctx)
+	suite.Require().NoError(err)
+	suite.Equal("1.16.5", response.Version.Name)
+	suite.Equal(754, response.Version.Protocol)
+	suite.Equal(20, response.Players.Max)
+	suite.Equal(1, response.Players.Online)
+	suite.True(latency > 0)
+
+	// the client is closed after the status
+	suite.Equal(DisconnectEvent{Err: ErrClosed}, suite.event())
+	_, err = suite.client.Login(suite.ctx, "Notch")
+	suite.Error(err)
+}
+
+func (suite *ClientSuite) TestLogin() {
+	suite.client.Close()
+	suite.start(WithLoginPluginHandler(id.ParseID("test:echo"), func(data []byte) ([]byte, bool) {
+		return data, true
+	}))
+
+	go func() {
+		suite.Equal(packet.NextStateLogin, suite.receive(packet.PhaseHandshaking).(*packet.ServerboundHandshake).NextState)
+		suite.Equal("Notch", suite.receive(packet.PhaseLogin).(*packet.ServerboundLoginStart).Username)
+
+		suite.send(packet.ClientboundSetCompression{Threshold: 16})
+		suite.threshold = 16
+
+		suite.send(packet.ClientboundLoginPluginRequest{MessageID: 1, Channel: id.ParseID("test:echo"), Data: []byte("hello")})
+		suite.Equal(&packet.ServerboundLoginPluginResponse{MessageID: 1, Successful: true, Data: []byte("hello")}, suite.receive(packet.PhaseLogin))
+		suite.send(packet.ClientboundLoginPluginRequest{MessageID: 2, Channel: id.ParseID("test:unknown"), Data: []byte("hello")})
+		suite.Equal(&packet.ServerboundLoginPluginResponse{MessageID: 2, Successful: false, Data: []byte{}}, suite.receive(packet.PhaseLogin))
+
+		suite.send(packet.ClientboundLoginSuccess{UUID: uuid.MustParse("069a79f4-44e9-4726-a5be-fca90e38aaf5"), Username: "Notch"})
+	}()
+
+	profile, err := suite.client.Login(suite.ctx, "Notch")
+	suite.Require().NoError(err)
+	suite.Equal(uuid.MustParse("069a79f4-44e9-4726-a5be-fca90e38aaf5"), profile.UUID)
+	suite.Equal("Notch", profile.Name)
+	suite.Equal(packet.PhasePlay, suite.client.Phase())
+}
+
+func (suite *ClientSuite) TestLoginDisconnected() {
+	go func() {
+		suite.receive(packet.PhaseHandshaking)
+		suite.receive(packet.PhaseLogin)
+		suite.send(packet.ClientboundDisconnectLogin{Reason: chat.Chat{ChatFragment: chat.ChatFragment{Text: "banned"}}})
+	}()
+
+	_, err := suite.client.Login(suite.ctx, "Notch")
+	suite.ErrorIs(err, ErrDisconnected)
+	suite.Contains(err.Error(), "banned")
+}
+
+func (suite *ClientSuite) TestLoginEncryptionRequired() {
+	go func() {
+		suite.receive(packet.PhaseHandshaking)
+		suite.receive(packet.PhaseLogin)
+		suite.send(packet.ClientboundEncryptionRequest{PublicKey: []byte{1}, VerifyToken: []byte{2}})
+	}()
+
+	_, err := suite.client.Login(suite.ctx, "Notch")
+	suite.ErrorIs(err, ErrEncryptionRequired)
+}
+
+func (suite *ClientSuite) TestLoginCancelled() {
+	cancelled, cancel := context.WithCancel(suite.ctx)
+	cancel()
+
+	_, err := suite.client.Login(cancelled, "Notch")
+	suite.ErrorIs(err, context.Canceled)
+}
+
+func (suite *ClientSuite) TestPlay() {
+	go suite.login()
+	_, err := suite.client.Login(suite.ctx, "Notch")
+	suite.Require().NoError(err)
+
+	// keep alives are answered
+	suite.send(packet.ClientboundKeepAlive{KeepAliveID: 42})
+	suite.Equal(&packet.ServerboundKeepAlive{KeepAliveID: 42}, suite.receive(packet.PhasePlay))
+	suite.Equal(PacketEvent{Packet: &packet.ClientboundKeepAlive{KeepAliveID: 42}}, suite.event())
+
+	// teleports are confirmed
+	suite.send(packet.ClientboundPlayerPositionAndLook{X: 1, Y: 2, Z: 3, Yaw: 90, TeleportID: 7})
+	suite.Equal(&packet.ServerboundTeleportConfirm{TeleportID: 7}, suite.receive(packet.PhasePlay))
+	suite.Equal(PositionEvent{X: 1, Y: 2, Z: 3, Yaw: 90}, suite.event())
+	suite.send(packet.ClientboundPlayerPositionAndLook{Y: 10, Flags: relativeY, TeleportID: 8})
+	suite.Equal(&packet.ServerboundTeleportConfirm{TeleportID: 8}, suite.receive(packet.PhasePlay))
+	suite.Equal(PositionEvent{X: 0, Y: 12, Z: 0}, suite.event())
+	suite.Equal(PositionEvent{Y: 12}, suite.client.Position())
+
+	suite.send(packet.ClientboundPluginMessage{Channel: id.ParseID("minecraft:brand"), Data: []byte("test")})
+	suite.Equal(PluginMessageEvent{Channel: id.ParseID("minecraft:brand"), Data: []byte("test")}, suite.event())
+
+	suite.Require().NoError(suite.client.WritePacket(packet.ServerboundPluginMessage{Channel: id.ParseID("test:ping"), Data: []byte{1}}))
+	suite.Equal(&packet.ServerboundPluginMessage{Channel: id.ParseID("test:ping"), Data: []byte{1}}, suite.receive(packet.PhasePlay))
+
+	stats := suite.client.Stats()
+	// login success, keep alive, two teleports and plugin message
+	suite.Equal(int64(5), stats.PacketsRead)
+	// handshake, login start, keep alive, two teleport confirms and plugin message
+	suite.Equal(int64(6), stats.PacketsWritten)
+	suite.True(stats.BytesRead > 0)
+	suite.True(stats.BytesWritten > 0)
+
+	reason := chat.Chat{ChatFragment: chat.ChatFragment{Text: "bye"}}
+	suite.send(packet.ClientboundDisconnectPlay{Reason: reason})
+	suite.Equal(DisconnectEvent{Reason: reason}, suite.event())
+	_, ok := <-suite.client.Events()
+	suite.False(ok)
+}
+
+func (suite *ClientSuite) TestPlayClosed() {
+	go suite.login()
+	_, err := suite.client.Login(suite.ctx, "Notch")
+	suite.Require().NoError(err)
+
+	suite.NoError(suite.client.Close())
+	suite.Equal(DisconnectEvent{Err: ErrClosed}, suite.event())
+	suite.ErrorIs(suite.client.WritePacket(packet.ServerboundKeepAlive{}), ErrClosed)
+}
+
+func (suite *ClientSuite) TestEventQueueFull() {
+	suite.client.Close()
+	suite.start(WithEventQueueSize(1))
+
+	go suite.login()
+	_, err := suite.client.Login(suite.ctx, "Notch")
+	suite.Require().NoError(err)
+
+	// keep alives are still answered if the events are not read
+	for i := int64(0); i < 3; i++ {
+		suite.send(packet.ClientboundKeepAlive{KeepAliveID: i})
+		suite.Equal(&packet.ServerboundKeepAlive{KeepAliveID: i}, suite.receive(packet.PhasePlay))
+	}
+	suite.Equal(PacketEvent{Packet: &packet.ClientboundKeepAlive{KeepAliveID: 0}}, suite.event())
+}
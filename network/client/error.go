@@ -0,0 +1,23 @@
+package client
+
+type Error string
+
+func (e Error) Error() string { return string(e) }
+
+const (
+	// ErrClosed indicates, that the client was closed with Close.
+	ErrClosed Error = "client closed"
+	// ErrDisconnected indicates, that the server disconnected the client. The
+	// error message contains the reason.
+	ErrDisconnected Error = "disconnected by server"
+	// ErrEncryptionRequired indicates, that the server is in online mode and requested
+	// encryption, which is not supported, since the client can't authenticate with
+	// the session server.
+	ErrEncryptionRequired Error = "server requires encryption"
+	// ErrUnexpectedPacket indicates, that the server sent a packet that is not
+	// allowed at that point. The connection has been closed.
+	ErrUnexpectedPacket Error = "unexpected packet"
+	// ErrInvalidPhase indicates, that a method was called in a phase in which it
+	// can't be used, e.g. Login after Status.
+	ErrInvalidPhase Error = "invalid phase"
+)
@@ -0,0 +1,54 @@
+package client
+
+import (
+	"github.com/tsatke/mcserver/game/chat"
+	"github.com/tsatke/mcserver/game/id"
+	"github.com/tsatke/mcserver/network/packet"
+)
+
+// Event is something that happened in the play phase, which is received from
+// Client.Events. It is one of the event types of this package.
+type Event interface {
+	event()
+}
+
+// JoinGameEvent is emitted when the server sent the information about the world
+// that the player joined.
+type JoinGameEvent struct {
+	packet.ClientboundJoinGame
+}
+
+// PositionEvent is emitted when the server teleported the player, after the
+// teleport was confirmed. The position is absolute, even if the server sent a
+// relative one.
+type PositionEvent struct {
+	X, Y, Z    float64
+	Yaw, Pitch float32
+}
+
+// PluginMessageEvent is emitted when the server sent a plugin message.
+type PluginMessageEvent struct {
+	Channel id.ID
+	Data    []byte
+}
+
+// PacketEvent is emitted for every packet that was received and decoded, and
+// isn't covered by another event, e.g. keep alives.
+type PacketEvent struct {
+	Packet packet.Packet
+}
+
+// DisconnectEvent is the last event, after which the connection is closed.
+// If the server disconnected the client, Reason holds the reason that it sent.
+// Otherwise, Err is the error that made the connection fail, or ErrClosed
+// if the client was closed with Close.
+type DisconnectEvent struct {
+	Reason chat.Chat
+	Err    error
+}
+
+func (JoinGameEvent) event()      {}
+func (PositionEvent) event()      {}
+func (PluginMessageEvent) event() {}
+func (PacketEvent) event()        {}
+func (DisconnectEvent) event()    {}
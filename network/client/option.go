@@ -0,0 +1,45 @@
+package client
+
+import (
+	"github.com/tsatke/mcserver/game/id"
+	"github.com/tsatke/mcserver/network/packet"
+)
+
+// Option is used to configure a Client on creation.
+type Option func(*Client)
+
+// WithVersion makes the client connect with the given protocol version. The
+// default is packet.LatestVersion.
+func WithVersion(version packet.Version) Option {
+	return func(c *Client) {
+		c.version = version
+	}
+}
+
+// WithServerAddress sets the address that the client sends in the handshake, which
+// is the address that the client connected to. The default is the remote address of
+// the connection, or the address that was passed to Dial.
+func WithServerAddress(host string, port int) Option {
+	return func(c *Client) {
+		c.host = host
+		c.port = port
+	}
+}
+
+// WithLoginPluginHandler makes the client answer login plugin requests on the given
+// channel with the given handler. Requests on channels without a handler are
+// answered as not understood, like the vanilla client does.
+func WithLoginPluginHandler(channel id.ID, handler LoginPluginHandler) Option {
+	return func(c *Client) {
+		c.loginPlugins[channel] = handler
+	}
+}
+
+// WithEventQueueSize sets the amount of events that are queued until they are read
+// from Events. If the queue is full, further events are dropped, so that keep alives
+// are still answered if the events are not read. The default is DefaultEventQueueSize.
+func WithEventQueueSize(size int) Option {
+	return func(c *Client) {
+		c.eventQueueSize = size
+	}
+}
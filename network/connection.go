@@ -1,42 +1,197 @@
 package network
 
 import (
+	"bufio"
+	"bytes"
+	"crypto/aes"
+	"crypto/cipher"
+	"errors"
 	"fmt"
+	"io"
 	"net"
+	"sync"
+	"time"
 
 	"github.com/rs/zerolog"
 
+	"github.com/tsatke/mcserver/network/capture"
 	"github.com/tsatke/mcserver/network/packet"
 )
 
+const (
+	// DefaultQueueSize is the amount of packets that can be queued for writing
+	// on a connection, before the OverflowPolicy is applied.
+	DefaultQueueSize = 1024
+
+	// writeBufferSize is the size of the buffer that outgoing packets are collected
+	// in before they are written to the underlying connection.
+	writeBufferSize = 1 << 15
+	// closeTimeout is the maximum time that Close waits for queued packets to be
+	// written to the client.
+	closeTimeout = 5 * time.Second
+)
+
+// Conn is a connection to a client that packets can be read from and written to.
+// Written packets are queued and written to the underlying connection by a separate
+// goroutine, so WritePacket is safe for concurrent use and a slow client doesn't
+// block the writing goroutine. ReadPacket must only be called by a single goroutine.
 type Conn struct {
 	log        zerolog.Logger
 	underlying net.Conn
-	phase      packet.Phase
-	closed     bool
+
+	// mu guards phase, phaseDeadline, closed, protocol, ip and compressionThreshold.
+	mu    sync.Mutex
+	phase packet.Phase
+	// phaseDeadline is the time until which the client has to complete the current
+	// phase, or the zero time if there is no phase timeout for the current phase.
+	phaseDeadline time.Time
+	closed        bool
+	// protocol holds the packet IDs of the client's protocol version.
+	protocol *packet.Protocol
+	// ip is the IP of the client, if it was set with SetIP.
+	ip net.IP
+
+	// rd is the reader that packets are read from. This is the underlying
+	// connection, or a decrypting reader on top of it.
+	rd io.Reader
+	// bw buffers the frames that are written to the underlying connection, or
+	// an encrypting writer on top of it. It must only be used by the writer goroutine.
+	bw *bufio.Writer
+
+	// compressionThreshold is the threshold that is used for compressing packets.
+	// If this is packet.CompressionDisabled, the uncompressed packet format is used.
+	compressionThreshold int
+
+	queueSize      int
+	overflowPolicy OverflowPolicy
+	// phaseTimeouts are the times that the client has to complete a phase in.
+	phaseTimeouts map[packet.Phase]time.Duration
+	// idleTimeout is the maximum time between two packets in the play phase.
+	idleTimeout time.Duration
+	// start is the time at which the handshaking phase started.
+	start time.Time
+	// capture records all packets of this connection, if not nil.
+	capture *capture.Writer
+	// queue holds the outgoing frames that are written by the writer goroutine.
+	queue chan outgoing
+	// closing is closed as soon as this connection is closed, which makes the writer
+	// goroutine write all remaining frames and stop.
+	closing chan struct{}
+	// writerDone is closed when the writer goroutine stopped.
+	writerDone chan struct{}
 }
 
-func NewConn(log zerolog.Logger, underlying net.Conn) *Conn {
-	return &Conn{
+// outgoing is an element in the write queue of a connection.
+type outgoing struct {
+	// frame is the encoded packet that is written, or nil.
+	frame []byte
+	// fn is called by the writer goroutine after all previous frames have been
+	// flushed to the client, if not nil.
+	fn func() error
+	// done receives the error of flushing and calling fn, if not nil.
+	done chan error
+}
+
+// NewConn creates a new connection on top of the given net.Conn and starts the
+// goroutine that writes outgoing packets. The connection must be closed with
+// Close, so that the goroutine is stopped.
+func NewConn(log zerolog.Logger, underlying net.Conn, opts ...Option) *Conn {
+	c := &Conn{
 		log:        log,
 		underlying: underlying,
 		phase:      packet.PhaseHandshaking,
+		protocol:   packet.Latest,
+
+		rd: underlying,
+		bw: bufio.NewWriterSize(underlying, writeBufferSize),
+
+		compressionThreshold: packet.CompressionDisabled,
+
+		queueSize:      DefaultQueueSize,
+		overflowPolicy: OverflowDisconnect,
+		phaseTimeouts:  make(map[packet.Phase]time.Duration),
+		start:          time.Now(),
+		closing:        make(chan struct{}),
+		writerDone:     make(chan struct{}),
+	}
+
+	for _, opt := range opts {
+		opt(c)
 	}
+
+	c.phaseDeadline = c.deadlineOf(packet.PhaseHandshaking, c.start)
+
+	c.queue = make(chan outgoing, c.queueSize)
+	go c.writeLoop()
+
+	return c
 }
 
 // Phase returns the packet.Phase that this connection is currently in.
-func (c Conn) Phase() packet.Phase {
+func (c *Conn) Phase() packet.Phase {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
 	return c.phase
 }
 
-// IP returns the IP address of the remote end of this connection.
-func (c Conn) IP() net.IP {
-	return c.underlying.RemoteAddr().(*net.TCPAddr).IP
+// IP returns the IP address of the remote end of this connection, which is the
+// IP of the underlying connection's RemoteAddr, unless it was overridden with SetIP.
+// If the server runs behind a proxy, the underlying connection should report the
+// address of the actual client.
+func (c *Conn) IP() net.IP {
+	c.mu.Lock()
+	ip := c.ip
+	c.mu.Unlock()
+
+	if ip != nil {
+		return ip
+	}
+	return AddrIP(c.underlying.RemoteAddr())
+}
+
+// SetIP overrides the IP address that IP returns. This is used if a proxy forwards
+// the IP of the client after the connection was established.
+func (c *Conn) SetIP(ip net.IP) {
+	c.mu.Lock()
+	c.ip = ip
+	c.mu.Unlock()
+}
+
+// AddrIP returns the IP of the given address. If the address doesn't
+// contain an IP, nil is returned.
+func AddrIP(addr net.Addr) net.IP {
+	switch a := addr.(type) {
+	case *net.TCPAddr:
+		return a.IP
+	case *net.UDPAddr:
+		return a.IP
+	case *net.IPAddr:
+		return a.IP
+	case nil:
+		return nil
+	}
+	host, _, err := net.SplitHostPort(addr.String())
+	if err != nil {
+		host = addr.String()
+	}
+	return net.ParseIP(host)
+}
+
+// SetProtocol sets the protocol that is used to encode and decode all following
+// packets. Until this is called, packet.Latest is used.
+func (c *Conn) SetProtocol(protocol *packet.Protocol) {
+	c.mu.Lock()
+	c.protocol = protocol
+	c.mu.Unlock()
 }
 
 // TransitionTo sets the phase of this connection to the given phase.
 // This method will panic if the transition is invalid.
 func (c *Conn) TransitionTo(phase packet.Phase) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
 	if !transitionValid(c.phase, phase) {
 		panic(fmt.Sprintf("cannot transition from %s to %s", c.phase, phase))
 	}
@@ -44,6 +199,66 @@ func (c *Conn) TransitionTo(phase packet.Phase) {
 		Stringer("to", phase).
 		Msg("transition connection")
 	c.phase = phase
+	c.phaseDeadline = c.deadlineOf(phase, time.Now())
+}
+
+// deadlineOf returns the deadline of the given phase, if it starts at the given time.
+// If there is no timeout for the phase, the zero time is returned.
+func (c *Conn) deadlineOf(phase packet.Phase, start time.Time) time.Time {
+	timeout, ok := c.phaseTimeouts[phase]
+	if !ok || timeout <= 0 {
+		return time.Time{}
+	}
+	return start.Add(timeout)
+}
+
+// readDeadline returns the deadline for reading the next packet in the given phase,
+// and the timeout that it is derived from, which is either the phase timeout or
+// the idle timeout.
+func (c *Conn) readDeadline(phase packet.Phase, phaseDeadline time.Time) (time.Time, time.Duration) {
+	if phase == packet.PhasePlay && c.idleTimeout > 0 {
+		return time.Now().Add(c.idleTimeout), c.idleTimeout
+	}
+	return phaseDeadline, c.phaseTimeouts[phase]
+}
+
+// EnableCompression makes this connection use the compressed packet format for
+// all following packets in both directions. Packets that are at least threshold
+// bytes long will be compressed. The client has to be notified about this with a
+// packet.ClientboundSetCompression before calling this method.
+func (c *Conn) EnableCompression(threshold int) {
+	c.log.Debug().
+		Int("threshold", threshold).
+		Msg("enable compression")
+
+	c.mu.Lock()
+	c.compressionThreshold = threshold
+	c.mu.Unlock()
+}
+
+// EnableEncryption makes this connection encrypt all following packets in both
+// directions with AES/CFB8, using the given shared secret as key and initial vector.
+// The shared secret must be 16 bytes long.
+func (c *Conn) EnableEncryption(sharedSecret []byte) error {
+	block, err := aes.NewCipher(sharedSecret)
+	if err != nil {
+		return fmt.Errorf("create cipher: %w", err)
+	}
+
+	c.log.Debug().
+		Msg("enable encryption")
+	c.rd = cipher.StreamReader{
+		S: NewCFB8Decrypter(block, sharedSecret),
+		R: c.underlying,
+	}
+	// all packets that were written before must still be sent unencrypted
+	return c.runInWriter(func() error {
+		c.bw.Reset(cipher.StreamWriter{
+			S: NewCFB8Encrypter(block, sharedSecret),
+			W: c.underlying,
+		})
+		return nil
+	})
 }
 
 func transitionValid(from, to packet.Phase) bool {
@@ -62,43 +277,269 @@ func transitionValid(from, to packet.Phase) bool {
 // or contains invalid values, then a respective error that is describing the issue is returned.
 // A packet is considered as containing invalid values if the packet definition implements the
 // packet.Validator interface and the validation fails.
+// Packets with an ID that is unknown in the current phase are skipped. If any other error is
+// returned, the connection should be closed, since the stream may be out of sync.
+// If the client doesn't complete the current phase or, in the play phase, doesn't send a
+// packet in time (see WithPhaseTimeout and WithIdleTimeout), the connection is closed and
+// ErrTimeout is returned.
 func (c *Conn) ReadPacket() (packet.Serverbound, error) {
-	if c.closed {
+	c.mu.Lock()
+	closed := c.closed
+	phase := c.phase
+	phaseDeadline := c.phaseDeadline
+	threshold := c.compressionThreshold
+	protocol := c.protocol
+	c.mu.Unlock()
+	if closed {
 		return nil, ErrClosed
 	}
 
-	packet, err := packet.Decode(c.underlying, c.phase)
-	if err != nil {
-		return nil, err
+	for {
+		deadline, timeout := c.readDeadline(phase, phaseDeadline)
+		if err := c.underlying.SetReadDeadline(deadline); err != nil {
+			return nil, fmt.Errorf("set read deadline: %w", err)
+		}
+
+		data, err := packet.ReadFrame(c.rd, phase, threshold >= 0)
+		if isTimeout(err) {
+			c.log.Info().
+				Stringer("phase", phase).
+				Stringer("timeout", timeout).
+				Msg("client stalled, closing connection")
+			_ = c.Close()
+			return nil, ErrTimeout
+		}
+		if err != nil {
+			return nil, err
+		}
+		c.record(capture.DirectionServerbound, phase, data)
+
+		p, err := protocol.DecodeData(data, phase)
+		if errors.Is(err, packet.ErrUnknownPacketID) {
+			c.log.Debug().
+				Err(err).
+				Msg("skipping packet")
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		c.log.Trace().
+			Str("name", p.Name()).
+			Msg("received packet")
+		return p, nil
 	}
-	c.log.Trace().
-		Str("name", packet.Name()).
-		Msg("received packet")
-	return packet, nil
 }
 
-// WritePacket writes the given clientbound packet to the client, returning any error if any
-// returns.
+// isTimeout determines whether the given error is caused by an exceeded deadline.
+func isTimeout(err error) bool {
+	var netErr net.Error
+	return errors.As(err, &netErr) && netErr.Timeout()
+}
+
+// WritePacket encodes the given clientbound packet and queues it for writing to the client.
+// This method doesn't wait for the packet to be written, use Flush for that. If the queue
+// of this connection is full, the OverflowPolicy of this connection is applied.
+// This method is safe for concurrent use.
 func (c *Conn) WritePacket(p packet.Clientbound) error {
-	if c.closed {
+	c.mu.Lock()
+	closed := c.closed
+	phase := c.phase
+	threshold := c.compressionThreshold
+	protocol := c.protocol
+	c.mu.Unlock()
+	if closed {
 		return ErrClosed
 	}
 
-	if err := packet.Encode(p, c.underlying); err != nil {
+	data, err := protocol.EncodeData(p)
+	if err != nil {
 		return fmt.Errorf("encode into: %w", err)
 	}
+	var buf bytes.Buffer
+	if err := packet.WriteFrame(&buf, data, threshold); err != nil {
+		return fmt.Errorf("encode into: %s: %w", p.Name(), err)
+	}
+	c.record(capture.DirectionClientbound, phase, data)
+	if err := c.enqueue(outgoing{frame: buf.Bytes()}); err != nil {
+		return err
+	}
 	c.log.Trace().
 		Str("name", p.Name()).
 		Msg("sent packet")
 	return nil
 }
 
-// Close closes this connection. This method is idempotent.
+// record writes the given packet data into the capture of this connection, if
+// packets are captured.
+func (c *Conn) record(direction capture.Direction, phase packet.Phase, data []byte) {
+	if c.capture == nil {
+		return
+	}
+	if err := c.capture.Write(capture.Record{
+		Direction: direction,
+		Phase:     phase,
+		Time:      time.Now(),
+		Data:      data,
+	}); err != nil {
+		c.log.Debug().
+			Err(err).
+			Msg("capture packet failed")
+	}
+}
+
+// Flush blocks until all packets that were written before were sent to the client.
+func (c *Conn) Flush() error {
+	return c.runInWriter(nil)
+}
+
+// Close closes this connection. Packets that were already written are still sent
+// to the client, if that is possible within a few seconds. This method is idempotent.
 func (c *Conn) Close() error {
-	if c.closed {
+	if !c.markClosed() {
 		return nil
 	}
 
-	c.closed = true
+	_ = c.underlying.SetWriteDeadline(time.Now().Add(closeTimeout))
+	<-c.writerDone
 	return c.underlying.Close()
 }
+
+// markClosed marks this connection as closed and signals the writer goroutine to stop.
+// Returns false if the connection was already closed.
+func (c *Conn) markClosed() bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.closed {
+		return false
+	}
+	c.closed = true
+	close(c.closing)
+	if c.capture != nil {
+		_ = c.capture.Close()
+	}
+	return true
+}
+
+// abort closes this connection without sending any queued packets.
+func (c *Conn) abort() {
+	c.markClosed()
+	_ = c.underlying.Close()
+}
+
+// runInWriter queues fn to be executed by the writer goroutine, after all previously
+// queued frames have been flushed, and waits for it. fn may be nil.
+func (c *Conn) runInWriter(fn func() error) error {
+	done := make(chan error, 1)
+	if err := c.enqueue(outgoing{fn: fn, done: done}); err != nil {
+		return err
+	}
+
+	select {
+	case err := <-done:
+		return err
+	case <-c.writerDone:
+		select {
+		case err := <-done:
+			return err
+		default:
+			return ErrClosed
+		}
+	}
+}
+
+func (c *Conn) enqueue(o outgoing) error {
+	select {
+	case <-c.closing:
+		return ErrClosed
+	default:
+	}
+
+	select {
+	case c.queue <- o:
+		return nil
+	default:
+	}
+
+	switch c.overflowPolicy {
+	case OverflowBlock:
+		select {
+		case c.queue <- o:
+			return nil
+		case <-c.closing:
+			return ErrClosed
+		}
+	default:
+		c.log.Warn().
+			Int("size", c.queueSize).
+			Msg("write queue overflow, closing connection")
+		c.abort()
+		return ErrQueueOverflow
+	}
+}
+
+// writeLoop writes all queued frames to the client, until the connection is closed
+// or a write fails.
+func (c *Conn) writeLoop() {
+	defer close(c.writerDone)
+
+	for {
+		select {
+		case o := <-c.queue:
+			if err := c.write(o); err != nil {
+				c.writeFailed(err)
+				return
+			}
+		case <-c.closing:
+			// write what's left in the queue, then stop
+			for {
+				select {
+				case o := <-c.queue:
+					if err := c.write(o); err != nil {
+						c.writeFailed(err)
+						return
+					}
+				default:
+					if err := c.bw.Flush(); err != nil {
+						c.writeFailed(err)
+					}
+					return
+				}
+			}
+		}
+	}
+}
+
+// write writes the given element into the write buffer. The buffer is flushed
+// if the queue is empty, so that multiple packets that are written at once are
+// sent together, or if the element requires it.
+func (c *Conn) write(o outgoing) (err error) {
+	if o.done != nil {
+		defer func() {
+			o.done <- err
+		}()
+	}
+
+	if o.frame != nil {
+		if _, err := c.bw.Write(o.frame); err != nil {
+			return err
+		}
+	}
+	if len(c.queue) == 0 || o.fn != nil || o.done != nil {
+		if err := c.bw.Flush(); err != nil {
+			return err
+		}
+	}
+	if o.fn != nil {
+		return o.fn()
+	}
+	return nil
+}
+
+func (c *Conn) writeFailed(err error) {
+	c.log.Debug().
+		Err(err).
+		Msg("write failed, closing connection")
+	c.abort()
+}
@@ -1,10 +1,16 @@
 package network
 
 import (
+	"compress/zlib"
+	"crypto/aes"
+	"crypto/cipher"
 	"io"
+	"io/ioutil"
 	"net"
+	"sync"
 	"testing"
 	"testing/iotest"
+	"time"
 
 	"github.com/google/uuid"
 	"github.com/rs/zerolog"
@@ -67,6 +73,113 @@ func (suite *ConnectionSuite) TestReadPacket() {
 		NextState:       1,
 	}, p)
 }
+func (suite *ConnectionSuite) TestCompression() {
+	net1, net2 := net.Pipe()
+	conn := NewConn(zerolog.Nop(), net1)
+	conn.EnableCompression(0)
+	go func() {
+		err := conn.WritePacket(packet.ClientboundPong{
+			Payload: 123456789,
+		})
+		if err != nil {
+			panic(err)
+		}
+	}()
+
+	dec := packet.Decoder{Rd: net2}
+	packetLen := dec.ReadVarInt("packet length")
+	frame := io.LimitReader(net2, int64(packetLen))
+	suite.EqualValues(9, packet.Decoder{Rd: frame}.ReadVarInt("data length"))
+	zr, err := zlib.NewReader(frame)
+	suite.Require().NoError(err)
+	zdec := packet.Decoder{Rd: zr}
+	suite.EqualValues(packet.IDClientboundPong, zdec.ReadVarInt("packet id"))
+	suite.EqualValues(123456789, zdec.ReadLong("payload"))
+
+	conn.TransitionTo(packet.PhaseStatus)
+	go func() {
+		enc := packet.Encoder{W: net2}
+		enc.WriteVarInt("packet length", 10)
+		enc.WriteVarInt("data length", 0)
+		enc.WriteVarInt("packet id", int(packet.IDServerboundPing))
+		enc.WriteLong("payload", 987654321)
+	}()
+	p, err := conn.ReadPacket()
+	suite.NoError(err)
+	suite.Equal(&packet.ServerboundPing{
+		Payload: 987654321,
+	}, p)
+}
+
+func (suite *ConnectionSuite) TestEncryption() {
+	net1, net2 := net.Pipe()
+	conn := NewConn(zerolog.Nop(), net1)
+	sharedSecret := []byte("0123456789abcdef")
+	suite.NoError(conn.EnableEncryption(sharedSecret))
+
+	block, err := aes.NewCipher(sharedSecret)
+	suite.Require().NoError(err)
+	clientRd := cipher.StreamReader{S: NewCFB8Decrypter(block, sharedSecret), R: net2}
+	clientWr := cipher.StreamWriter{S: NewCFB8Encrypter(block, sharedSecret), W: net2}
+
+	go func() {
+		err := conn.WritePacket(packet.ClientboundPong{
+			Payload: 123456789,
+		})
+		if err != nil {
+			panic(err)
+		}
+	}()
+	dec := packet.Decoder{Rd: clientRd}
+	suite.EqualValues(9, dec.ReadVarInt("packet length"))
+	suite.EqualValues(packet.IDClientboundPong, dec.ReadVarInt("packet id"))
+	suite.EqualValues(123456789, dec.ReadLong("payload"))
+
+	conn.TransitionTo(packet.PhaseStatus)
+	go func() {
+		enc := packet.Encoder{W: clientWr}
+		enc.WriteVarInt("packet length", 9)
+		enc.WriteVarInt("packet id", int(packet.IDServerboundPing))
+		enc.WriteLong("payload", 987654321)
+	}()
+	p, err := conn.ReadPacket()
+	suite.NoError(err)
+	suite.Equal(&packet.ServerboundPing{
+		Payload: 987654321,
+	}, p)
+}
+
+func (suite *ConnectionSuite) TestReadPacketSkipsUnknown() {
+	net1, net2 := net.Pipe()
+	sink := NewConn(zerolog.Nop(), net1)
+	sink.TransitionTo(packet.PhaseStatus)
+	go func() {
+		enc := packet.Encoder{W: net2}
+		enc.WriteVarInt("packet length", 4)
+		enc.WriteVarInt("packet id", 0x7f)
+		enc.WriteByteArray("payload", []byte{1, 2, 3})
+		enc.WriteVarInt("packet length", 9)
+		enc.WriteVarInt("packet id", int(packet.IDServerboundPing))
+		enc.WriteLong("payload", 987654321)
+	}()
+	p, err := sink.ReadPacket()
+	suite.NoError(err)
+	suite.Equal(&packet.ServerboundPing{
+		Payload: 987654321,
+	}, p)
+}
+
+func (suite *ConnectionSuite) TestReadPacketFrameTooLarge() {
+	net1, net2 := net.Pipe()
+	sink := NewConn(zerolog.Nop(), net1)
+	go func() {
+		packet.Encoder{W: net2}.WriteVarInt("packet length", packet.MaxFrameLength)
+	}()
+	p, err := sink.ReadPacket()
+	suite.ErrorIs(err, packet.ErrFrameTooLarge)
+	suite.Nil(p)
+}
+
 func (suite *ConnectionSuite) TestReadPacketMalformedWrite() {
 	net1, net2 := net.Pipe()
 	sink := NewConn(zerolog.Nop(), net1)
@@ -90,7 +203,7 @@ func (suite *ConnectionSuite) TestReadPacketMalformedWrite() {
 	p, err := sink.ReadPacket()
 	suite.NotEqual(io.EOF, err) // make sure that the error is not simply EOF, since we stop writing in the middle of a string
 	// the error message may change, however, it must not be simply io.EOF
-	suite.EqualError(err, "decode Handshake: server address: prefix indicated length of 13, but only got 9 bytes")
+	suite.EqualError(err, "read frame: unexpected EOF")
 	suite.Nil(p)
 }
 
@@ -106,6 +219,66 @@ func (suite *ConnectionSuite) TestReadPacketClosedConnection() {
 	suite.Nil(p)
 }
 
+func (suite *ConnectionSuite) TestReadPacketPhaseTimeout() {
+	net1, net2 := net.Pipe()
+	defer func() { _ = net2.Close() }()
+	sink := NewConn(zerolog.Nop(), net1,
+		WithPhaseTimeout(packet.PhaseHandshaking, 10*time.Millisecond),
+		WithPhaseTimeout(packet.PhaseStatus, time.Hour),
+	)
+	// the client sends nothing
+	p, err := sink.ReadPacket()
+	suite.ErrorIs(err, ErrTimeout)
+	suite.Nil(p)
+	suite.True(sink.closed)
+}
+
+func (suite *ConnectionSuite) TestReadPacketPhaseTimeoutStartTime() {
+	net1, net2 := net.Pipe()
+	defer func() { _ = net2.Close() }()
+	// the handshake timeout already elapsed before the connection was created
+	sink := NewConn(zerolog.Nop(), net1,
+		WithPhaseTimeout(packet.PhaseHandshaking, time.Minute),
+		WithStartTime(time.Now().Add(-time.Minute)),
+	)
+	start := time.Now()
+	p, err := sink.ReadPacket()
+	suite.ErrorIs(err, ErrTimeout)
+	suite.Nil(p)
+	suite.Less(int64(time.Since(start)), int64(time.Second))
+}
+
+func (suite *ConnectionSuite) TestReadPacketIdleTimeout() {
+	net1, net2 := net.Pipe()
+	defer func() { _ = net2.Close() }()
+	sink := NewConn(zerolog.Nop(), net1,
+		WithPhaseTimeout(packet.PhasePlay, time.Hour),
+		WithIdleTimeout(50*time.Millisecond),
+	)
+	sink.TransitionTo(packet.PhaseLogin)
+	sink.TransitionTo(packet.PhasePlay)
+
+	go func() {
+		// keep the client active for longer than the idle timeout
+		for i := 0; i < 5; i++ {
+			time.Sleep(20 * time.Millisecond)
+			enc := packet.Encoder{W: net2}
+			enc.WriteVarInt("packet length", 9)
+			enc.WriteVarInt("packet id", int(packet.IDServerboundKeepAlive))
+			enc.WriteLong("keep alive id", int64(i))
+		}
+	}()
+	for i := 0; i < 5; i++ {
+		p, err := sink.ReadPacket()
+		suite.NoError(err)
+		suite.Equal(&packet.ServerboundKeepAlive{KeepAliveID: int64(i)}, p)
+	}
+
+	p, err := sink.ReadPacket()
+	suite.ErrorIs(err, ErrTimeout)
+	suite.Nil(p)
+}
+
 func (suite *ConnectionSuite) TestClose() {
 	net, _ := net.Pipe()
 	conn := NewConn(zerolog.Nop(), net)
@@ -118,6 +291,89 @@ func (suite *ConnectionSuite) TestClose() {
 	suite.True(conn.closed)
 }
 
+func (suite *ConnectionSuite) TestWritePacketConcurrent() {
+	net1, net2 := net.Pipe()
+	conn := NewConn(zerolog.Nop(), net1)
+	defer func() { _ = conn.Close() }()
+
+	const writers, packets = 10, 50
+	var wg sync.WaitGroup
+	for i := 0; i < writers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for j := 0; j < packets; j++ {
+				suite.NoError(conn.WritePacket(packet.ClientboundPong{
+					Payload: 123456789,
+				}))
+			}
+		}()
+	}
+
+	defer func() {
+		rec := recover()
+		suite.Nilf(rec, "%v", rec)
+	}()
+	dec := packet.Decoder{Rd: net2}
+	for i := 0; i < writers*packets; i++ {
+		suite.EqualValues(9, dec.ReadVarInt("packet length"))
+		suite.EqualValues(packet.IDClientboundPong, dec.ReadVarInt("packet id"))
+		suite.EqualValues(123456789, dec.ReadLong("payload"))
+	}
+	wg.Wait()
+}
+
+func (suite *ConnectionSuite) TestWritePacketOverflow() {
+	net1, _ := net.Pipe() // nobody reads, so the writer blocks on the first packet
+	conn := NewConn(zerolog.Nop(), net1, WithQueueSize(2))
+
+	var err error
+	for i := 0; i < 10 && err == nil; i++ {
+		err = conn.WritePacket(packet.ClientboundPong{})
+	}
+	suite.ErrorIs(err, ErrQueueOverflow)
+	suite.ErrorIs(conn.WritePacket(packet.ClientboundPong{}), ErrClosed)
+	suite.NoError(conn.Close())
+}
+
+func (suite *ConnectionSuite) TestFlush() {
+	net1, net2 := net.Pipe()
+	conn := NewConn(zerolog.Nop(), net1)
+	defer func() { _ = conn.Close() }()
+
+	suite.NoError(conn.WritePacket(packet.ClientboundPong{
+		Payload: 123456789,
+	}))
+	received := make(chan []byte)
+	go func() {
+		buf := make([]byte, 10)
+		_, _ = io.ReadFull(net2, buf)
+		received <- buf
+	}()
+	suite.NoError(conn.Flush())
+	suite.Len(<-received, 10)
+}
+
+func (suite *ConnectionSuite) TestCloseSendsQueuedPackets() {
+	net1, net2 := net.Pipe()
+	conn := NewConn(zerolog.Nop(), net1)
+
+	for i := 0; i < 5; i++ {
+		suite.NoError(conn.WritePacket(packet.ClientboundPong{
+			Payload: int64(i),
+		}))
+	}
+	closed := make(chan error)
+	go func() {
+		closed <- conn.Close()
+	}()
+
+	all, err := ioutil.ReadAll(net2)
+	suite.NoError(err)
+	suite.Len(all, 5*10)
+	suite.NoError(<-closed)
+}
+
 func Test_transitionValid(t *testing.T) {
 	type args struct {
 		from packet.Phase
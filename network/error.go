@@ -9,4 +9,14 @@ const (
 	// closed, and required resources might already have
 	// been released.
 	ErrClosed Error = "already closed"
+	// ErrQueueOverflow indicates, that a packet couldn't be written
+	// because the client didn't read the previously written packets
+	// fast enough. The connection has been closed.
+	ErrQueueOverflow Error = "write queue overflow"
+	// ErrInvalidProxyHeader indicates, that a connection didn't start with
+	// a valid PROXY protocol header.
+	ErrInvalidProxyHeader Error = "invalid proxy protocol header"
+	// ErrTimeout indicates, that the client didn't send a packet in time.
+	// The connection has been closed.
+	ErrTimeout Error = "read timed out"
 )
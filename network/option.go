@@ -0,0 +1,63 @@
+package network
+
+import (
+	"time"
+
+	"github.com/tsatke/mcserver/network/capture"
+	"github.com/tsatke/mcserver/network/packet"
+)
+
+// Option is used to configure a Conn on creation.
+type Option func(*Conn)
+
+// WithQueueSize sets the amount of packets that can be queued for writing, before
+// the OverflowPolicy is applied. The default is DefaultQueueSize.
+func WithQueueSize(size int) Option {
+	return func(c *Conn) {
+		c.queueSize = size
+	}
+}
+
+// WithOverflowPolicy sets the policy that is applied when the write queue of the
+// connection is full. The default is OverflowDisconnect.
+func WithOverflowPolicy(policy OverflowPolicy) Option {
+	return func(c *Conn) {
+		c.overflowPolicy = policy
+	}
+}
+
+// WithCapture makes the connection record all packets that are read and written
+// into the given capture. The capture is closed when the connection is closed.
+func WithCapture(w *capture.Writer) Option {
+	return func(c *Conn) {
+		c.capture = w
+	}
+}
+
+// WithPhaseTimeout sets the time that the client has to complete the given phase in,
+// starting when the connection transitions to it. If the client is too slow, the
+// connection is closed. A timeout of zero or less disables the timeout, which is
+// the default. The timeout of the play phase is ignored if WithIdleTimeout is given.
+func WithPhaseTimeout(phase packet.Phase, timeout time.Duration) Option {
+	return func(c *Conn) {
+		c.phaseTimeouts[phase] = timeout
+	}
+}
+
+// WithIdleTimeout sets the maximum time between two packets from the client in the
+// play phase. If the client doesn't send a packet in time, the connection is closed.
+// A timeout of zero or less disables the timeout, which is the default.
+func WithIdleTimeout(timeout time.Duration) Option {
+	return func(c *Conn) {
+		c.idleTimeout = timeout
+	}
+}
+
+// WithStartTime sets the time at which the handshaking phase started, which the
+// handshake timeout counts from. Use this if data was already read from the
+// connection before the Conn was created. The default is the time of creation.
+func WithStartTime(start time.Time) Option {
+	return func(c *Conn) {
+		c.start = start
+	}
+}
@@ -0,0 +1,15 @@
+package network
+
+//go:generate stringer -trimprefix=Overflow -type=OverflowPolicy
+
+// OverflowPolicy determines what happens when a packet is written to a connection
+// whose write queue is full, which happens if the client doesn't read fast enough.
+type OverflowPolicy uint8
+
+const (
+	// OverflowDisconnect closes the connection without sending the queued packets,
+	// and the write fails with ErrQueueOverflow.
+	OverflowDisconnect OverflowPolicy = iota
+	// OverflowBlock blocks the write until there is space in the queue.
+	OverflowBlock
+)
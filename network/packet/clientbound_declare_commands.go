@@ -0,0 +1,113 @@
+package packet
+
+import (
+	"io"
+	"reflect"
+
+	"github.com/tsatke/mcserver/game/id"
+)
+
+func init() {
+	RegisterPacket(PhasePlay, reflect.TypeOf(ClientboundDeclareCommands{}))
+}
+
+// CommandNodeType is the type of a CommandNode.
+type CommandNodeType byte
+
+// Available command node types.
+const (
+	CommandNodeRoot CommandNodeType = iota
+	CommandNodeLiteral
+	CommandNodeArgument
+)
+
+// Flags of a command node, in addition to the node type in the lowest two bits.
+const (
+	commandNodeExecutable      = 0x04
+	commandNodeHasRedirect     = 0x08
+	commandNodeHasSuggestsType = 0x10
+)
+
+// SuggestionsAskServer is the suggestions type of argument nodes, for which the
+// client sends a ServerboundTabComplete to get suggestions.
+var SuggestionsAskServer = id.ParseID("minecraft:ask_server")
+
+// CommandNode is a single node of the command tree in ClientboundDeclareCommands.
+type CommandNode struct {
+	Type CommandNodeType
+	// Executable indicates that the command can be executed if the input ends
+	// after this node.
+	Executable bool
+	// Children are the indices of the child nodes.
+	Children []int
+	// HasRedirect indicates that parsing continues at the node with the index
+	// Redirect after this node.
+	HasRedirect bool
+	Redirect    int
+	// Name is the name of literal and argument nodes, which is the literal text
+	// of literals.
+	Name string
+	// Parser is the identifier of the parser of argument nodes, such as
+	// brigadier:integer.
+	Parser id.ID
+	// Properties are the encoded properties of the parser, whose format depends
+	// on the parser.
+	Properties []byte
+	// SuggestionsType is the suggestions type of argument nodes, such as
+	// SuggestionsAskServer, or the empty ID if the client suggests by itself.
+	SuggestionsType id.ID
+}
+
+// ClientboundDeclareCommands sends the command tree to the client, which uses it to
+// highlight and complete the commands that the player types.
+type ClientboundDeclareCommands struct {
+	Nodes []CommandNode
+	// RootIndex is the index of the root node in Nodes.
+	RootIndex int
+}
+
+func (ClientboundDeclareCommands) ID() ID       { return IDClientboundDeclareCommands }
+func (ClientboundDeclareCommands) Name() string { return "Declare Commands" }
+
+func (c ClientboundDeclareCommands) EncodeInto(w io.Writer) (err error) {
+	defer recoverAndSetErr(&err)
+
+	enc := Encoder{w}
+
+	enc.WriteVarInt("count", len(c.Nodes))
+	for _, node := range c.Nodes {
+		flags := byte(node.Type)
+		if node.Executable {
+			flags |= commandNodeExecutable
+		}
+		if node.HasRedirect {
+			flags |= commandNodeHasRedirect
+		}
+		hasSuggestionsType := node.Type == CommandNodeArgument && node.SuggestionsType != (id.ID{})
+		if hasSuggestionsType {
+			flags |= commandNodeHasSuggestsType
+		}
+		enc.WriteUbyte("flags", flags)
+
+		enc.WriteVarInt("children count", len(node.Children))
+		for _, child := range node.Children {
+			enc.WriteVarInt("children", child)
+		}
+		if node.HasRedirect {
+			enc.WriteVarInt("redirect node", node.Redirect)
+		}
+		if node.Type != CommandNodeRoot {
+			enc.WriteString("name", node.Name)
+		}
+		if node.Type == CommandNodeArgument {
+			enc.WriteID("parser", node.Parser)
+			enc.WriteByteArray("properties", node.Properties)
+		}
+		if hasSuggestionsType {
+			enc.WriteID("suggestions type", node.SuggestionsType)
+		}
+	}
+	enc.WriteVarInt("root index", c.RootIndex)
+
+	return
+}
@@ -11,7 +11,7 @@ import (
 )
 
 func init() {
-	RegisterPacket(PhasePlay, reflect.TypeOf(ClientboundJoinGame{}))
+	RegisterClientbound(PhasePlay, reflect.TypeOf(ClientboundJoinGame{}))
 }
 
 type ClientboundJoinGame struct {
@@ -61,3 +61,30 @@ func (c ClientboundJoinGame) EncodeInto(w io.Writer) (err error) {
 
 	return
 }
+
+func (c *ClientboundJoinGame) DecodeFrom(rd io.Reader) (err error) {
+	defer recoverAndSetErr(&err)
+
+	dec := Decoder{rd}
+
+	c.EntityID = dec.ReadInt("entity ID")
+	c.Hardcore = dec.ReadBoolean("is hardcore")
+	c.Gamemode = int(dec.ReadUbyte("gamemode"))
+	c.PreviousGamemode = int(dec.ReadByte("previous gamemode"))
+	c.WorldNames = make([]id.ID, dec.ReadVarInt("world count"))
+	for i := range c.WorldNames {
+		c.WorldNames[i] = dec.ReadID("world names[" + strconv.Itoa(i) + "]")
+	}
+	c.DimensionCodec = dec.ReadNBT("dimension codec")
+	c.Dimension = dec.ReadNBT("dimension")
+	c.WorldName = dec.ReadID("world name")
+	c.HashedSeed = dec.ReadLong("hashed seed")
+	c.MaxPlayers = dec.ReadVarInt("max players")
+	c.ViewDistance = dec.ReadVarInt("view distance")
+	c.ReducedDebugInfo = dec.ReadBoolean("reduced debug info")
+	c.EnableRespawnScreen = dec.ReadBoolean("enable respawn screen")
+	c.Debug = dec.ReadBoolean("is debug")
+	c.Flat = dec.ReadBoolean("is flat")
+
+	return
+}
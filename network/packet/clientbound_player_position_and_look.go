@@ -6,7 +6,7 @@ import (
 )
 
 func init() {
-	RegisterPacket(PhasePlay, reflect.TypeOf(ClientboundPlayerPositionAndLook{}))
+	RegisterClientbound(PhasePlay, reflect.TypeOf(ClientboundPlayerPositionAndLook{}))
 }
 
 type ClientboundPlayerPositionAndLook struct {
@@ -34,3 +34,19 @@ func (c ClientboundPlayerPositionAndLook) EncodeInto(w io.Writer) (err error) {
 
 	return
 }
+
+func (c *ClientboundPlayerPositionAndLook) DecodeFrom(rd io.Reader) (err error) {
+	defer recoverAndSetErr(&err)
+
+	dec := Decoder{rd}
+
+	c.X = dec.ReadDouble("x")
+	c.Y = dec.ReadDouble("y")
+	c.Z = dec.ReadDouble("z")
+	c.Yaw = dec.ReadFloat("yaw")
+	c.Pitch = dec.ReadFloat("pitch")
+	c.Flags = dec.ReadByte("flags")
+	c.TeleportID = dec.ReadVarInt("teleport id")
+
+	return
+}
@@ -2,6 +2,7 @@ package packet
 
 import (
 	"encoding/json"
+	"fmt"
 	"io"
 	"reflect"
 
@@ -9,7 +10,7 @@ import (
 )
 
 func init() {
-	RegisterPacket(PhaseStatus, reflect.TypeOf(ClientboundResponse{}))
+	RegisterClientbound(PhaseStatus, reflect.TypeOf(ClientboundResponse{}))
 }
 
 type (
@@ -106,3 +107,16 @@ func (c ClientboundResponse) EncodeInto(w io.Writer) (err error) {
 
 	return
 }
+
+// DecodeFrom will fill this struct with values read from the given reader.
+func (c *ClientboundResponse) DecodeFrom(rd io.Reader) (err error) {
+	defer recoverAndSetErr(&err)
+
+	dec := Decoder{rd}
+
+	if err := json.Unmarshal([]byte(dec.ReadString("json response")), &c.JSONResponse); err != nil {
+		return fmt.Errorf("json response: %w", err)
+	}
+
+	return
+}
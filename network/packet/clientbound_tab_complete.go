@@ -0,0 +1,77 @@
+package packet
+
+import (
+	"io"
+	"reflect"
+	"strconv"
+
+	"github.com/tsatke/mcserver/game/chat"
+)
+
+func init() {
+	RegisterClientbound(PhasePlay, reflect.TypeOf(ClientboundTabComplete{}))
+}
+
+// TabCompleteMatch is a single suggestion of a ClientboundTabComplete.
+type TabCompleteMatch struct {
+	// Match is the text that replaces the range of the text that the client sent.
+	Match string
+	// Tooltip is shown next to the suggestion, if it isn't nil.
+	Tooltip *chat.Chat
+}
+
+// ClientboundTabComplete is the response to a ServerboundTabComplete, which suggests
+// replacements for a range of the text that the client sent.
+type ClientboundTabComplete struct {
+	// TransactionID is the ID of the request.
+	TransactionID int
+	// Start is the index of the first character of the text that is replaced,
+	// including the leading slash.
+	Start int
+	// Length is the length of the text that is replaced.
+	Length  int
+	Matches []TabCompleteMatch
+}
+
+func (ClientboundTabComplete) ID() ID       { return IDClientboundTabComplete }
+func (ClientboundTabComplete) Name() string { return "Tab-Complete (clientbound)" }
+
+func (c ClientboundTabComplete) EncodeInto(w io.Writer) (err error) {
+	defer recoverAndSetErr(&err)
+
+	enc := Encoder{w}
+
+	enc.WriteVarInt("transaction id", c.TransactionID)
+	enc.WriteVarInt("start", c.Start)
+	enc.WriteVarInt("length", c.Length)
+	enc.WriteVarInt("count", len(c.Matches))
+	for _, match := range c.Matches {
+		enc.WriteString("match", match.Match)
+		enc.WriteBoolean("has tooltip", match.Tooltip != nil)
+		if match.Tooltip != nil {
+			enc.WriteChat("tooltip", *match.Tooltip)
+		}
+	}
+
+	return
+}
+
+func (c *ClientboundTabComplete) DecodeFrom(rd io.Reader) (err error) {
+	defer recoverAndSetErr(&err)
+
+	dec := Decoder{rd}
+
+	c.TransactionID = dec.ReadVarInt("transaction id")
+	c.Start = dec.ReadVarInt("start")
+	c.Length = dec.ReadVarInt("length")
+	c.Matches = make([]TabCompleteMatch, dec.ReadVarInt("count"))
+	for i := range c.Matches {
+		c.Matches[i].Match = dec.ReadString("match[" + strconv.Itoa(i) + "]")
+		if dec.ReadBoolean("has tooltip") {
+			tooltip := dec.ReadChat("tooltip[" + strconv.Itoa(i) + "]")
+			c.Matches[i].Tooltip = &tooltip
+		}
+	}
+
+	return
+}
@@ -2,13 +2,18 @@ package packet
 
 import (
 	"bytes"
+	"encoding/json"
 	"fmt"
 	"io"
+	"io/ioutil"
 	"math"
 
 	"github.com/google/uuid"
+	"github.com/tsatke/nbt"
 
+	"github.com/tsatke/mcserver/game/chat"
 	"github.com/tsatke/mcserver/game/id"
+	"github.com/tsatke/mcserver/game/voxel"
 )
 
 // Decoder is a struct that can decode protocol values from a reader.
@@ -46,6 +51,32 @@ func (d Decoder) ReadVarInt(fieldName string) int {
 	return int(res)
 }
 
+// ReadVarLong decodes a VarLong from the reader. This panics if the VarLong
+// consists of more than 10 bytes.
+func (d Decoder) ReadVarLong(fieldName string) int64 {
+	var res int64
+	var readCnt int
+
+	var buf [1]byte
+	for {
+		_, err := io.ReadFull(d.Rd, buf[:])
+		panicIffErr(fieldName, err)
+
+		val := buf[0] & (1<<7 - 1)
+		res |= int64(val) << (7 * readCnt)
+		readCnt++
+		if readCnt > 10 {
+			panicIffErr(fieldName, fmt.Errorf("VarLong too big"))
+		}
+
+		if buf[0]&(1<<7) == 0 {
+			break
+		}
+	}
+
+	return res
+}
+
 // ReadString reads a VarInt from the reader. After the VarInt, n bytes will
 // be read, where n is the value of the read VarInt. There will be no pre-allocation.
 // Bytes are allocated as they are read.
@@ -73,6 +104,22 @@ func (d Decoder) ReadString(fieldName string) string {
 	return buf.String()
 }
 
+// ReadPrefixedByteArray reads a VarInt from the reader. After the VarInt, n bytes
+// will be read, where n is the value of the read VarInt. As with ReadString, there
+// will be no pre-allocation.
+func (d Decoder) ReadPrefixedByteArray(fieldName string) []byte {
+	arrLen := d.ReadVarInt(fieldName + " length")
+
+	var buf bytes.Buffer
+	n, err := buf.ReadFrom(io.LimitReader(d.Rd, int64(arrLen)))
+	panicIffErr(fieldName, err)
+	if n != int64(arrLen) {
+		panicIffErr(fieldName, fmt.Errorf("prefix indicated length of %d, but only got %d bytes", arrLen, n))
+	}
+
+	return buf.Bytes()
+}
+
 // ReadUbyte reads one byte from the reader.
 func (d Decoder) ReadUbyte(fieldName string) byte {
 	var buf [ByteSize]byte
@@ -91,6 +138,20 @@ func (d Decoder) ReadUshort(fieldName string) uint16 {
 	return ByteOrder.Uint16(buf[:])
 }
 
+// ReadShort reads two bytes in ByteOrder from the reader and returns them
+// as signed value.
+func (d Decoder) ReadShort(fieldName string) int16 {
+	return int16(d.ReadUshort(fieldName))
+}
+
+// ReadInt reads 4 bytes in the ByteOrder from the reader.
+func (d Decoder) ReadInt(fieldName string) int32 {
+	var buf [IntSize]byte
+	_, err := io.ReadFull(d.Rd, buf[:])
+	panicIffErr(fieldName, err)
+	return int32(ByteOrder.Uint32(buf[:]))
+}
+
 // ReadByte reads a single byte from the reader and returns it with the MSB
 // as sign.
 func (d Decoder) ReadByte(fieldName string) int8 {
@@ -146,3 +207,36 @@ func (d Decoder) ReadFloat(fieldName string) float32 {
 func (d Decoder) ReadID(fieldName string) id.ID {
 	return id.ParseID(d.ReadString(fieldName))
 }
+
+// ReadChat reads a string from the reader and unmarshals it as json chat object.
+func (d Decoder) ReadChat(fieldName string) chat.Chat {
+	var val chat.Chat
+	panicIffErr(fieldName, json.Unmarshal([]byte(d.ReadString(fieldName)), &val))
+	return val
+}
+
+// ReadPosition reads a position that is encoded as single long from the reader.
+// See Encoder.WritePosition.
+func (d Decoder) ReadPosition(fieldName string) voxel.V3 {
+	val := d.ReadLong(fieldName)
+	return voxel.V3{
+		X: int(val >> 38),
+		Y: int(val << 52 >> 52),
+		Z: int(val << 26 >> 38),
+	}
+}
+
+// ReadNBT reads an nbt tag from the reader.
+func (d Decoder) ReadNBT(fieldName string) nbt.Tag {
+	tag, err := nbt.NewDecoder(d.Rd, ByteOrder).ReadTag()
+	panicIffErr(fieldName, err)
+	return tag
+}
+
+// ReadRemainingBytes reads all bytes until the reader is exhausted. Use this only
+// with readers that are limited to the packet length.
+func (d Decoder) ReadRemainingBytes(fieldName string) []byte {
+	data, err := ioutil.ReadAll(d.Rd)
+	panicIffErr(fieldName, err)
+	return data
+}
@@ -9,6 +9,8 @@ import (
 
 	"github.com/google/uuid"
 	"github.com/stretchr/testify/suite"
+
+	"github.com/tsatke/mcserver/game/voxel"
 )
 
 func TestDecoderSuite(t *testing.T) {
@@ -555,3 +557,153 @@ func (suite *DecoderSuite) TestDecoder_ReadUUID() {
 		})
 	}
 }
+
+func (suite *DecoderSuite) TestDecoder_ReadPrefixedByteArray() {
+	tests := []struct {
+		name    string
+		source  io.Reader
+		want    []byte
+		wantErr bool
+	}{
+		{
+			"empty input",
+			bytes.NewReader([]byte{}),
+			nil,
+			true,
+		},
+		{
+			"empty",
+			bytes.NewReader([]byte{0x00}),
+			[]byte{},
+			false,
+		},
+		{
+			"small",
+			bytes.NewReader([]byte{0x02, 0xca, 0xfe}),
+			[]byte{0xca, 0xfe},
+			false,
+		},
+		{
+			"with remaining",
+			bytes.NewReader([]byte{0x02, 0xca, 0xfe, 0xba, 0xbe}),
+			[]byte{0xca, 0xfe},
+			false,
+		},
+		{
+			"fewer payload than length",
+			bytes.NewReader([]byte{0x4f, 0x39, 0x40}),
+			nil,
+			true,
+		},
+	}
+	for _, tt := range tests {
+		suite.Run(tt.name, func() {
+			testFn := func() {
+				dec := Decoder{tt.source}
+				got := dec.ReadPrefixedByteArray("field")
+				suite.EqualValues(tt.want, got)
+			}
+			if tt.wantErr {
+				suite.Panics(testFn) // the decoder fails by panicking an error
+			} else {
+				suite.NotPanics(testFn)
+			}
+		})
+	}
+}
+
+func (suite *DecoderSuite) TestDecoder_ReadPosition() {
+	tests := []struct {
+		name    string
+		source  io.Reader
+		want    voxel.V3
+		wantErr bool
+	}{
+		{
+			"zero",
+			bytes.NewReader([]byte{0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00}),
+			voxel.V3{},
+			false,
+		},
+		{
+			"positive and negative",
+			bytes.NewReader([]byte{0x46, 0x07, 0x63, 0x2c, 0x15, 0xb4, 0x83, 0x3f}),
+			voxel.V3{X: 18357644, Y: 831, Z: -20882616},
+			false,
+		},
+		{
+			"all negative",
+			bytes.NewReader([]byte{0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff}),
+			voxel.V3{X: -1, Y: -1, Z: -1},
+			false,
+		},
+		{
+			"too few bytes",
+			bytes.NewReader([]byte{0x07}),
+			voxel.V3{},
+			true,
+		},
+	}
+	for _, tt := range tests {
+		suite.Run(tt.name, func() {
+			testFn := func() {
+				dec := Decoder{tt.source}
+				got := dec.ReadPosition("field")
+				suite.EqualValues(tt.want, got)
+			}
+			if tt.wantErr {
+				suite.Panics(testFn) // the decoder fails by panicking an error
+			} else {
+				suite.NotPanics(testFn)
+			}
+		})
+	}
+}
+
+func (suite *DecoderSuite) TestDecoder_ReadVarLong() {
+	tests := []struct {
+		name    string
+		source  io.Reader
+		want    int64
+		wantErr bool
+	}{
+		{
+			"zero",
+			bytes.NewReader([]byte{0x00}),
+			0,
+			false,
+		},
+		{
+			"max int",
+			bytes.NewReader([]byte{0xff, 0xff, 0xff, 0xff, 0x07}),
+			2147483647,
+			false,
+		},
+		{
+			"minus one",
+			bytes.NewReader([]byte{0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0x01}),
+			-1,
+			false,
+		},
+		{
+			"too big",
+			bytes.NewReader([]byte{0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0x01}),
+			0,
+			true,
+		},
+	}
+	for _, tt := range tests {
+		suite.Run(tt.name, func() {
+			testFn := func() {
+				dec := Decoder{tt.source}
+				got := dec.ReadVarLong("field")
+				suite.EqualValues(tt.want, got)
+			}
+			if tt.wantErr {
+				suite.Panics(testFn) // the decoder fails by panicking an error
+			} else {
+				suite.NotPanics(testFn)
+			}
+		})
+	}
+}
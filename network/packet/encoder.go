@@ -11,6 +11,7 @@ import (
 
 	"github.com/tsatke/mcserver/game/chat"
 	"github.com/tsatke/mcserver/game/id"
+	"github.com/tsatke/mcserver/game/voxel"
 )
 
 // Encoder is a decorating struct, which uses specialized algorithms to
@@ -39,6 +40,29 @@ func (e Encoder) WriteVarInt(fieldName string, val int) {
 	_write(e.W, fieldName, buf)
 }
 
+// WriteVarLong writes the given int64 into the writer as a VarLong.
+func (e Encoder) WriteVarLong(fieldName string, val int64) {
+	value := uint64(val)
+	buf := make([]byte, 0)
+	for {
+		tmp := byte(value & 0b01111111)
+		value >>= 7
+		if value != 0 {
+			tmp |= 0b10000000
+		}
+		buf = append(buf, tmp)
+		if value == 0 {
+			break
+		}
+	}
+	_write(e.W, fieldName, buf)
+}
+
+// WriteShort writes the given int16 with ByteOrder into the writer.
+func (e Encoder) WriteShort(fieldName string, val int16) {
+	e.WriteUshort(fieldName, uint16(val))
+}
+
 // WriteInt writes the given int32 with ByteOrder into the writer.
 func (e Encoder) WriteInt(fieldName string, val int32) {
 	var buf [IntSize]byte
@@ -71,6 +95,14 @@ func (e Encoder) WriteByteArray(fieldName string, val []byte) {
 	_write(e.W, fieldName, val)
 }
 
+// WritePrefixedByteArray writes a VarInt into the writer, indicating the length
+// of the given byte array. After that, the byte array is written.
+// See Encoder.WriteVarInt.
+func (e Encoder) WritePrefixedByteArray(fieldName string, val []byte) {
+	e.WriteVarInt(fieldName+" length", len(val))
+	_write(e.W, fieldName, val)
+}
+
 // WriteUbyte writes the given byte into the writer.
 func (e Encoder) WriteUbyte(fieldName string, val uint8) {
 	_write(e.W, fieldName, []byte{val})
@@ -127,6 +159,12 @@ func (e Encoder) WriteID(fieldName string, val id.ID) {
 	e.WriteString(fieldName, val.String())
 }
 
+// WritePosition writes the given position into the writer as a single long,
+// with 26 bits for x, 26 bits for z and 12 bits for y.
+func (e Encoder) WritePosition(fieldName string, val voxel.V3) {
+	e.WriteLong(fieldName, int64(val.X&0x3FFFFFF)<<38|int64(val.Z&0x3FFFFFF)<<12|int64(val.Y&0xFFF))
+}
+
 // WriteNBT writes an nbt tag into the writer.
 func (e Encoder) WriteNBT(fieldName string, val nbt.Tag) {
 	enc := nbt.NewEncoder(e.W, ByteOrder)
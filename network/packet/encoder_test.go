@@ -10,6 +10,7 @@ import (
 	"github.com/stretchr/testify/suite"
 
 	"github.com/tsatke/mcserver/game/chat"
+	"github.com/tsatke/mcserver/game/voxel"
 )
 
 func TestEncoderSuite(t *testing.T) {
@@ -99,6 +100,45 @@ func (suite *EncoderSuite) TestEncoder_WriteByteArray() {
 	}
 }
 
+func (suite *EncoderSuite) TestEncoder_WritePrefixedByteArray() {
+	tests := []struct {
+		name string
+		val  []byte
+		want []byte
+	}{
+		{
+			"empty",
+			[]byte{},
+			[]byte{0x00},
+		},
+		{
+			"single",
+			[]byte{0x53},
+			[]byte{0x01, 0x53},
+		},
+		{
+			"long",
+			bytes.Repeat([]byte{0x00, 0x01, 0x02, 0x03, 0x04, 0x05, 0x06, 0x07, 0x08, 0x09}, 100),
+			append(
+				[]byte{0xe8, 0x07}, // length prefix as varint
+				bytes.Repeat([]byte{0x00, 0x01, 0x02, 0x03, 0x04, 0x05, 0x06, 0x07, 0x08, 0x09}, 100)...,
+			),
+		},
+	}
+	for _, tt := range tests {
+		suite.Run(tt.name, func() {
+			testFn := func() {
+				var buf bytes.Buffer
+				enc := Encoder{&buf}
+
+				enc.WritePrefixedByteArray("field", tt.val)
+				suite.EqualValues(tt.want, buf.Bytes())
+			}
+			suite.NotPanics(testFn)
+		})
+	}
+}
+
 func (suite *EncoderSuite) TestEncoder_WriteChat() {
 	tests := []struct {
 		name string
@@ -542,3 +582,70 @@ func (suite *EncoderSuite) TestEncoder_WriteVarInt() {
 		})
 	}
 }
+
+func (suite *EncoderSuite) TestEncoder_WritePosition() {
+	tests := []struct {
+		name string
+		val  voxel.V3
+		want []byte
+	}{
+		{
+			"zero",
+			voxel.V3{},
+			[]byte{0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00},
+		},
+		{
+			"positive and negative",
+			voxel.V3{X: 18357644, Y: 831, Z: -20882616},
+			[]byte{0x46, 0x07, 0x63, 0x2c, 0x15, 0xb4, 0x83, 0x3f},
+		},
+	}
+	for _, tt := range tests {
+		suite.Run(tt.name, func() {
+			testFn := func() {
+				var buf bytes.Buffer
+				enc := Encoder{&buf}
+
+				enc.WritePosition("field", tt.val)
+				suite.EqualValues(tt.want, buf.Bytes())
+			}
+			suite.NotPanics(testFn)
+		})
+	}
+}
+
+func (suite *EncoderSuite) TestEncoder_WriteVarLong() {
+	tests := []struct {
+		name string
+		val  int64
+		want []byte
+	}{
+		{
+			"zero",
+			0,
+			[]byte{0x00},
+		},
+		{
+			"max int",
+			2147483647,
+			[]byte{0xff, 0xff, 0xff, 0xff, 0x07},
+		},
+		{
+			"minus one",
+			-1,
+			[]byte{0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0x01},
+		},
+	}
+	for _, tt := range tests {
+		suite.Run(tt.name, func() {
+			testFn := func() {
+				var buf bytes.Buffer
+				enc := Encoder{&buf}
+
+				enc.WriteVarLong("field", tt.val)
+				suite.EqualValues(tt.want, buf.Bytes())
+			}
+			suite.NotPanics(testFn)
+		})
+	}
+}
@@ -0,0 +1,28 @@
+package packet
+
+// Error is a sentinel error type for errors that are returned when
+// decoding or encoding packets.
+type Error string
+
+func (e Error) Error() string { return string(e) }
+
+const (
+	// ErrUnknownPacketID indicates, that a frame was received whose packet ID is
+	// not registered in the current phase. The whole frame has been consumed, so
+	// the next frame can be read.
+	ErrUnknownPacketID Error = "unknown packet ID"
+	// ErrFrameTooLarge indicates, that a frame is longer than allowed in the
+	// current phase. The frame may not have been consumed, so the stream has
+	// to be considered out of sync.
+	ErrFrameTooLarge Error = "frame too large"
+	// ErrUnregisteredPacket indicates, that a packet was encoded that is not
+	// registered in the protocol of the connection.
+	ErrUnregisteredPacket Error = "packet not registered in protocol"
+	// ErrInvalidFrame indicates, that a frame has an invalid length. The stream
+	// has to be considered out of sync.
+	ErrInvalidFrame Error = "invalid frame"
+	// ErrUndecodablePacket indicates, that a clientbound packet was received that
+	// can't be decoded, since only the server side of it is implemented. The whole
+	// frame has been consumed, so the next frame can be read.
+	ErrUndecodablePacket Error = "packet can not be decoded"
+)
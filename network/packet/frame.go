@@ -0,0 +1,144 @@
+package packet
+
+import (
+	"bytes"
+	"compress/zlib"
+	"fmt"
+	"io"
+	"io/ioutil"
+)
+
+// MaxFrameLength is the maximum length of a frame in bytes, excluding the
+// frame length itself. This is the largest number that can be encoded as
+// VarInt with 3 bytes, which is what the vanilla client and server allow.
+const MaxFrameLength = 1<<21 - 1
+
+// frameLimits are the maximum frame lengths for the phases in which only
+// small packets are expected. All other phases use MaxFrameLength.
+var frameLimits = map[Phase]int{
+	// the handshake is small, but proxies may forward player information
+	// in the server address field
+	PhaseHandshaking: 1 << 15,
+	PhaseStatus:      1 << 8,
+	// login plugin responses may carry forwarded player information
+	PhaseLogin: 1 << 15,
+}
+
+// FrameLimit returns the maximum length of a frame that is accepted from a
+// client in the given phase.
+func FrameLimit(phase Phase) int {
+	if limit, ok := frameLimits[phase]; ok {
+		return limit
+	}
+	return MaxFrameLength
+}
+
+// checkFrameLength returns an error if the given frame length is not allowed
+// in the given phase.
+func checkFrameLength(fieldName string, length int, phase Phase) error {
+	if length < 1 {
+		return fmt.Errorf("%s: %w: length must be positive, but was %d", fieldName, ErrInvalidFrame, length)
+	}
+	if limit := FrameLimit(phase); length > limit {
+		return fmt.Errorf("%s: %w: %d > %d in phase %s", fieldName, ErrFrameTooLarge, length, limit, phase)
+	}
+	return nil
+}
+
+// WriteFrame writes the given frame data, which is the packet ID and payload, as
+// frame onto the given writer. If the threshold is negative, the uncompressed packet
+// format is used. Otherwise, the compressed packet format is used, where data that
+// is at least threshold bytes long is compressed with zlib, and shorter data is
+// written uncompressed with a data length of 0.
+func WriteFrame(w io.Writer, data []byte, threshold int) (err error) {
+	defer recoverAndSetErr(&err)
+
+	var frame bytes.Buffer
+	switch {
+	case threshold < 0:
+		frame.Write(data)
+	case len(data) < threshold:
+		Encoder{&frame}.WriteVarInt("data length", 0)
+		frame.Write(data)
+	default:
+		Encoder{&frame}.WriteVarInt("data length", len(data))
+		zw := zlib.NewWriter(&frame)
+		if _, err := zw.Write(data); err != nil {
+			return fmt.Errorf("compress: %w", err)
+		}
+		if err := zw.Close(); err != nil {
+			return fmt.Errorf("compress: %w", err)
+		}
+	}
+
+	if frame.Len() > MaxFrameLength {
+		return fmt.Errorf("%w: %d > %d", ErrFrameTooLarge, frame.Len(), MaxFrameLength)
+	}
+	Encoder{w}.WriteVarInt("packet length", frame.Len())
+	if _, err := frame.WriteTo(w); err != nil {
+		return fmt.Errorf("write to: %w", err)
+	}
+	return
+}
+
+// ReadFrame reads a single frame from the given reader and returns its data, which
+// is the packet ID and payload. If compressed is true, the frame is expected to be
+// in the compressed packet format, and the data is decompressed if necessary.
+// If the frame or the uncompressed data is longer than allowed in the given phase
+// (see FrameLimit), an error wrapping ErrFrameTooLarge is returned. Otherwise, the
+// whole frame was consumed from the reader after this returns.
+func ReadFrame(rd io.Reader, phase Phase, compressed bool) (data []byte, err error) {
+	return readFrame(rd, compressed, func(fieldName string, length int) error {
+		return checkFrameLength(fieldName, length, phase)
+	})
+}
+
+// ReadClientboundFrame reads a single frame that was sent by a server, like ReadFrame.
+// Servers are not limited like clients, so frames and uncompressed data may be up to
+// MaxFrameLength bytes long in every phase.
+func ReadClientboundFrame(rd io.Reader, compressed bool) (data []byte, err error) {
+	return readFrame(rd, compressed, func(fieldName string, length int) error {
+		return checkFrameLength(fieldName, length, PhasePlay)
+	})
+}
+
+// readFrame reads a single frame from the given reader, where check returns an
+// error if the frame or data length is not allowed.
+func readFrame(rd io.Reader, compressed bool, check func(fieldName string, length int) error) (data []byte, err error) {
+	defer recoverAndSetErr(&err)
+
+	packetLen := Decoder{rd}.ReadVarInt("packet length")
+	if err := check("packet length", packetLen); err != nil {
+		return nil, err
+	}
+	frame := make([]byte, packetLen)
+	if _, err := io.ReadFull(rd, frame); err != nil {
+		return nil, fmt.Errorf("read frame: %w", err)
+	}
+	if !compressed {
+		return frame, nil
+	}
+
+	frameReader := bytes.NewReader(frame)
+	dataLen := Decoder{frameReader}.ReadVarInt("data length")
+	if dataLen == 0 {
+		return frame[len(frame)-frameReader.Len():], nil
+	}
+	if err := check("data length", dataLen); err != nil {
+		return nil, err
+	}
+	zr, err := zlib.NewReader(frameReader)
+	if err != nil {
+		return nil, fmt.Errorf("decompress: %w", err)
+	}
+	defer func() { _ = zr.Close() }()
+	// read one byte more than declared, to detect data that is too long
+	data, err = ioutil.ReadAll(io.LimitReader(zr, int64(dataLen)+1))
+	if err != nil {
+		return nil, fmt.Errorf("decompress: %w", err)
+	}
+	if len(data) != dataLen {
+		return nil, fmt.Errorf("data length: %w: declared %d, but decompressed %d", ErrInvalidFrame, dataLen, len(data))
+	}
+	return data, nil
+}
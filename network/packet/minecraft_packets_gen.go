@@ -0,0 +1,3 @@
+package packet
+
+//go:generate go run ../../tools/packetgen -pkg=packet -in=minecraft_packets.yaml -out=minecraft_packets.go -test=minecraft_packets_test.go
@@ -1,20 +1,21 @@
 package packet
 
 import (
-	"bytes"
 	"fmt"
 	"io"
-	"io/ioutil"
 	"reflect"
 )
 
+// CompressionDisabled is the compression threshold that indicates, that
+// the uncompressed packet format is used.
+const CompressionDisabled = -1
+
 var (
-	packetInterfaceType    = reflect.TypeOf((*Packet)(nil)).Elem()
-	serverboundPacketTypes = make(map[Phase]map[ID]reflect.Type)
-	clientboundPacketTypes = make(map[Phase]map[ID]reflect.Type)
+	packetInterfaceType = reflect.TypeOf((*Packet)(nil)).Elem()
 )
 
-// RegisterPacket will associate a given type with a given Phase..
+// RegisterPacket will associate a given type with a given Phase in the
+// Latest protocol, using the ID that the packet returns.
 // The given type must implement Packet and either Serverbound
 // or Clientbound. If a packet implements both (which wouldn't make much
 // sense, but it's possible), Serverbound takes precedence.
@@ -23,32 +24,29 @@ var (
 // create and decode an incoming packet depending on a Phase and the
 // decoded packet ID.
 func RegisterPacket(phase Phase, typ reflect.Type) {
+	Latest.RegisterPacket(phase, typ)
+}
+
+// RegisterServerbound registers the given Serverbound type with the given Phase in
+// the Latest protocol, using the ID that the packet returns. Use this instead of
+// RegisterPacket for packets that implement both Serverbound and Clientbound.
+func RegisterServerbound(phase Phase, typ reflect.Type) {
+	Latest.RegisterServerbound(phase, packetID(typ), typ)
+}
+
+// RegisterClientbound registers the given Clientbound type with the given Phase in
+// the Latest protocol, using the ID that the packet returns. Use this instead of
+// RegisterPacket for packets that implement both Serverbound and Clientbound.
+func RegisterClientbound(phase Phase, typ reflect.Type) {
+	Latest.RegisterClientbound(phase, packetID(typ), typ)
+}
+
+// packetID returns the ID of the packet with the given type.
+func packetID(typ reflect.Type) ID {
 	if !typ.Implements(packetInterfaceType) {
 		panic(fmt.Sprintf("%s does not implement the Packet interface", typ.Name()))
 	}
-	created := reflect.New(typ).Interface()
-	id := created.(Packet).ID()
-	var target map[Phase]map[ID]reflect.Type
-	if _, ok := created.(Serverbound); ok {
-		// initialize phase map if necessary
-		if serverboundPacketTypes[phase] == nil {
-			serverboundPacketTypes[phase] = make(map[ID]reflect.Type)
-		}
-		target = serverboundPacketTypes
-	} else if _, ok := created.(Clientbound); ok {
-		// initialize phase map if necessary
-		if clientboundPacketTypes[phase] == nil {
-			clientboundPacketTypes[phase] = make(map[ID]reflect.Type)
-		}
-		target = clientboundPacketTypes
-	} else {
-		panic(fmt.Sprintf("%s is a packet, but does neither implement Serverbound nor Clientbound", typ.Name()))
-	}
-
-	if target[phase][id] != nil {
-		panic(fmt.Sprintf("already registered packet %T in phase %s", created, phase))
-	}
-	target[phase][id] = typ
+	return reflect.New(typ).Interface().(Packet).ID()
 }
 
 // Packet is a packet that can either be sent to a client or the server.
@@ -80,47 +78,40 @@ type Clientbound interface {
 	EncodeInto(io.Writer) error
 }
 
-// Encode will write the given packet onto the given writer. As opposed to the
-// Clientbound.EncodeInto method, this will also write the packet length and ID.
-func Encode(pkg Clientbound, w io.Writer) (err error) {
-	defer recoverAndSetErr(&err)
-
-	var buf bytes.Buffer
-	enc := Encoder{&buf}
-	enc.WriteVarInt("packet ID", int(pkg.ID()))
-	panicIffErr("packet", pkg.EncodeInto(&buf))
-	Encoder{w}.WriteVarInt("packet length", buf.Len())
-	if _, err := buf.WriteTo(w); err != nil {
-		return fmt.Errorf("write to: %w", err)
-	}
-	return
+// Encodable is a packet that can be encoded. All Clientbound packets are Encodable,
+// and so are most Serverbound packets, so that they can be sent by a client.
+type Encodable interface {
+	Packet
+	EncodeInto(io.Writer) error
+}
+
+// Decodable is a packet that can be decoded. All Serverbound packets are Decodable,
+// and so are most Clientbound packets, so that they can be received by a client.
+type Decodable interface {
+	Packet
+	DecodeFrom(io.Reader) error
+}
+
+// Encode will write the given packet onto the given writer, using the Latest protocol.
+// See Protocol.Encode.
+func Encode(pkg Clientbound, w io.Writer) error {
+	return Latest.Encode(pkg, w)
+}
+
+// EncodeCompressed will write the given packet onto the given writer in the compressed
+// packet format, using the Latest protocol. See Protocol.EncodeCompressed.
+func EncodeCompressed(pkg Clientbound, w io.Writer, threshold int) error {
+	return Latest.EncodeCompressed(pkg, w, threshold)
 }
 
 // Decode decodes a Serverbound packet from the given reader, depending on the
-// given phase.
-func Decode(rd io.Reader, phase Phase) (p Serverbound, err error) {
-	defer recoverAndSetErr(&err)
-
-	dec := Decoder{rd}
-
-	packetLen := dec.ReadVarInt("packet length")
-	packetID := ID(dec.ReadVarInt("packet ID"))
-	payloadLength := packetLen - 1 /* packetID.Len(), which seems to always be 1 */
-	payloadReader := io.LimitReader(rd, int64(payloadLength))
-	packetType := serverboundPacketTypes[phase][packetID]
-	if packetType == nil {
-		_, _ = io.CopyN(ioutil.Discard, rd, int64(payloadLength)) // discard remaining bytes of the packet
-		return nil, fmt.Errorf("unknown ID %s in Phase %s, discarding", packetID, phase)
-	}
-	packetInterface := reflect.New(packetType).Interface()
-	packet := packetInterface.(Serverbound)
-	if err := packet.DecodeFrom(payloadReader); err != nil {
-		return nil, fmt.Errorf("decode %s: %w", packet.Name(), err)
-	}
-	if validator, ok := packetInterface.(Validator); ok {
-		if err := validator.Validate(); err != nil {
-			return nil, fmt.Errorf("validate %s: %w", packet.Name(), err)
-		}
-	}
-	return packet, nil
+// given phase, using the Latest protocol. See Protocol.Decode.
+func Decode(rd io.Reader, phase Phase) (Serverbound, error) {
+	return Latest.Decode(rd, phase)
+}
+
+// DecodeCompressed decodes a Serverbound packet in the compressed packet format from
+// the given reader, using the Latest protocol. See Protocol.DecodeCompressed.
+func DecodeCompressed(rd io.Reader, phase Phase) (Serverbound, error) {
+	return Latest.DecodeCompressed(rd, phase)
 }
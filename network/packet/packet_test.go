@@ -1,9 +1,16 @@
 package packet
 
 import (
+	"bytes"
+	"compress/zlib"
+	"fmt"
+	"io/ioutil"
 	"testing"
 
 	"github.com/stretchr/testify/suite"
+
+	"github.com/tsatke/mcserver/game/chat"
+	"github.com/tsatke/mcserver/game/id"
 )
 
 func TestPacketSuite(t *testing.T) {
@@ -13,3 +20,283 @@ func TestPacketSuite(t *testing.T) {
 type PacketSuite struct {
 	suite.Suite
 }
+
+func (suite *PacketSuite) TestEncodeCompressed() {
+	suite.Run("below threshold", func() {
+		var buf bytes.Buffer
+		suite.NoError(EncodeCompressed(ClientboundPong{Payload: 17}, &buf, 256))
+
+		dec := Decoder{&buf}
+		suite.EqualValues(10, dec.ReadVarInt("packet length"))
+		suite.EqualValues(0, dec.ReadVarInt("data length"))
+		suite.EqualValues(IDClientboundPong, dec.ReadVarInt("packet id"))
+		suite.EqualValues(17, dec.ReadLong("payload"))
+		suite.Zero(buf.Len())
+	})
+	suite.Run("above threshold", func() {
+		var buf bytes.Buffer
+		suite.NoError(EncodeCompressed(ClientboundPong{Payload: 17}, &buf, 0))
+
+		dec := Decoder{&buf}
+		packetLen := dec.ReadVarInt("packet length")
+		suite.Equal(packetLen, buf.Len())
+		suite.EqualValues(9, dec.ReadVarInt("data length"))
+		zr, err := zlib.NewReader(&buf)
+		suite.Require().NoError(err)
+		data, err := ioutil.ReadAll(zr)
+		suite.NoError(err)
+
+		dataDec := Decoder{bytes.NewReader(data)}
+		suite.EqualValues(IDClientboundPong, dataDec.ReadVarInt("packet id"))
+		suite.EqualValues(17, dataDec.ReadLong("payload"))
+	})
+	suite.Run("disabled", func() {
+		var compressed, uncompressed bytes.Buffer
+		suite.NoError(EncodeCompressed(ClientboundPong{Payload: 17}, &compressed, CompressionDisabled))
+		suite.NoError(Encode(ClientboundPong{Payload: 17}, &uncompressed))
+		suite.Equal(uncompressed.Bytes(), compressed.Bytes())
+	})
+}
+
+func (suite *PacketSuite) TestDecode() {
+	frame := func(packetLen int, id ID, payload string) *bytes.Buffer {
+		var buf bytes.Buffer
+		enc := Encoder{&buf}
+		enc.WriteVarInt("packet length", packetLen)
+		enc.WriteVarInt("packet id", int(id))
+		buf.WriteString(payload)
+		buf.WriteString("trailing")
+		return &buf
+	}
+
+	suite.Run("known id", func() {
+		buf := frame(9, IDServerboundPing, "\x00\x00\x00\x00\x07\x5b\xcd\x15")
+		p, err := Decode(buf, PhaseStatus)
+		suite.NoError(err)
+		suite.Equal(&ServerboundPing{Payload: 123456789}, p)
+		suite.Equal("trailing", buf.String())
+	})
+	suite.Run("unknown multi byte id", func() {
+		buf := frame(2+5, 0x80, "abcde") // 0x80 needs two bytes as VarInt
+		p, err := Decode(buf, PhasePlay)
+		suite.ErrorIs(err, ErrUnknownPacketID)
+		suite.Nil(p)
+		suite.Equal("trailing", buf.String())
+	})
+	suite.Run("too large", func() {
+		buf := frame(FrameLimit(PhaseStatus)+1, IDServerboundPing, "")
+		p, err := Decode(buf, PhaseStatus)
+		suite.ErrorIs(err, ErrFrameTooLarge)
+		suite.Nil(p)
+	})
+	suite.Run("too large for protocol", func() {
+		buf := frame(MaxFrameLength+1, IDServerboundPing, "")
+		p, err := Decode(buf, PhasePlay)
+		suite.ErrorIs(err, ErrFrameTooLarge)
+		suite.Nil(p)
+	})
+	suite.Run("empty", func() {
+		var buf bytes.Buffer
+		Encoder{&buf}.WriteVarInt("packet length", 0)
+		p, err := Decode(&buf, PhasePlay)
+		suite.ErrorIs(err, ErrInvalidFrame)
+		suite.Nil(p)
+	})
+}
+
+func (suite *PacketSuite) TestDecodeCompressed() {
+	var data bytes.Buffer
+	enc := Encoder{&data}
+	enc.WriteVarInt("packet id", int(IDServerboundPing))
+	enc.WriteLong("payload", 123456789)
+
+	suite.Run("uncompressed", func() {
+		var frame bytes.Buffer
+		frameEnc := Encoder{&frame}
+		frameEnc.WriteVarInt("data length", 0)
+		frameEnc.WriteByteArray("data", data.Bytes())
+
+		var buf bytes.Buffer
+		Encoder{&buf}.WriteVarInt("packet length", frame.Len())
+		_, _ = frame.WriteTo(&buf)
+		buf.WriteString("trailing")
+
+		p, err := DecodeCompressed(&buf, PhaseStatus)
+		suite.NoError(err)
+		suite.Equal(&ServerboundPing{Payload: 123456789}, p)
+		suite.Equal("trailing", buf.String())
+	})
+	suite.Run("compressed", func() {
+		var frame bytes.Buffer
+		Encoder{&frame}.WriteVarInt("data length", data.Len())
+		zw := zlib.NewWriter(&frame)
+		_, _ = zw.Write(data.Bytes())
+		suite.NoError(zw.Close())
+
+		var buf bytes.Buffer
+		Encoder{&buf}.WriteVarInt("packet length", frame.Len())
+		_, _ = frame.WriteTo(&buf)
+		buf.WriteString("trailing")
+
+		p, err := DecodeCompressed(&buf, PhaseStatus)
+		suite.NoError(err)
+		suite.Equal(&ServerboundPing{Payload: 123456789}, p)
+		suite.Equal("trailing", buf.String())
+	})
+	suite.Run("unknown id", func() {
+		var frame bytes.Buffer
+		frameEnc := Encoder{&frame}
+		frameEnc.WriteVarInt("data length", 0)
+		frameEnc.WriteVarInt("packet id", 0x7f)
+		frameEnc.WriteLong("payload", 123456789)
+
+		var buf bytes.Buffer
+		Encoder{&buf}.WriteVarInt("packet length", frame.Len())
+		_, _ = frame.WriteTo(&buf)
+		buf.WriteString("trailing")
+
+		p, err := DecodeCompressed(&buf, PhaseStatus)
+		suite.ErrorIs(err, ErrUnknownPacketID)
+		suite.Nil(p)
+		suite.Equal("trailing", buf.String())
+	})
+	suite.Run("data too large", func() {
+		var frame bytes.Buffer
+		Encoder{&frame}.WriteVarInt("data length", FrameLimit(PhaseStatus)+1)
+		zw := zlib.NewWriter(&frame)
+		_, _ = zw.Write(data.Bytes())
+		suite.NoError(zw.Close())
+
+		var buf bytes.Buffer
+		Encoder{&buf}.WriteVarInt("packet length", frame.Len())
+		_, _ = frame.WriteTo(&buf)
+
+		p, err := DecodeCompressed(&buf, PhaseStatus)
+		suite.ErrorIs(err, ErrFrameTooLarge)
+		suite.Nil(p)
+	})
+	for _, dataLen := range []int{data.Len() + 1, data.Len() - 1} {
+		suite.Run(fmt.Sprintf("data length %d", dataLen), func() {
+			var frame bytes.Buffer
+			Encoder{W: &frame}.WriteVarInt("data length", dataLen)
+			zw := zlib.NewWriter(&frame)
+			_, _ = zw.Write(data.Bytes())
+			suite.NoError(zw.Close())
+
+			var buf bytes.Buffer
+			Encoder{W: &buf}.WriteVarInt("packet length", frame.Len())
+			_, _ = frame.WriteTo(&buf)
+
+			p, err := DecodeCompressed(&buf, PhaseStatus)
+			suite.ErrorIs(err, ErrInvalidFrame)
+			suite.Nil(p)
+		})
+	}
+}
+
+func (suite *PacketSuite) TestEncodeServerboundData() {
+	handshake := ServerboundHandshake{
+		ProtocolVersion: LatestVersionNumber,
+		ServerAddress:   "localhost",
+		ServerPort:      25565,
+		NextState:       NextStateLogin,
+	}
+	data, err := Latest.EncodeServerboundData(handshake)
+	suite.NoError(err)
+	p, err := Latest.DecodeData(data, PhaseHandshaking)
+	suite.NoError(err)
+	suite.Equal(&handshake, p)
+
+	_, err = Latest.EncodeServerboundData(ClientboundPong{})
+	suite.ErrorIs(err, ErrUnregisteredPacket)
+}
+
+func (suite *PacketSuite) TestDecodeClientboundData() {
+	suite.Run("decodable", func() {
+		position := ClientboundPlayerPositionAndLook{X: 1, Y: 2, Z: 3, Yaw: 4, Pitch: 5, Flags: 6, TeleportID: 7}
+		data, err := Latest.EncodeData(position)
+		suite.NoError(err)
+		p, err := Latest.DecodeClientboundData(data, PhasePlay)
+		suite.NoError(err)
+		suite.Equal(&position, p)
+	})
+	suite.Run("undecodable", func() {
+		data, err := Latest.EncodeData(ClientboundTags{})
+		suite.NoError(err)
+		p, err := Latest.DecodeClientboundData(data, PhasePlay)
+		suite.ErrorIs(err, ErrUndecodablePacket)
+		suite.Nil(p)
+	})
+	suite.Run("unknown", func() {
+		data, err := Latest.EncodeData(ClientboundPong{})
+		suite.NoError(err)
+		p, err := Latest.DecodeClientboundData(data, PhasePlay)
+		suite.ErrorIs(err, ErrUnknownPacketID)
+		suite.Nil(p)
+	})
+}
+
+func (suite *PacketSuite) TestTabComplete() {
+	tooltip := chat.Chat{ChatFragment: chat.ChatFragment{Text: "tooltip"}}
+	tabComplete := ClientboundTabComplete{
+		TransactionID: 3,
+		Start:         6,
+		Length:        2,
+		Matches: []TabCompleteMatch{
+			{Match: "Notch"},
+			{Match: "jeb_", Tooltip: &tooltip},
+		},
+	}
+	data, err := Latest.EncodeData(tabComplete)
+	suite.NoError(err)
+	p, err := Latest.DecodeClientboundData(data, PhasePlay)
+	suite.NoError(err)
+	suite.Equal(&tabComplete, p)
+}
+
+func (suite *PacketSuite) TestEncodeDeclareCommands() {
+	var buf bytes.Buffer
+	suite.NoError(ClientboundDeclareCommands{
+		Nodes: []CommandNode{
+			{Type: CommandNodeRoot, Children: []int{1}},
+			{Type: CommandNodeLiteral, Name: "tp", Children: []int{2}},
+			{
+				Type:            CommandNodeArgument,
+				Executable:      true,
+				Name:            "x",
+				Parser:          id.ParseID("brigadier:integer"),
+				Properties:      []byte{0x00},
+				SuggestionsType: SuggestionsAskServer,
+			},
+		},
+	}.EncodeInto(&buf))
+
+	dec := Decoder{&buf}
+	suite.EqualValues(3, dec.ReadVarInt("count"))
+	suite.EqualValues(0x00, dec.ReadUbyte("flags"))
+	suite.EqualValues(1, dec.ReadVarInt("children count"))
+	suite.EqualValues(1, dec.ReadVarInt("children"))
+
+	suite.EqualValues(0x01, dec.ReadUbyte("flags"))
+	suite.EqualValues(1, dec.ReadVarInt("children count"))
+	suite.EqualValues(2, dec.ReadVarInt("children"))
+	suite.Equal("tp", dec.ReadString("name"))
+
+	suite.EqualValues(0x02|0x04|0x10, dec.ReadUbyte("flags"))
+	suite.EqualValues(0, dec.ReadVarInt("children count"))
+	suite.Equal("x", dec.ReadString("name"))
+	suite.Equal(id.ParseID("brigadier:integer"), dec.ReadID("parser"))
+	suite.EqualValues(0x00, dec.ReadUbyte("properties"))
+	suite.Equal(SuggestionsAskServer, dec.ReadID("suggestions type"))
+
+	suite.EqualValues(0, dec.ReadVarInt("root index"))
+	suite.Zero(buf.Len())
+}
+
+func (suite *PacketSuite) TestReadClientboundFrame() {
+	var buf bytes.Buffer
+	suite.NoError(WriteFrame(&buf, make([]byte, FrameLimit(PhaseStatus)+1), CompressionDisabled))
+	data, err := ReadClientboundFrame(&buf, false)
+	suite.NoError(err)
+	suite.Len(data, FrameLimit(PhaseStatus)+1)
+}
@@ -0,0 +1,264 @@
+package packet
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"reflect"
+)
+
+// Protocol maps packet IDs to packet types and vice versa. Clients with
+// different protocol versions may use different IDs for the same packets.
+// A Protocol is shared by all Versions that use the same IDs.
+type Protocol struct {
+	serverbound map[Phase]map[ID]reflect.Type
+	clientbound map[reflect.Type]ID
+	// serverboundIDs is the reverse of serverbound.
+	serverboundIDs map[reflect.Type]ID
+	// clientboundTypes is the reverse of clientbound, by phase.
+	clientboundTypes map[Phase]map[ID]reflect.Type
+}
+
+// NewProtocol creates a new protocol without any packets.
+func NewProtocol() *Protocol {
+	return &Protocol{
+		serverbound:      make(map[Phase]map[ID]reflect.Type),
+		clientbound:      make(map[reflect.Type]ID),
+		serverboundIDs:   make(map[reflect.Type]ID),
+		clientboundTypes: make(map[Phase]map[ID]reflect.Type),
+	}
+}
+
+// RegisterPacket registers the given type in the given phase with the ID that
+// the packet returns. See Register.
+func (p *Protocol) RegisterPacket(phase Phase, typ reflect.Type) {
+	p.Register(phase, packetID(typ), typ)
+}
+
+// Register associates the given type with the given Phase and ID in this protocol.
+// The given type must implement Packet and either Serverbound or Clientbound.
+// If a packet implements both, Serverbound takes precedence. Use RegisterServerbound
+// or RegisterClientbound to register such packets with an explicit direction.
+func (p *Protocol) Register(phase Phase, id ID, typ reflect.Type) {
+	if !typ.Implements(packetInterfaceType) {
+		panic(fmt.Sprintf("%s does not implement the Packet interface", typ.Name()))
+	}
+	created := reflect.New(typ).Interface()
+	if _, ok := created.(Serverbound); ok {
+		p.RegisterServerbound(phase, id, typ)
+	} else if _, ok := created.(Clientbound); ok {
+		p.RegisterClientbound(phase, id, typ)
+	} else {
+		panic(fmt.Sprintf("%s is a packet, but does neither implement Serverbound nor Clientbound", typ.Name()))
+	}
+}
+
+// RegisterServerbound associates the given type with the given Phase and ID in this
+// protocol, so that it is decoded when a client sends a packet with that ID.
+// The given type must implement Serverbound.
+func (p *Protocol) RegisterServerbound(phase Phase, id ID, typ reflect.Type) {
+	created := reflect.New(typ).Interface()
+	if _, ok := created.(Serverbound); !ok {
+		panic(fmt.Sprintf("%s does not implement the Serverbound interface", typ.Name()))
+	}
+	// initialize phase map if necessary
+	if p.serverbound[phase] == nil {
+		p.serverbound[phase] = make(map[ID]reflect.Type)
+	}
+	if p.serverbound[phase][id] != nil {
+		panic(fmt.Sprintf("already registered packet %T in phase %s", created, phase))
+	}
+	p.serverbound[phase][id] = typ
+	p.serverboundIDs[typ] = id
+}
+
+// RegisterClientbound associates the given type with the given ID in this protocol,
+// so that it is encoded with that ID. The given type must implement Clientbound.
+func (p *Protocol) RegisterClientbound(phase Phase, id ID, typ reflect.Type) {
+	created := reflect.New(typ).Interface()
+	if _, ok := created.(Clientbound); !ok {
+		panic(fmt.Sprintf("%s does not implement the Clientbound interface", typ.Name()))
+	}
+	if _, ok := p.clientbound[typ]; ok {
+		panic(fmt.Sprintf("already registered packet %T in phase %s", created, phase))
+	}
+	p.clientbound[typ] = id
+	if p.clientboundTypes[phase] == nil {
+		p.clientboundTypes[phase] = make(map[ID]reflect.Type)
+	}
+	p.clientboundTypes[phase][id] = typ
+}
+
+// ServerboundType returns the type that is registered for the given serverbound
+// packet ID in the given phase, or nil if there is none.
+func (p *Protocol) ServerboundType(phase Phase, id ID) reflect.Type {
+	return p.serverbound[phase][id]
+}
+
+// ClientboundType returns the type that is registered for the given clientbound
+// packet ID in the given phase, or nil if there is none.
+func (p *Protocol) ClientboundType(phase Phase, id ID) reflect.Type {
+	return p.clientboundTypes[phase][id]
+}
+
+// clientboundID returns the ID of the given packet in this protocol.
+func (p *Protocol) clientboundID(pkg Clientbound) (ID, error) {
+	typ := reflect.TypeOf(pkg)
+	if typ.Kind() == reflect.Ptr {
+		typ = typ.Elem()
+	}
+	id, ok := p.clientbound[typ]
+	if !ok {
+		return 0, fmt.Errorf("%s: %w", pkg.Name(), ErrUnregisteredPacket)
+	}
+	return id, nil
+}
+
+// serverboundID returns the ID of the given serverbound packet in this protocol.
+func (p *Protocol) serverboundID(pkg Packet) (ID, error) {
+	typ := reflect.TypeOf(pkg)
+	if typ.Kind() == reflect.Ptr {
+		typ = typ.Elem()
+	}
+	id, ok := p.serverboundIDs[typ]
+	if !ok {
+		return 0, fmt.Errorf("%s: %w", pkg.Name(), ErrUnregisteredPacket)
+	}
+	return id, nil
+}
+
+// EncodeData encodes the packet ID and payload of the given packet, which is the
+// data of a frame, before compression. The packet must be registered in this protocol.
+func (p *Protocol) EncodeData(pkg Clientbound) (data []byte, err error) {
+	defer recoverAndSetErr(&err)
+
+	id, err := p.clientboundID(pkg)
+	if err != nil {
+		return nil, err
+	}
+
+	var buf bytes.Buffer
+	enc := Encoder{&buf}
+	enc.WriteVarInt("packet ID", int(id))
+	panicIffErr("packet", pkg.EncodeInto(&buf))
+	return buf.Bytes(), nil
+}
+
+// Encode will write the given packet onto the given writer. As opposed to the
+// Clientbound.EncodeInto method, this will also write the packet length and ID.
+// The packet must be registered in this protocol.
+func (p *Protocol) Encode(pkg Clientbound, w io.Writer) error {
+	return p.EncodeCompressed(pkg, w, CompressionDisabled)
+}
+
+// EncodeCompressed will write the given packet onto the given writer, using the
+// compressed packet format. If the packet ID and payload together are at least
+// threshold bytes long, they will be compressed with zlib, otherwise they are
+// written uncompressed with a data length of 0. If the threshold is negative,
+// this is equivalent to Encode.
+func (p *Protocol) EncodeCompressed(pkg Clientbound, w io.Writer, threshold int) error {
+	data, err := p.EncodeData(pkg)
+	if err != nil {
+		return err
+	}
+	if err := WriteFrame(w, data, threshold); err != nil {
+		return fmt.Errorf("%s: %w", pkg.Name(), err)
+	}
+	return nil
+}
+
+// Decode decodes a Serverbound packet from the given reader, depending on the
+// given phase. If the frame is longer than allowed in the phase (see FrameLimit),
+// an error wrapping ErrFrameTooLarge is returned. Otherwise, the whole frame was
+// consumed from the reader after this returns, even if decoding failed or the packet
+// ID is unknown, in which case an error wrapping ErrUnknownPacketID is returned.
+func (p *Protocol) Decode(rd io.Reader, phase Phase) (Serverbound, error) {
+	data, err := ReadFrame(rd, phase, false)
+	if err != nil {
+		return nil, err
+	}
+	return p.DecodeData(data, phase)
+}
+
+// DecodeCompressed decodes a Serverbound packet in the compressed packet format
+// from the given reader, depending on the given phase. Packets with a data length
+// of 0 are read uncompressed, all other packets are decompressed with zlib.
+// Frame limits and errors are the same as with Decode, where the limit also applies
+// to the uncompressed data length.
+func (p *Protocol) DecodeCompressed(rd io.Reader, phase Phase) (Serverbound, error) {
+	data, err := ReadFrame(rd, phase, true)
+	if err != nil {
+		return nil, err
+	}
+	return p.DecodeData(data, phase)
+}
+
+// DecodeData decodes a Serverbound packet from the given frame data, which is the
+// packet ID and payload, as returned by ReadFrame. If the packet ID is unknown in the
+// given phase, an error wrapping ErrUnknownPacketID is returned.
+func (p *Protocol) DecodeData(data []byte, phase Phase) (pkg Serverbound, err error) {
+	defer recoverAndSetErr(&err)
+
+	rd := bytes.NewReader(data)
+	packetID := ID(Decoder{rd}.ReadVarInt("packet ID"))
+	return p.decodePayload(rd, phase, packetID)
+}
+
+// EncodeServerboundData encodes the packet ID and payload of the given serverbound
+// packet, as it is sent by a client. The packet must be registered in this protocol.
+func (p *Protocol) EncodeServerboundData(pkg Encodable) (data []byte, err error) {
+	defer recoverAndSetErr(&err)
+
+	id, err := p.serverboundID(pkg)
+	if err != nil {
+		return nil, err
+	}
+
+	var buf bytes.Buffer
+	enc := Encoder{&buf}
+	enc.WriteVarInt("packet ID", int(id))
+	panicIffErr("packet", pkg.EncodeInto(&buf))
+	return buf.Bytes(), nil
+}
+
+// DecodeClientboundData decodes a clientbound packet from the given frame data, as it
+// is received by a client. If the packet ID is unknown in the given phase, an error
+// wrapping ErrUnknownPacketID is returned. If the packet is known, but can't be
+// decoded, because it doesn't implement Decodable, an error wrapping
+// ErrUndecodablePacket is returned.
+func (p *Protocol) DecodeClientboundData(data []byte, phase Phase) (pkg Packet, err error) {
+	defer recoverAndSetErr(&err)
+
+	rd := bytes.NewReader(data)
+	packetID := ID(Decoder{rd}.ReadVarInt("packet ID"))
+	packetType := p.clientboundTypes[phase][packetID]
+	if packetType == nil {
+		return nil, fmt.Errorf("%w %s in phase %s", ErrUnknownPacketID, packetID, phase)
+	}
+	decodable, ok := reflect.New(packetType).Interface().(Decodable)
+	if !ok {
+		return nil, fmt.Errorf("%s: %w", packetType.Name(), ErrUndecodablePacket)
+	}
+	if err := decodable.DecodeFrom(rd); err != nil {
+		return nil, fmt.Errorf("decode %s: %w", decodable.Name(), err)
+	}
+	return decodable, nil
+}
+
+func (p *Protocol) decodePayload(payloadReader io.Reader, phase Phase, packetID ID) (Serverbound, error) {
+	packetType := p.serverbound[phase][packetID]
+	if packetType == nil {
+		return nil, fmt.Errorf("%w %s in phase %s", ErrUnknownPacketID, packetID, phase)
+	}
+	packetInterface := reflect.New(packetType).Interface()
+	packet := packetInterface.(Serverbound)
+	if err := packet.DecodeFrom(payloadReader); err != nil {
+		return nil, fmt.Errorf("decode %s: %w", packet.Name(), err)
+	}
+	if validator, ok := packetInterface.(Validator); ok {
+		if err := validator.Validate(); err != nil {
+			return nil, fmt.Errorf("validate %s: %w", packet.Name(), err)
+		}
+	}
+	return packet, nil
+}
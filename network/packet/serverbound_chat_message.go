@@ -0,0 +1,11 @@
+package packet
+
+// Validate implements the Validator interface.
+func (s ServerboundChatMessage) Validate() error {
+	return multiValidate(
+		stringNotEmpty("message", s.Message),
+		stringMaxLength("message", 256, s.Message),
+		// the vanilla server disconnects players that use formatting codes in chat
+		stringNotContains("message", s.Message, "§"),
+	)
+}
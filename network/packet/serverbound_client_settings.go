@@ -6,7 +6,7 @@ import (
 )
 
 func init() {
-	RegisterPacket(PhasePlay, reflect.TypeOf(ServerboundClientSettings{}))
+	RegisterServerbound(PhasePlay, reflect.TypeOf(ServerboundClientSettings{}))
 }
 
 // ChatMode is a type for allowed constants.
@@ -64,6 +64,22 @@ func (ServerboundClientSettings) ID() ID { return IDServerboundClientSettings }
 // Name returns the constant packet name.
 func (ServerboundClientSettings) Name() string { return "Client Settings" }
 
+// EncodeInto writes this packet into the given writer.
+func (s ServerboundClientSettings) EncodeInto(w io.Writer) (err error) {
+	defer recoverAndSetErr(&err)
+
+	enc := Encoder{w}
+
+	enc.WriteString("locale", s.Locale)
+	enc.WriteByte("view distance", int8(s.ViewDistance))
+	enc.WriteVarInt("chat mode", int(s.ChatMode))
+	enc.WriteBoolean("chat colors", s.ChatColors)
+	enc.WriteUbyte("displayed skin parts", s.DisplayedSkinParts)
+	enc.WriteVarInt("main hand", int(s.MainHand))
+
+	return
+}
+
 // DecodeFrom will fill this struct with values read from the given reader.
 func (s *ServerboundClientSettings) DecodeFrom(rd io.Reader) (err error) {
 	defer recoverAndSetErr(&err)
@@ -0,0 +1,9 @@
+package packet
+
+// Validate implements the Validator interface.
+func (s ServerboundEncryptionResponse) Validate() error {
+	return multiValidate(
+		intWithinRange("shared secret length", 1, 512, len(s.SharedSecret)),
+		intWithinRange("verify token length", 1, 512, len(s.VerifyToken)),
+	)
+}
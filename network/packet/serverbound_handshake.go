@@ -3,10 +3,11 @@ package packet
 import (
 	"io"
 	"reflect"
+	"strings"
 )
 
 func init() {
-	RegisterPacket(PhaseHandshaking, reflect.TypeOf(ServerboundHandshake{}))
+	RegisterServerbound(PhaseHandshaking, reflect.TypeOf(ServerboundHandshake{}))
 }
 
 //go:generate stringer -linecomment -output=serverbound_handshake_string.go -type=NextState
@@ -38,6 +39,20 @@ func (ServerboundHandshake) ID() ID { return IDServerboundHandshake }
 // Name returns the constant packet name.
 func (ServerboundHandshake) Name() string { return "Handshake" }
 
+// EncodeInto writes this packet into the given writer.
+func (s ServerboundHandshake) EncodeInto(w io.Writer) (err error) {
+	defer recoverAndSetErr(&err)
+
+	enc := Encoder{w}
+
+	enc.WriteVarInt("protocol version", s.ProtocolVersion)
+	enc.WriteString("server address", s.ServerAddress)
+	enc.WriteUshort("server port", uint16(s.ServerPort))
+	enc.WriteVarInt("next state", int(s.NextState))
+
+	return
+}
+
 // DecodeFrom will fill this struct with values read from the given reader.
 func (s *ServerboundHandshake) DecodeFrom(rd io.Reader) (err error) {
 	defer recoverAndSetErr(&err)
@@ -52,11 +67,17 @@ func (s *ServerboundHandshake) DecodeFrom(rd io.Reader) (err error) {
 	return
 }
 
+// Host returns the server address without the data that proxies or modded clients
+// append to it, separated by null bytes.
+func (s ServerboundHandshake) Host() string {
+	return strings.SplitN(s.ServerAddress, "\x00", 2)[0]
+}
+
 // Validate implements the Validator interface.
 func (s ServerboundHandshake) Validate() error {
 	return multiValidate(
-		stringNotEmpty("server address", s.ServerAddress),
-		stringMaxLength("server address", 255, s.ServerAddress),
+		stringNotEmpty("server address", s.Host()),
+		stringMaxLength("server address", 255, s.Host()),
 		intWithinRange("next state", 1, 2, int(s.NextState)),
 	)
 }
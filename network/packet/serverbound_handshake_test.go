@@ -48,6 +48,16 @@ func (suite *PacketSuite) TestServerboundHandshake_Validate() {
 			},
 			false,
 		},
+		{
+			"forwarded server address",
+			fields{
+				123,
+				"abc.de\x00203.0.113.7\x00069a79f444e94726a5befca90e38aaf5\x00[]",
+				65234,
+				2,
+			},
+			false,
+		},
 		{
 			"invalid server address",
 			fields{
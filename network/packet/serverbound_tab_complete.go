@@ -0,0 +1,6 @@
+package packet
+
+// Validate implements the Validator interface.
+func (s ServerboundTabComplete) Validate() error {
+	return stringMaxLength("text", 32500, s.Text)
+}
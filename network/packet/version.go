@@ -0,0 +1,71 @@
+package packet
+
+import (
+	"fmt"
+	"sort"
+)
+
+// The latest protocol version, which is the one that all packets are implemented for.
+// Minecraft 1.16.4 and 1.16.5 both use protocol version 754, so clients of both
+// game versions can connect. The status response reports the newer name.
+const (
+	LatestVersionNumber = 754
+	LatestVersionName   = "1.16.5"
+)
+
+var (
+	// Latest is the protocol of the latest supported version. Packets that are
+	// registered with RegisterPacket are registered in this protocol.
+	Latest = NewProtocol()
+	// LatestVersion is the latest supported version.
+	LatestVersion = Version{
+		Number:   LatestVersionNumber,
+		Name:     LatestVersionName,
+		Protocol: Latest,
+	}
+
+	versions = make(map[int]Version)
+)
+
+func init() {
+	RegisterVersion(LatestVersion)
+}
+
+// Version is a protocol version that clients can connect with.
+type Version struct {
+	// Number is the protocol version number that the client sends in the handshake.
+	Number int
+	// Name is the name of the game version, such as 1.16.5. If several game
+	// versions share the protocol version number, this is the newest of them.
+	Name string
+	// Protocol holds the packet IDs that are used by clients with this version.
+	Protocol *Protocol
+}
+
+// RegisterVersion makes the given version supported, so that it can be looked up
+// with LookupVersion. This panics if a version with the same number is already registered.
+func RegisterVersion(v Version) {
+	if _, ok := versions[v.Number]; ok {
+		panic(fmt.Sprintf("already registered protocol version %d", v.Number))
+	}
+	versions[v.Number] = v
+}
+
+// LookupVersion returns the version with the given protocol version number, and
+// false if that version is not supported.
+func LookupVersion(number int) (Version, bool) {
+	v, ok := versions[number]
+	return v, ok
+}
+
+// SupportedVersions returns all registered versions, ordered by their number.
+func SupportedVersions() []Version {
+	supported := make([]Version, 0, len(versions))
+	for _, v := range versions {
+		supported = append(supported, v)
+	}
+	sort.Slice(supported, func(i, j int) bool {
+		return supported[i].Number < supported[j].Number
+	})
+	return supported
+}
@@ -0,0 +1,60 @@
+package packet
+
+import (
+	"bytes"
+	"reflect"
+	"testing"
+
+	"github.com/stretchr/testify/suite"
+)
+
+func TestProtocolSuite(t *testing.T) {
+	suite.Run(t, new(ProtocolSuite))
+}
+
+type ProtocolSuite struct {
+	suite.Suite
+}
+
+func (suite *ProtocolSuite) TestSupportedVersions() {
+	versions := SupportedVersions()
+	suite.Require().Len(versions, 1)
+	suite.Equal(LatestVersion, versions[0])
+
+	v, ok := LookupVersion(754)
+	suite.True(ok)
+	suite.Equal("1.16.5", v.Name)
+	suite.Same(Latest, v.Protocol)
+
+	for _, number := range []int{47, 751, 753, 755} {
+		_, ok = LookupVersion(number)
+		suite.Falsef(ok, "version %d must not be supported", number)
+	}
+}
+
+func (suite *ProtocolSuite) TestCustomIDs() {
+	protocol := NewProtocol()
+	protocol.RegisterServerbound(PhaseStatus, 0x05, reflect.TypeOf(ServerboundPing{}))
+	protocol.RegisterClientbound(PhaseStatus, 0x06, reflect.TypeOf(ClientboundPong{}))
+
+	var buf bytes.Buffer
+	suite.NoError(protocol.Encode(ClientboundPong{Payload: 17}, &buf))
+	dec := Decoder{&buf}
+	suite.EqualValues(9, dec.ReadVarInt("packet length"))
+	suite.EqualValues(0x06, dec.ReadVarInt("packet id"))
+	suite.EqualValues(17, dec.ReadLong("payload"))
+
+	enc := Encoder{&buf}
+	enc.WriteVarInt("packet length", 9)
+	enc.WriteVarInt("packet id", 0x05)
+	enc.WriteLong("payload", 17)
+	p, err := protocol.Decode(&buf, PhaseStatus)
+	suite.NoError(err)
+	suite.Equal(&ServerboundPing{Payload: 17}, p)
+
+	suite.ErrorIs(protocol.Encode(ClientboundLoginSuccess{}, &buf), ErrUnregisteredPacket)
+
+	suite.Equal(reflect.TypeOf(ServerboundPing{}), protocol.ServerboundType(PhaseStatus, 0x05))
+	suite.Equal(reflect.TypeOf(ClientboundPong{}), protocol.ClientboundType(PhaseStatus, 0x06))
+	suite.Nil(protocol.ClientboundType(PhasePlay, 0x06))
+}
@@ -0,0 +1,151 @@
+package network
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"net"
+	"strconv"
+	"strings"
+)
+
+const (
+	// proxyV1MaxLength is the maximum length of a version 1 header, including
+	// the terminating CRLF.
+	proxyV1MaxLength = 107
+
+	proxyV2HeaderLength = 16
+	proxyV2Version      = 0x2
+	proxyV2CmdLocal     = 0x0
+	proxyV2CmdProxy     = 0x1
+	proxyV2FamilyTCP4   = 0x1
+	proxyV2FamilyTCP6   = 0x2
+)
+
+var (
+	proxyV1Signature = []byte("PROXY ")
+	proxyV2Signature = []byte("\r\n\r\n\x00\r\nQUIT\n")
+)
+
+// ReadProxyHeader reads a PROXY protocol header of version 1 or 2 from the given
+// reader, as it is sent by load balancers like HAProxy before the actual data
+// of the connection. The returned address is the address of the client that
+// connected to the load balancer. If the header is valid, but doesn't contain an
+// address, e.g. because the load balancer sends health checks, the returned
+// address is nil. If the reader doesn't start with a PROXY protocol header, an
+// ErrInvalidProxyHeader is returned.
+//
+// Protocol specification: https://www.haproxy.org/download/2.3/doc/proxy-protocol.txt
+func ReadProxyHeader(rd *bufio.Reader) (net.Addr, error) {
+	signature, err := rd.Peek(len(proxyV1Signature))
+	if err != nil {
+		return nil, fmt.Errorf("peek signature: %w", err)
+	}
+	if bytes.Equal(signature, proxyV1Signature) {
+		return readProxyHeaderV1(rd)
+	}
+
+	signature, err = rd.Peek(len(proxyV2Signature))
+	if err != nil {
+		return nil, fmt.Errorf("peek signature: %w", err)
+	}
+	if bytes.Equal(signature, proxyV2Signature) {
+		return readProxyHeaderV2(rd)
+	}
+
+	return nil, fmt.Errorf("%w: unknown signature", ErrInvalidProxyHeader)
+}
+
+// readProxyHeaderV1 reads a human readable header, such as
+//
+//	PROXY TCP4 192.168.0.1 192.168.0.11 56324 25565\r\n
+func readProxyHeaderV1(rd *bufio.Reader) (net.Addr, error) {
+	var line []byte
+	for !bytes.HasSuffix(line, []byte("\r\n")) {
+		if len(line) == proxyV1MaxLength {
+			return nil, fmt.Errorf("%w: header too long", ErrInvalidProxyHeader)
+		}
+		b, err := rd.ReadByte()
+		if err != nil {
+			return nil, fmt.Errorf("read header: %w", err)
+		}
+		line = append(line, b)
+	}
+
+	fields := strings.Split(strings.TrimSuffix(string(line), "\r\n"), " ")
+	if len(fields) >= 2 && fields[1] == "UNKNOWN" {
+		// the rest of the line must be ignored
+		return nil, nil
+	}
+	if len(fields) != 6 {
+		return nil, fmt.Errorf("%w: expected 6 fields, but got %d", ErrInvalidProxyHeader, len(fields))
+	}
+	if fields[1] != "TCP4" && fields[1] != "TCP6" {
+		return nil, fmt.Errorf("%w: unknown protocol %s", ErrInvalidProxyHeader, fields[1])
+	}
+
+	ip := net.ParseIP(fields[2])
+	if ip == nil {
+		return nil, fmt.Errorf("%w: invalid source address %s", ErrInvalidProxyHeader, fields[2])
+	}
+	port, err := strconv.ParseUint(fields[4], 10, 16)
+	if err != nil {
+		return nil, fmt.Errorf("%w: invalid source port %s", ErrInvalidProxyHeader, fields[4])
+	}
+	return &net.TCPAddr{
+		IP:   ip,
+		Port: int(port),
+	}, nil
+}
+
+// readProxyHeaderV2 reads a binary header, which consists of the signature, the
+// version and command, the address family, the length of the remaining header and
+// the addresses, optionally followed by additional information that is ignored.
+func readProxyHeaderV2(rd *bufio.Reader) (net.Addr, error) {
+	header := make([]byte, proxyV2HeaderLength)
+	if _, err := io.ReadFull(rd, header); err != nil {
+		return nil, fmt.Errorf("read header: %w", err)
+	}
+	version, command := header[12]>>4, header[12]&0x0F
+	family := header[13] >> 4
+	length := binary.BigEndian.Uint16(header[14:])
+
+	if version != proxyV2Version {
+		return nil, fmt.Errorf("%w: unknown version %d", ErrInvalidProxyHeader, version)
+	}
+
+	payload := make([]byte, length)
+	if _, err := io.ReadFull(rd, payload); err != nil {
+		return nil, fmt.Errorf("read addresses: %w", err)
+	}
+
+	switch command {
+	case proxyV2CmdLocal:
+		// connection was established by the proxy itself
+		return nil, nil
+	case proxyV2CmdProxy:
+	default:
+		return nil, fmt.Errorf("%w: unknown command %d", ErrInvalidProxyHeader, command)
+	}
+
+	var ipLength int
+	switch family {
+	case proxyV2FamilyTCP4:
+		ipLength = net.IPv4len
+	case proxyV2FamilyTCP6:
+		ipLength = net.IPv6len
+	default:
+		// unspecified or unix socket, we don't have an IP to work with
+		return nil, nil
+	}
+	// source address, destination address, source port, destination port
+	if len(payload) < 2*ipLength+4 {
+		return nil, fmt.Errorf("%w: addresses too short", ErrInvalidProxyHeader)
+	}
+	return &net.TCPAddr{
+		IP:   net.IP(payload[:ipLength]),
+		Port: int(binary.BigEndian.Uint16(payload[2*ipLength:])),
+	}, nil
+}
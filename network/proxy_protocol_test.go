@@ -0,0 +1,104 @@
+package network
+
+import (
+	"bufio"
+	"bytes"
+	"net"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestReadProxyHeader(t *testing.T) {
+	v2 := func(command, family byte, addresses []byte) []byte {
+		header := append([]byte("\r\n\r\n\x00\r\nQUIT\n"), 0x20|command, family<<4|0x1, 0, byte(len(addresses)))
+		return append(header, addresses...)
+	}
+
+	tests := []struct {
+		name    string
+		input   []byte
+		want    net.Addr
+		wantErr bool
+	}{
+		{
+			name:  "v1 tcp4",
+			input: []byte("PROXY TCP4 203.0.113.7 192.168.0.11 56324 25565\r\n"),
+			want:  &net.TCPAddr{IP: net.ParseIP("203.0.113.7"), Port: 56324},
+		},
+		{
+			name:  "v1 tcp6",
+			input: []byte("PROXY TCP6 2001:db8::1 2001:db8::2 56324 25565\r\n"),
+			want:  &net.TCPAddr{IP: net.ParseIP("2001:db8::1"), Port: 56324},
+		},
+		{
+			name:  "v1 unknown",
+			input: []byte("PROXY UNKNOWN ffff::1 ffff::2 1 2\r\n"),
+		},
+		{
+			name:    "v1 missing fields",
+			input:   []byte("PROXY TCP4 203.0.113.7\r\n"),
+			wantErr: true,
+		},
+		{
+			name:    "v1 invalid address",
+			input:   []byte("PROXY TCP4 notanip 192.168.0.11 56324 25565\r\n"),
+			wantErr: true,
+		},
+		{
+			name:    "v1 too long",
+			input:   append([]byte("PROXY "), bytes.Repeat([]byte{'a'}, 200)...),
+			wantErr: true,
+		},
+		{
+			name: "v2 tcp4",
+			input: v2(0x1, 0x1, []byte{
+				203, 0, 113, 7, // source
+				192, 168, 0, 11, // destination
+				0xdc, 0x04, // source port
+				0x63, 0xdd, // destination port
+			}),
+			want: &net.TCPAddr{IP: net.IP{203, 0, 113, 7}, Port: 56324},
+		},
+		{
+			name: "v2 tcp6",
+			input: v2(0x1, 0x2, append(append(
+				net.ParseIP("2001:db8::1").To16(),
+				net.ParseIP("2001:db8::2").To16()...),
+				0xdc, 0x04, 0x63, 0xdd,
+			)),
+			want: &net.TCPAddr{IP: net.ParseIP("2001:db8::1"), Port: 56324},
+		},
+		{
+			name:  "v2 local",
+			input: v2(0x0, 0x0, nil),
+		},
+		{
+			name:    "v2 addresses too short",
+			input:   v2(0x1, 0x1, []byte{203, 0, 113, 7}),
+			wantErr: true,
+		},
+		{
+			name:    "no header",
+			input:   []byte{0x10, 0x00, 0xf2, 0x05, 0x09, 'l', 'o', 'c', 'a', 'l', 'h', 'o', 's', 't'},
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert := assert.New(t)
+
+			// data after the header must not be consumed
+			rd := bufio.NewReader(bytes.NewReader(append(tt.input, 0xCA, 0xFE)))
+			got, err := ReadProxyHeader(rd)
+			if tt.wantErr {
+				assert.ErrorIs(err, ErrInvalidProxyHeader)
+				return
+			}
+			assert.NoError(err)
+			assert.Equal(tt.want, got)
+			rest, _ := rd.Peek(2)
+			assert.Equal([]byte{0xCA, 0xFE}, rest)
+		})
+	}
+}
@@ -0,0 +1,251 @@
+// Package query implements the server side of the GameSpy4 query protocol, which
+// is used by server lists and monitoring tools to read information about a server
+// over UDP, as it is enabled with enable-query in the vanilla server.
+//
+// A client first sends a handshake, which is answered with a challenge token. With
+// that token, the client can request the basic stat (MOTD, map and player counts)
+// or the full stat (which additionally contains the version and the names of all
+// online players).
+package query
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"encoding/binary"
+	"errors"
+	"net"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/rs/zerolog"
+)
+
+const (
+	// TokenLifetime is the time for which a challenge token is valid after the
+	// handshake.
+	TokenLifetime = 30 * time.Second
+
+	typeHandshake byte = 9
+	typeStat      byte = 0
+
+	// sessionIDMask is applied to the session ID that clients send, since only the
+	// lower 4 bits of every byte are used.
+	sessionIDMask = 0x0F0F0F0F
+	// maxPacketSize is the maximum size of a request. Requests are at most 15 bytes
+	// long, but some clients send additional padding.
+	maxPacketSize = 1460
+	// basicStatLength is the length of a basic stat request. Full stat requests
+	// have 4 additional padding bytes.
+	basicStatLength = 11
+)
+
+var (
+	magic = []byte{0xFE, 0xFD}
+
+	fullStatPadding   = []byte("splitnum\x00\x80\x00")
+	fullStatPlayerKey = []byte("\x01player_\x00\x00")
+)
+
+// Info is the information about the server that is reported to clients.
+type Info struct {
+	// MOTD is the message of the day of the server, without formatting.
+	MOTD string
+	// Map is the name of the world.
+	Map string
+	// Version is the name of the game version, such as 1.16.5.
+	Version string
+	// Players are the names of all online players.
+	Players []string
+	// MaxPlayers is the maximum amount of players that can be online.
+	MaxPlayers int
+	// HostIP is the IP address that the server listens on for players.
+	HostIP string
+	// HostPort is the port that the server listens on for players.
+	HostPort int
+}
+
+// Server answers query requests that it receives on a packet connection.
+type Server struct {
+	log  zerolog.Logger
+	conn net.PacketConn
+	info func() Info
+
+	// mu guards tokens.
+	mu sync.Mutex
+	// tokens holds the challenge tokens that were handed out, by client address.
+	tokens map[string]challenge
+}
+
+type challenge struct {
+	token   int32
+	expires time.Time
+}
+
+// NewServer creates a new query server, which answers requests on the given
+// connection with the information that the given function returns.
+func NewServer(log zerolog.Logger, conn net.PacketConn, info func() Info) *Server {
+	return &Server{
+		log:    log,
+		conn:   conn,
+		info:   info,
+		tokens: make(map[string]challenge),
+	}
+}
+
+// Serve reads and answers requests until the given context is cancelled, which
+// closes the connection, or reading from the connection fails.
+func (s *Server) Serve(ctx context.Context) error {
+	go func() {
+		<-ctx.Done()
+		_ = s.conn.Close()
+	}()
+
+	buf := make([]byte, maxPacketSize)
+	for {
+		n, addr, err := s.conn.ReadFrom(buf)
+		if err != nil {
+			select {
+			case <-ctx.Done():
+				return nil
+			default:
+				return err
+			}
+		}
+
+		response, err := s.handle(buf[:n], addr, time.Now())
+		if err != nil {
+			s.log.Debug().
+				Err(err).
+				Stringer("from", addr).
+				Msg("ignoring invalid query")
+			continue
+		}
+		if _, err := s.conn.WriteTo(response, addr); err != nil {
+			s.log.Debug().
+				Err(err).
+				Stringer("to", addr).
+				Msg("write query response failed")
+		}
+	}
+}
+
+// handle creates the response to the given request of the client with the
+// given address.
+func (s *Server) handle(request []byte, addr net.Addr, now time.Time) ([]byte, error) {
+	if len(request) < 7 || !bytes.Equal(request[:2], magic) {
+		return nil, errors.New("invalid magic")
+	}
+	typ := request[2]
+	sessionID := int32(binary.BigEndian.Uint32(request[3:7]) & sessionIDMask)
+
+	switch typ {
+	case typeHandshake:
+		token, err := s.newToken(addr, now)
+		if err != nil {
+			return nil, err
+		}
+		response := header(typeHandshake, sessionID)
+		response.WriteString(strconv.Itoa(int(token)))
+		response.WriteByte(0)
+		return response.Bytes(), nil
+	case typeStat:
+		if len(request) < basicStatLength {
+			return nil, errors.New("stat request too short")
+		}
+		token := int32(binary.BigEndian.Uint32(request[7:11]))
+		if !s.validToken(addr, token, now) {
+			return nil, errors.New("invalid challenge token")
+		}
+		if len(request) == basicStatLength {
+			return basicStat(sessionID, s.info()), nil
+		}
+		return fullStat(sessionID, s.info()), nil
+	}
+	return nil, errors.New("unknown type " + strconv.Itoa(int(typ)))
+}
+
+// newToken creates a new challenge token for the given address, which replaces
+// the previous token of the address.
+func (s *Server) newToken(addr net.Addr, now time.Time) (int32, error) {
+	var token int32
+	if err := binary.Read(rand.Reader, binary.BigEndian, &token); err != nil {
+		return 0, err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for key, c := range s.tokens {
+		if now.After(c.expires) {
+			delete(s.tokens, key)
+		}
+	}
+	s.tokens[addr.String()] = challenge{
+		token:   token,
+		expires: now.Add(TokenLifetime),
+	}
+	return token, nil
+}
+
+func (s *Server) validToken(addr net.Addr, token int32, now time.Time) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	c, ok := s.tokens[addr.String()]
+	return ok && c.token == token && !now.After(c.expires)
+}
+
+func header(typ byte, sessionID int32) *bytes.Buffer {
+	var buf bytes.Buffer
+	buf.WriteByte(typ)
+	_ = binary.Write(&buf, binary.BigEndian, sessionID)
+	return &buf
+}
+
+func writeString(buf *bytes.Buffer, s string) {
+	buf.WriteString(s)
+	buf.WriteByte(0)
+}
+
+func basicStat(sessionID int32, info Info) []byte {
+	buf := header(typeStat, sessionID)
+	writeString(buf, info.MOTD)
+	writeString(buf, "SMP")
+	writeString(buf, info.Map)
+	writeString(buf, strconv.Itoa(len(info.Players)))
+	writeString(buf, strconv.Itoa(info.MaxPlayers))
+	// the port is the only little endian value of the protocol
+	_ = binary.Write(buf, binary.LittleEndian, uint16(info.HostPort))
+	writeString(buf, info.HostIP)
+	return buf.Bytes()
+}
+
+func fullStat(sessionID int32, info Info) []byte {
+	buf := header(typeStat, sessionID)
+	buf.Write(fullStatPadding)
+	for _, kv := range [][2]string{
+		{"hostname", info.MOTD},
+		{"gametype", "SMP"},
+		{"game_id", "MINECRAFT"},
+		{"version", info.Version},
+		{"plugins", ""},
+		{"map", info.Map},
+		{"numplayers", strconv.Itoa(len(info.Players))},
+		{"maxplayers", strconv.Itoa(info.MaxPlayers)},
+		{"hostport", strconv.Itoa(info.HostPort)},
+		{"hostip", info.HostIP},
+	} {
+		writeString(buf, kv[0])
+		writeString(buf, kv[1])
+	}
+	buf.WriteByte(0)
+
+	buf.Write(fullStatPlayerKey)
+	for _, name := range info.Players {
+		writeString(buf, name)
+	}
+	buf.WriteByte(0)
+	return buf.Bytes()
+}
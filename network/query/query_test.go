@@ -0,0 +1,161 @@
+package query
+
+import (
+	"bytes"
+	"context"
+	"encoding/binary"
+	"net"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/rs/zerolog"
+	"github.com/stretchr/testify/suite"
+)
+
+func TestQuerySuite(t *testing.T) {
+	suite.Run(t, new(QuerySuite))
+}
+
+type QuerySuite struct {
+	suite.Suite
+
+	cancel func()
+	client net.Conn
+}
+
+func (suite *QuerySuite) SetupTest() {
+	conn, err := net.ListenPacket("udp", "127.0.0.1:0")
+	suite.Require().NoError(err)
+
+	server := NewServer(zerolog.Nop(), conn, func() Info {
+		return Info{
+			MOTD:       "A Minecraft Server",
+			Map:        "world",
+			Version:    "1.16.5",
+			Players:    []string{"Notch", "jeb_"},
+			MaxPlayers: 20,
+			HostIP:     "127.0.0.1",
+			HostPort:   25565,
+		}
+	})
+	ctx, cancel := context.WithCancel(context.Background())
+	suite.cancel = cancel
+	go func() { _ = server.Serve(ctx) }()
+
+	client, err := net.Dial("udp", conn.LocalAddr().String())
+	suite.Require().NoError(err)
+	suite.client = client
+}
+
+func (suite *QuerySuite) TearDownTest() {
+	suite.cancel()
+	_ = suite.client.Close()
+}
+
+// request sends the given request and returns the response, or nil if the server
+// didn't answer.
+func (suite *QuerySuite) request(typ byte, sessionID int32, payload ...byte) []byte {
+	var buf bytes.Buffer
+	buf.Write(magic)
+	buf.WriteByte(typ)
+	_ = binary.Write(&buf, binary.BigEndian, sessionID)
+	buf.Write(payload)
+	_, err := suite.client.Write(buf.Bytes())
+	suite.Require().NoError(err)
+
+	suite.Require().NoError(suite.client.SetReadDeadline(time.Now().Add(500 * time.Millisecond)))
+	response := make([]byte, maxPacketSize)
+	n, err := suite.client.Read(response)
+	if netErr, ok := err.(net.Error); ok && netErr.Timeout() {
+		return nil
+	}
+	suite.Require().NoError(err)
+	return response[:n]
+}
+
+func (suite *QuerySuite) handshake(sessionID int32) []byte {
+	response := suite.request(typeHandshake, sessionID)
+	suite.Require().NotNil(response)
+	suite.Equal(typeHandshake, response[0])
+	suite.EqualValues(sessionID, binary.BigEndian.Uint32(response[1:5]))
+	token, err := strconv.Atoi(strings.TrimSuffix(string(response[5:]), "\x00"))
+	suite.Require().NoError(err)
+
+	tokenBytes := make([]byte, 4)
+	binary.BigEndian.PutUint32(tokenBytes, uint32(int32(token)))
+	return tokenBytes
+}
+
+func (suite *QuerySuite) TestBasicStat() {
+	token := suite.handshake(1)
+	response := suite.request(typeStat, 1, token...)
+	suite.Require().NotNil(response)
+
+	suite.Equal(typeStat, response[0])
+	suite.EqualValues(1, binary.BigEndian.Uint32(response[1:5]))
+	body := response[5:]
+	fields := strings.SplitN(string(body), "\x00", 6)
+	suite.Equal([]string{"A Minecraft Server", "SMP", "world", "2", "20"}, fields[:5])
+	rest := []byte(fields[5])
+	suite.EqualValues(25565, binary.LittleEndian.Uint16(rest[:2]))
+	suite.Equal("127.0.0.1\x00", string(rest[2:]))
+}
+
+func (suite *QuerySuite) TestFullStat() {
+	token := suite.handshake(0x01020304)
+	response := suite.request(typeStat, 0x01020304, append(token, 0, 0, 0, 0)...)
+	suite.Require().NotNil(response)
+
+	suite.Equal(typeStat, response[0])
+	suite.EqualValues(0x01020304, binary.BigEndian.Uint32(response[1:5]))
+	body := response[5:]
+	suite.True(bytes.HasPrefix(body, fullStatPadding))
+	body = body[len(fullStatPadding):]
+
+	parts := bytes.SplitN(body, append([]byte{0}, fullStatPlayerKey...), 2)
+	suite.Require().Len(parts, 2)
+	kv := strings.Split(strings.TrimSuffix(string(parts[0]), "\x00\x00"), "\x00")
+	values := make(map[string]string)
+	for i := 0; i+1 < len(kv); i += 2 {
+		values[kv[i]] = kv[i+1]
+	}
+	suite.Equal(map[string]string{
+		"hostname":   "A Minecraft Server",
+		"gametype":   "SMP",
+		"game_id":    "MINECRAFT",
+		"version":    "1.16.5",
+		"plugins":    "",
+		"map":        "world",
+		"numplayers": "2",
+		"maxplayers": "20",
+		"hostport":   "25565",
+		"hostip":     "127.0.0.1",
+	}, values)
+	suite.Equal("Notch\x00jeb_\x00\x00", string(parts[1]))
+}
+
+func (suite *QuerySuite) TestInvalidToken() {
+	token := suite.handshake(1)
+	token[3]++
+	suite.Nil(suite.request(typeStat, 1, token...))
+}
+
+func (suite *QuerySuite) TestSessionIDMasked() {
+	response := suite.request(typeHandshake, -1)
+	suite.Require().NotNil(response)
+	suite.EqualValues(sessionIDMask, binary.BigEndian.Uint32(response[1:5]))
+}
+
+func (suite *QuerySuite) TestTokenExpires() {
+	s := NewServer(zerolog.Nop(), nil, func() Info { return Info{} })
+	addr := &net.UDPAddr{IP: net.IPv4(127, 0, 0, 1), Port: 1234}
+	now := time.Now()
+
+	token, err := s.newToken(addr, now)
+	suite.Require().NoError(err)
+	suite.True(s.validToken(addr, token, now.Add(TokenLifetime)))
+	suite.False(s.validToken(addr, token, now.Add(TokenLifetime+time.Second)))
+	suite.False(s.validToken(&net.UDPAddr{IP: net.IPv4(127, 0, 0, 2), Port: 1234}, token, now))
+}
@@ -0,0 +1,257 @@
+// Package rcon implements the server side of the Source RCON protocol, which is
+// used to administer a server remotely, as it is enabled with enable-rcon in the
+// vanilla server.
+//
+// A client logs in with a password, and can then execute commands, whose output
+// is sent back to the client. Outputs that are too long for a single packet are
+// split into multiple response packets with the same request ID.
+package rcon
+
+import (
+	"bufio"
+	"context"
+	"crypto/subtle"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"sync"
+	"unicode/utf8"
+
+	"github.com/rs/zerolog"
+)
+
+// Packet types.
+const (
+	TypeResponse int32 = 0
+	TypeCommand  int32 = 2
+	// TypeAuthResponse has the same value as TypeCommand. Which one is meant
+	// depends on the direction of the packet.
+	TypeAuthResponse int32 = 2
+	TypeLogin        int32 = 3
+)
+
+const (
+	// MaxRequestLength is the maximum length of a request packet, without the
+	// length field itself, which is the same as in the vanilla server.
+	MaxRequestLength = 1460
+	// MaxResponseBodyLength is the maximum length of the body of a single response
+	// packet. Longer outputs are split into multiple packets.
+	MaxResponseBodyLength = 4096
+
+	// failedAuthID is the request ID of the response to a failed login.
+	failedAuthID int32 = -1
+	// minPacketLength is the length of a packet with an empty body, which consists
+	// of the request ID, the type and two null bytes.
+	minPacketLength = 10
+)
+
+// ErrInvalidPacket indicates, that a client sent a packet that is not a valid
+// RCON packet. The connection is closed.
+var ErrInvalidPacket = errors.New("invalid rcon packet")
+
+// Packet is a single RCON packet.
+type Packet struct {
+	// RequestID is chosen by the client, and is the same in the response.
+	RequestID int32
+	Type      int32
+	Body      string
+}
+
+// ReadPacket reads a single packet from the given reader, which may be a request or
+// a response.
+func ReadPacket(rd io.Reader) (Packet, error) {
+	return readPacket(rd, MaxResponseBodyLength+minPacketLength)
+}
+
+// readPacket reads a single packet, which must not be longer than the given
+// length, from the given reader.
+func readPacket(rd io.Reader, maxLength int32) (Packet, error) {
+	var length int32
+	if err := binary.Read(rd, binary.LittleEndian, &length); err != nil {
+		return Packet{}, err
+	}
+	if length < minPacketLength || length > maxLength {
+		return Packet{}, fmt.Errorf("%w: length %d", ErrInvalidPacket, length)
+	}
+
+	data := make([]byte, length)
+	if _, err := io.ReadFull(rd, data); err != nil {
+		return Packet{}, fmt.Errorf("read packet: %w", err)
+	}
+	if data[length-2] != 0 || data[length-1] != 0 {
+		return Packet{}, fmt.Errorf("%w: missing null terminators", ErrInvalidPacket)
+	}
+	return Packet{
+		RequestID: int32(binary.LittleEndian.Uint32(data[0:4])),
+		Type:      int32(binary.LittleEndian.Uint32(data[4:8])),
+		Body:      string(data[8 : length-2]),
+	}, nil
+}
+
+// WritePacket writes the given packet to the given writer.
+func WritePacket(w io.Writer, p Packet) error {
+	data := make([]byte, 4+minPacketLength+len(p.Body))
+	binary.LittleEndian.PutUint32(data[0:4], uint32(minPacketLength+len(p.Body)))
+	binary.LittleEndian.PutUint32(data[4:8], uint32(p.RequestID))
+	binary.LittleEndian.PutUint32(data[8:12], uint32(p.Type))
+	copy(data[12:], p.Body)
+	_, err := w.Write(data)
+	return err
+}
+
+// Server accepts RCON connections on a listener, and executes the commands of
+// clients that logged in.
+type Server struct {
+	log      zerolog.Logger
+	listener net.Listener
+	password []byte
+	execute  func(ctx context.Context, command string) string
+
+	// wg waits for all connections to be handled.
+	wg sync.WaitGroup
+}
+
+// NewServer creates a new RCON server, which accepts connections on the given
+// listener. Clients must log in with the given password, which must not be empty.
+// Commands are executed with the given function, which returns the output that is
+// sent to the client.
+func NewServer(log zerolog.Logger, listener net.Listener, password string, execute func(ctx context.Context, command string) string) *Server {
+	return &Server{
+		log:      log,
+		listener: listener,
+		password: []byte(password),
+		execute:  execute,
+	}
+}
+
+// Serve accepts and handles connections until the given context is cancelled, which
+// closes the listener and all connections, or accepting connections fails.
+func (s *Server) Serve(ctx context.Context) error {
+	ctx, cancel := context.WithCancel(ctx)
+	defer func() {
+		cancel()
+		s.wg.Wait()
+	}()
+	go func() {
+		<-ctx.Done()
+		_ = s.listener.Close()
+	}()
+
+	for {
+		conn, err := s.listener.Accept()
+		if err != nil {
+			select {
+			case <-ctx.Done():
+				return nil
+			default:
+				return fmt.Errorf("accept: %w", err)
+			}
+		}
+
+		s.wg.Add(1)
+		go func() {
+			defer s.wg.Done()
+			s.handle(ctx, conn)
+		}()
+	}
+}
+
+func (s *Server) handle(ctx context.Context, conn net.Conn) {
+	log := s.log.With().
+		Stringer("remote", conn.RemoteAddr()).
+		Logger()
+	log.Debug().
+		Msg("rcon connection")
+
+	done := make(chan struct{})
+	defer close(done)
+	go func() {
+		select {
+		case <-ctx.Done():
+		case <-done:
+		}
+		_ = conn.Close()
+	}()
+
+	rd := bufio.NewReader(conn)
+	authenticated := false
+	for {
+		p, err := readPacket(rd, MaxRequestLength)
+		if err != nil {
+			if !errors.Is(err, io.EOF) && ctx.Err() == nil {
+				log.Debug().
+					Err(err).
+					Msg("read rcon packet failed, closing connection")
+			}
+			return
+		}
+
+		switch {
+		case p.Type == TypeLogin:
+			authenticated = subtle.ConstantTimeCompare([]byte(p.Body), s.password) == 1
+			response := Packet{
+				RequestID: p.RequestID,
+				Type:      TypeAuthResponse,
+			}
+			if !authenticated {
+				log.Info().
+					Msg("rcon login with wrong password")
+				response.RequestID = failedAuthID
+			}
+			err = WritePacket(conn, response)
+		case !authenticated:
+			log.Info().
+				Msg("rcon packet before login, closing connection")
+			return
+		case p.Type == TypeCommand:
+			err = s.writeResponse(conn, p.RequestID, s.execute(ctx, p.Body))
+		default:
+			// clients send an unknown packet after a command, and wait until it is
+			// mirrored, to find out when all parts of the response were received
+			err = WritePacket(conn, Packet{
+				RequestID: p.RequestID,
+				Type:      TypeResponse,
+			})
+		}
+		if err != nil {
+			log.Debug().
+				Err(err).
+				Msg("write rcon packet failed, closing connection")
+			return
+		}
+	}
+}
+
+// writeResponse writes the given output in response packets with the given request
+// ID, each with a body of at most MaxResponseBodyLength bytes. The output is only
+// split between runes, so that every body is valid UTF-8 on its own.
+func (s *Server) writeResponse(w io.Writer, requestID int32, output string) error {
+	for {
+		part := output
+		if len(part) > MaxResponseBodyLength {
+			end := MaxResponseBodyLength
+			for end > 0 && !utf8.RuneStart(part[end]) {
+				end--
+			}
+			if end == 0 {
+				// not valid UTF-8, split anywhere
+				end = MaxResponseBodyLength
+			}
+			part = part[:end]
+		}
+		output = output[len(part):]
+
+		if err := WritePacket(w, Packet{
+			RequestID: requestID,
+			Type:      TypeResponse,
+			Body:      part,
+		}); err != nil {
+			return err
+		}
+		if len(output) == 0 {
+			return nil
+		}
+	}
+}
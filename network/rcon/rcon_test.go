@@ -0,0 +1,137 @@
+package rcon
+
+import (
+	"bytes"
+	"context"
+	"net"
+	"strings"
+	"testing"
+	"time"
+	"unicode/utf8"
+
+	"github.com/rs/zerolog"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/suite"
+)
+
+func TestPacketRoundTrip(t *testing.T) {
+	assert := assert.New(t)
+
+	var buf bytes.Buffer
+	p := Packet{RequestID: 42, Type: TypeCommand, Body: "list"}
+	assert.NoError(WritePacket(&buf, p))
+	assert.Equal([]byte{
+		14, 0, 0, 0, // length
+		42, 0, 0, 0, // request id
+		2, 0, 0, 0, // type
+		'l', 'i', 's', 't', 0, 0,
+	}, buf.Bytes())
+
+	read, err := ReadPacket(&buf)
+	assert.NoError(err)
+	assert.Equal(p, read)
+}
+
+func TestReadPacketInvalid(t *testing.T) {
+	_, err := ReadPacket(bytes.NewReader([]byte{0xFF, 0xFF, 0, 0}))
+	assert.ErrorIs(t, err, ErrInvalidPacket)
+
+	_, err = ReadPacket(bytes.NewReader([]byte{10, 0, 0, 0, 1, 0, 0, 0, 2, 0, 0, 0, 'a', 'b'}))
+	assert.ErrorIs(t, err, ErrInvalidPacket)
+}
+
+func TestRCONSuite(t *testing.T) {
+	suite.Run(t, new(RCONSuite))
+}
+
+type RCONSuite struct {
+	suite.Suite
+
+	cancel func()
+	client net.Conn
+}
+
+func (suite *RCONSuite) SetupTest() {
+	lis, err := net.Listen("tcp", "127.0.0.1:0")
+	suite.Require().NoError(err)
+
+	server := NewServer(zerolog.Nop(), lis, "secret", func(_ context.Context, command string) string {
+		if command == "long" {
+			return strings.Repeat("a", MaxResponseBodyLength+10)
+		}
+		if command == "long unicode" {
+			// the last rune of the first packet would be split
+			return strings.Repeat("a", MaxResponseBodyLength-1) + strings.Repeat("ä", 5)
+		}
+		return "executed " + command
+	})
+	ctx, cancel := context.WithCancel(context.Background())
+	suite.cancel = cancel
+	go func() { _ = server.Serve(ctx) }()
+
+	client, err := net.Dial("tcp", lis.Addr().String())
+	suite.Require().NoError(err)
+	suite.Require().NoError(client.SetDeadline(time.Now().Add(5 * time.Second)))
+	suite.client = client
+}
+
+func (suite *RCONSuite) TearDownTest() {
+	suite.cancel()
+	_ = suite.client.Close()
+}
+
+func (suite *RCONSuite) send(p Packet) {
+	suite.Require().NoError(WritePacket(suite.client, p))
+}
+
+func (suite *RCONSuite) receive() Packet {
+	p, err := ReadPacket(suite.client)
+	suite.Require().NoError(err)
+	return p
+}
+
+func (suite *RCONSuite) TestCommand() {
+	suite.send(Packet{RequestID: 1, Type: TypeLogin, Body: "secret"})
+	suite.Equal(Packet{RequestID: 1, Type: TypeAuthResponse}, suite.receive())
+
+	suite.send(Packet{RequestID: 2, Type: TypeCommand, Body: "list"})
+	suite.Equal(Packet{RequestID: 2, Type: TypeResponse, Body: "executed list"}, suite.receive())
+}
+
+func (suite *RCONSuite) TestMultiPacketResponse() {
+	suite.send(Packet{RequestID: 1, Type: TypeLogin, Body: "secret"})
+	suite.receive()
+
+	suite.send(Packet{RequestID: 2, Type: TypeCommand, Body: "long"})
+	suite.send(Packet{RequestID: 3, Type: TypeResponse})
+	first := suite.receive()
+	suite.Equal(int32(2), first.RequestID)
+	suite.Len(first.Body, MaxResponseBodyLength)
+	second := suite.receive()
+	suite.Equal(int32(2), second.RequestID)
+	suite.Len(second.Body, 10)
+	// the mirrored packet marks the end of the response
+	suite.Equal(Packet{RequestID: 3, Type: TypeResponse}, suite.receive())
+}
+
+func (suite *RCONSuite) TestMultiPacketResponseUnicode() {
+	suite.send(Packet{RequestID: 1, Type: TypeLogin, Body: "secret"})
+	suite.receive()
+
+	suite.send(Packet{RequestID: 2, Type: TypeCommand, Body: "long unicode"})
+	first := suite.receive()
+	suite.Len(first.Body, MaxResponseBodyLength-1)
+	suite.True(utf8.ValidString(first.Body))
+	second := suite.receive()
+	suite.Equal(strings.Repeat("ä", 5), second.Body)
+}
+
+func (suite *RCONSuite) TestWrongPassword() {
+	suite.send(Packet{RequestID: 1, Type: TypeLogin, Body: "wrong"})
+	suite.Equal(Packet{RequestID: -1, Type: TypeAuthResponse}, suite.receive())
+
+	// commands are not executed
+	suite.send(Packet{RequestID: 2, Type: TypeCommand, Body: "list"})
+	_, err := ReadPacket(suite.client)
+	suite.Error(err)
+}
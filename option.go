@@ -4,6 +4,8 @@ import (
 	"net"
 
 	"github.com/rs/zerolog"
+
+	"github.com/tsatke/mcserver/auth"
 )
 
 // Option is an API function that can be passed into New to customize the created server
@@ -18,9 +20,56 @@ func WithListener(lis net.Listener) Option {
 	}
 }
 
+// WithQueryListener tells the server, which packet connection it should use to answer
+// queries. If this is given, queries are answered no matter if they are enabled in the
+// config, and the server will not allocate a separate connection.
+func WithQueryListener(conn net.PacketConn) Option {
+	return func(srv *MCServer) {
+		srv.queryConn = conn
+	}
+}
+
+// WithRCONListener tells the server, which listener it should use to accept remote
+// console connections. If this is given, the remote console is enabled no matter if
+// it is enabled in the config, and the server will not allocate a separate listener.
+// A password must still be configured.
+func WithRCONListener(lis net.Listener) Option {
+	return func(srv *MCServer) {
+		srv.rconListener = lis
+	}
+}
+
 // WithLogger will make the server use the given logger to write logs.
 func WithLogger(log zerolog.Logger) Option {
 	return func(srv *MCServer) {
 		srv.log = log
 	}
 }
+
+// WithAuthenticator will make the server use the given authenticator to authenticate
+// players that are logging in. If this is not given, the server will use an
+// auth.SessionServer in online mode, and auth.Offline otherwise.
+func WithAuthenticator(authenticator auth.Authenticator) Option {
+	return func(srv *MCServer) {
+		srv.authenticator = authenticator
+	}
+}
+
+// WithProxyProtocol makes the server expect a PROXY protocol header (version 1 or 2)
+// at the start of every incoming connection, as it is sent by load balancers like
+// HAProxy. The client address from the header is used instead of the address of the
+// load balancer. Connections without a valid header are closed, so only use this if
+// the server can't be reached without going through the load balancer.
+func WithProxyProtocol() Option {
+	return func(srv *MCServer) {
+		srv.proxyProtocol = true
+	}
+}
+
+// WithStatusHook makes the server call the given hook for every status request, so
+// that the response that is displayed in the server list can be built dynamically.
+func WithStatusHook(hook StatusHook) Option {
+	return func(srv *MCServer) {
+		srv.statusHook = hook
+	}
+}
@@ -0,0 +1,50 @@
+package mcserver
+
+import (
+	"context"
+	"net"
+	"strconv"
+
+	"github.com/tsatke/mcserver/network/query"
+)
+
+// serveQueries answers GameSpy4 queries until the given context is cancelled.
+func (s *MCServer) serveQueries(ctx context.Context) {
+	s.log.Info().
+		Stringer("addr", s.queryConn.LocalAddr()).
+		Msg("answering queries")
+
+	server := query.NewServer(
+		s.log.With().
+			Str("component", "query").
+			Logger(),
+		s.queryConn,
+		s.queryInfo,
+	)
+	if err := server.Serve(ctx); err != nil {
+		s.log.Error().
+			Err(err).
+			Msg("stopped answering queries")
+	}
+}
+
+// queryInfo returns the information about this server that is reported to queries.
+func (s *MCServer) queryInfo() query.Info {
+	players := s.game.Players()
+	names := make([]string, len(players))
+	for i, p := range players {
+		names[i] = p.Name()
+	}
+
+	host, portString, _ := net.SplitHostPort(s.listener.Addr().String())
+	port, _ := strconv.Atoi(portString)
+	return query.Info{
+		MOTD:       s.config.MOTD().PlainText(),
+		Map:        s.config.GameWorld(),
+		Version:    ServerVersion,
+		Players:    names,
+		MaxPlayers: s.config.MaxPlayers(),
+		HostIP:     host,
+		HostPort:   port,
+	}
+}
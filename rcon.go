@@ -0,0 +1,38 @@
+package mcserver
+
+import (
+	"context"
+
+	"github.com/tsatke/mcserver/network/rcon"
+)
+
+// serveRCON accepts remote console connections until the given context is cancelled.
+func (s *MCServer) serveRCON(ctx context.Context) {
+	s.log.Info().
+		Stringer("addr", s.rconListener.Addr()).
+		Msg("accepting rcon connections")
+
+	server := rcon.NewServer(
+		s.log.With().
+			Str("component", "rcon").
+			Logger(),
+		s.rconListener,
+		s.config.RCONPassword(),
+		s.executeRCONCommand,
+	)
+	if err := server.Serve(ctx); err != nil {
+		s.log.Error().
+			Err(err).
+			Msg("stopped accepting rcon connections")
+	}
+}
+
+// executeRCONCommand executes the given command, and returns its output, or the
+// error if the command failed, since the client only gets to see text.
+func (s *MCServer) executeRCONCommand(ctx context.Context, command string) string {
+	output, err := s.ExecuteCommand(ctx, command)
+	if err != nil {
+		return err.Error()
+	}
+	return output
+}
@@ -0,0 +1,86 @@
+package mcserver
+
+import (
+	"bytes"
+	"encoding/base64"
+	"fmt"
+	"image/png"
+	"io/ioutil"
+	"net"
+	"os"
+
+	"github.com/tsatke/mcserver/network/packet"
+)
+
+const (
+	// statusSampleSize is the maximum amount of players in the sample of the status
+	// response, which is the same as in the vanilla server.
+	statusSampleSize = 12
+	// faviconSize is the width and height of the server icon in pixels.
+	faviconSize = 64
+)
+
+// StatusHook is called for every status request, including legacy pings, with the
+// IP and version of the client and the response that the server built from its
+// config and the online players. The returned response is sent to the client.
+type StatusHook func(ip net.IP, version packet.Version, response packet.Response) packet.Response
+
+// statusResponse creates the response that is sent to clients in the status phase,
+// which they display in the server list. If the given version is not the client's
+// version, the client shows the server as incompatible.
+func (s *MCServer) statusResponse(ip net.IP, version packet.Version) packet.Response {
+	players := s.game.Players()
+	sample := make([]packet.ResponsePlayersSample, 0, statusSampleSize)
+	for _, p := range players {
+		if len(sample) == statusSampleSize {
+			break
+		}
+		sample = append(sample, packet.ResponsePlayersSample{
+			Name: p.Name(),
+			ID:   p.UUID.String(),
+		})
+	}
+
+	response := packet.Response{
+		Version: packet.ResponseVersion{
+			Name:     version.Name,
+			Protocol: version.Number,
+		},
+		Players: packet.ResponsePlayers{
+			Max:    s.config.MaxPlayers(),
+			Online: len(players),
+			Sample: sample,
+		},
+		Description: s.config.MOTD(),
+		Favicon:     s.favicon,
+	}
+	if s.statusHook != nil {
+		response = s.statusHook(ip, version, response)
+	}
+	return response
+}
+
+// loadFavicon loads the server icon from the PNG image at the given path, and
+// returns it as data URL, as it is expected in the status response. If the file
+// doesn't exist, an empty string is returned.
+func loadFavicon(path string) (string, error) {
+	if path == "" {
+		return "", nil
+	}
+	data, err := ioutil.ReadFile(path)
+	if os.IsNotExist(err) {
+		return "", nil
+	}
+	if err != nil {
+		return "", err
+	}
+
+	cfg, err := png.DecodeConfig(bytes.NewReader(data))
+	if err != nil {
+		return "", fmt.Errorf("decode png: %w", err)
+	}
+	if cfg.Width != faviconSize || cfg.Height != faviconSize {
+		return "", fmt.Errorf("icon must be %dx%d pixels, but is %dx%d", faviconSize, faviconSize, cfg.Width, cfg.Height)
+	}
+	return "data:image/png;base64," + base64.StdEncoding.EncodeToString(data), nil
+}
@@ -0,0 +1,48 @@
+package mcserver
+
+import (
+	"bytes"
+	"encoding/base64"
+	"image"
+	"image/png"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestLoadFavicon(t *testing.T) {
+	assert := assert.New(t)
+
+	dir, err := ioutil.TempDir("", "mcserver-favicon")
+	assert.NoError(err)
+	defer func() { _ = os.RemoveAll(dir) }()
+
+	writePNG := func(name string, size int) string {
+		var buf bytes.Buffer
+		assert.NoError(png.Encode(&buf, image.NewRGBA(image.Rect(0, 0, size, size))))
+		path := filepath.Join(dir, name)
+		assert.NoError(ioutil.WriteFile(path, buf.Bytes(), 0600))
+		return path
+	}
+
+	valid := writePNG("valid.png", 64)
+	favicon, err := loadFavicon(valid)
+	assert.NoError(err)
+	data, _ := ioutil.ReadFile(valid)
+	assert.Equal("data:image/png;base64,"+base64.StdEncoding.EncodeToString(data), favicon)
+
+	_, err = loadFavicon(writePNG("small.png", 32))
+	assert.EqualError(err, "icon must be 64x64 pixels, but is 32x32")
+
+	notPNG := filepath.Join(dir, "icon.txt")
+	assert.NoError(ioutil.WriteFile(notPNG, []byte("not a png"), 0600))
+	_, err = loadFavicon(notPNG)
+	assert.Error(err)
+
+	favicon, err = loadFavicon(filepath.Join(dir, "missing.png"))
+	assert.NoError(err)
+	assert.Empty(favicon)
+}
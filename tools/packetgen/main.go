@@ -0,0 +1,285 @@
+// Command packetgen generates packet definitions for the packet package from a
+// declarative description of the packets in a yaml file. For every packet, the
+// struct, ID constant, codec methods and registration are generated, as well as
+// a round trip test.
+//
+// The yaml file contains a list of packets.
+//
+//	# minecraft_packets.yaml
+//	- name: ServerboundKeepAlive      # name of the generated struct
+//	  title: Keep Alive (serverbound) # return value of the Name method
+//	  direction: serverbound          # serverbound or clientbound
+//	  phase: play                     # handshaking, status, login or play
+//	  id: 0x10
+//	  doc: ServerboundKeepAlive is the response to a ClientboundKeepAlive.
+//	  fields:
+//	    - name: KeepAliveID
+//	      type: long
+//	      doc: KeepAliveID is the ID that was sent in the ClientboundKeepAlive.
+//
+// A field can be marked as optional, in which case it is prefixed with a boolean
+// that indicates whether the field is present, and it is generated as pointer.
+// A field can also be an array, in which case it is prefixed with the number of
+// elements as VarInt. For the supported types, see the types variable.
+package main
+
+import (
+	"bytes"
+	"flag"
+	"fmt"
+	"go/format"
+	"io/ioutil"
+	"sort"
+	"strings"
+	"text/template"
+	"unicode"
+
+	"gopkg.in/yaml.v3"
+)
+
+var (
+	inFile   string
+	outFile  string
+	testFile string
+	pkg      string
+)
+
+func init() {
+	flag.StringVar(&inFile, "in", "packets.yaml", "The configuration file that defines the packets")
+	flag.StringVar(&outFile, "out", "packets.go", "The Go source file that will be generated")
+	flag.StringVar(&testFile, "test", "packets_test.go", "The Go test file that will be generated")
+	flag.StringVar(&pkg, "pkg", "main", "The package for which the source file is generated")
+}
+
+func main() {
+	flag.Parse()
+
+	var packets []Packet
+	data, err := ioutil.ReadFile(inFile) // #nosec
+	if err != nil {
+		panic(err)
+	}
+	if err := yaml.Unmarshal(data, &packets); err != nil {
+		panic(err)
+	}
+	if err := validate(packets); err != nil {
+		panic(err)
+	}
+
+	templateData := TemplateData{
+		InFile:   inFile,
+		OutFile:  outFile,
+		TestFile: testFile,
+		Package:  pkg,
+		Packets:  packets,
+	}
+	generate(sourceTemplate, outFile, templateData)
+	generate(testTemplate, testFile, templateData)
+}
+
+func generate(text, file string, data TemplateData) {
+	tmpl := template.Must(template.New("").
+		Funcs(map[string]interface{}{
+			"comment":   comment,
+			"wirename":  wirename,
+			"phaseName": phaseName,
+			"imports":   imports,
+		}).
+		Parse(text))
+
+	var buf bytes.Buffer
+	if err := tmpl.Execute(&buf, data); err != nil {
+		panic(err)
+	}
+	source, err := format.Source(buf.Bytes())
+	if err != nil {
+		panic(fmt.Errorf("format %s: %w\n%s", file, err, buf.String()))
+	}
+	if err := ioutil.WriteFile(file, source, 0666); err != nil { // #nosec
+		panic(err)
+	}
+}
+
+// TemplateData is the template structure that will be passed into the templates.
+type TemplateData struct {
+	InFile   string
+	OutFile  string
+	TestFile string
+	Package  string
+	Packets  []Packet
+}
+
+// Packet is the description of a single packet.
+type Packet struct {
+	Name      string
+	Title     string
+	Direction string
+	Phase     string
+	ID        int
+	Doc       string
+	Fields    []Field
+}
+
+// Field is the description of a single packet field.
+type Field struct {
+	Name     string
+	Type     string
+	Doc      string
+	Optional bool
+	Array    bool
+}
+
+// T returns the type description of this field.
+func (f Field) T() Type {
+	return types[f.Type]
+}
+
+// GoType returns the Go type of the field in the generated struct.
+func (f Field) GoType() string {
+	typ := f.T().GoType
+	if f.Optional {
+		typ = "*" + typ
+	}
+	if f.Array {
+		typ = "[]" + typ
+	}
+	return typ
+}
+
+// Sample returns a Go expression that can be used as non-zero value for this
+// field in tests.
+func (f Field) Sample() string {
+	t := f.T()
+	switch {
+	case f.Array:
+		return fmt.Sprintf("[]%s{%s, %s}", t.GoType, t.Sample, t.Sample)
+	case f.Optional:
+		return fmt.Sprintf("func() *%s { v := %s(%s); return &v }()", t.GoType, t.GoType, t.Sample)
+	}
+	return t.Sample
+}
+
+// Type describes how a protocol data type is represented in Go and how it is
+// encoded and decoded.
+type Type struct {
+	// GoType is the Go type that the field has.
+	GoType string
+	// Write and Read are the names of the Encoder and Decoder methods.
+	Write, Read string
+	// Import is the package that GoType is declared in, if any.
+	Import string
+	// Sample is a Go expression of a non-zero value for tests.
+	Sample string
+}
+
+var types = map[string]Type{
+	"boolean":    {GoType: "bool", Write: "WriteBoolean", Read: "ReadBoolean", Sample: "true"},
+	"byte":       {GoType: "int8", Write: "WriteByte", Read: "ReadByte", Sample: "-7"},
+	"ubyte":      {GoType: "byte", Write: "WriteUbyte", Read: "ReadUbyte", Sample: "200"},
+	"short":      {GoType: "int16", Write: "WriteShort", Read: "ReadShort", Sample: "-300"},
+	"ushort":     {GoType: "uint16", Write: "WriteUshort", Read: "ReadUshort", Sample: "65000"},
+	"int":        {GoType: "int32", Write: "WriteInt", Read: "ReadInt", Sample: "-70000"},
+	"long":       {GoType: "int64", Write: "WriteLong", Read: "ReadLong", Sample: "-5000000000"},
+	"float":      {GoType: "float32", Write: "WriteFloat", Read: "ReadFloat", Sample: "1.5"},
+	"double":     {GoType: "float64", Write: "WriteDouble", Read: "ReadDouble", Sample: "-2.25"},
+	"varint":     {GoType: "int", Write: "WriteVarInt", Read: "ReadVarInt", Sample: "300"},
+	"varlong":    {GoType: "int64", Write: "WriteVarLong", Read: "ReadVarLong", Sample: "5000000000"},
+	"string":     {GoType: "string", Write: "WriteString", Read: "ReadString", Sample: `"sample"`},
+	"bytes":      {GoType: "[]byte", Write: "WritePrefixedByteArray", Read: "ReadPrefixedByteArray", Sample: "[]byte{1, 2, 3}"},
+	"rest":       {GoType: "[]byte", Write: "WriteByteArray", Read: "ReadRemainingBytes", Sample: "[]byte{1, 2, 3}"},
+	"uuid":       {GoType: "uuid.UUID", Write: "WriteUUID", Read: "ReadUUID", Import: "github.com/google/uuid", Sample: `uuid.MustParse("b50ad385-829d-3141-a216-7e7d7539ba7f")`},
+	"chat":       {GoType: "chat.Chat", Write: "WriteChat", Read: "ReadChat", Import: "github.com/tsatke/mcserver/game/chat", Sample: `chat.Chat{ChatFragment: chat.ChatFragment{Text: "sample"}}`},
+	"identifier": {GoType: "id.ID", Write: "WriteID", Read: "ReadID", Import: "github.com/tsatke/mcserver/game/id", Sample: `id.ParseID("minecraft:sample")`},
+	"position":   {GoType: "voxel.V3", Write: "WritePosition", Read: "ReadPosition", Import: "github.com/tsatke/mcserver/game/voxel", Sample: "voxel.V3{X: 18357644, Y: 831, Z: -20882616}"},
+	"nbt":        {GoType: "nbt.Tag", Write: "WriteNBT", Read: "ReadNBT", Import: "github.com/tsatke/nbt", Sample: `nbt.NewCompoundTag("", []nbt.Tag{nbt.NewStringTag("sample", "sample")})`},
+}
+
+var phases = map[string]string{
+	"handshaking": "PhaseHandshaking",
+	"status":      "PhaseStatus",
+	"login":       "PhaseLogin",
+	"play":        "PhasePlay",
+}
+
+func validate(packets []Packet) error {
+	names := make(map[string]bool)
+	for _, p := range packets {
+		if names[p.Name] {
+			return fmt.Errorf("duplicate packet %s", p.Name)
+		}
+		names[p.Name] = true
+
+		if p.Direction != "serverbound" && p.Direction != "clientbound" {
+			return fmt.Errorf("%s: invalid direction %q", p.Name, p.Direction)
+		}
+		if _, ok := phases[p.Phase]; !ok {
+			return fmt.Errorf("%s: invalid phase %q", p.Name, p.Phase)
+		}
+		for _, f := range p.Fields {
+			if _, ok := types[f.Type]; !ok {
+				return fmt.Errorf("%s.%s: unknown type %q", p.Name, f.Name, f.Type)
+			}
+			if f.Optional && f.Array {
+				return fmt.Errorf("%s.%s: a field can't be optional and an array", p.Name, f.Name)
+			}
+			if f.Type == "rest" && (f.Optional || f.Array) {
+				return fmt.Errorf("%s.%s: rest can't be optional or an array", p.Name, f.Name)
+			}
+		}
+	}
+	return nil
+}
+
+// comment formats the given text as Go comment with the given indentation.
+func comment(indent, text string) string {
+	text = strings.TrimSpace(text)
+	if text == "" {
+		return ""
+	}
+	var buf strings.Builder
+	for _, line := range strings.Split(text, "\n") {
+		buf.WriteString(indent + strings.TrimRight("// "+line, " ") + "\n")
+	}
+	return buf.String()
+}
+
+// wirename converts a field name to the lower case words that are used as field
+// name in errors, for example KeepAliveID becomes "keep alive id".
+func wirename(name string) string {
+	runes := []rune(name)
+	var words []string
+	start := 0
+	for i := 1; i < len(runes); i++ {
+		lowerToUpper := unicode.IsLower(runes[i-1]) && unicode.IsUpper(runes[i])
+		acronymEnd := unicode.IsUpper(runes[i-1]) && unicode.IsUpper(runes[i]) && i+1 < len(runes) && unicode.IsLower(runes[i+1])
+		if lowerToUpper || acronymEnd {
+			words = append(words, string(runes[start:i]))
+			start = i
+		}
+	}
+	words = append(words, string(runes[start:]))
+	return strings.ToLower(strings.Join(words, " "))
+}
+
+func phaseName(phase string) string {
+	return phases[phase]
+}
+
+// imports returns the sorted imports that are needed for the field types of
+// the given packets, excluding the standard library.
+func imports(packets []Packet) []string {
+	set := make(map[string]bool)
+	for _, p := range packets {
+		for _, f := range p.Fields {
+			if imp := f.T().Import; imp != "" {
+				set[imp] = true
+			}
+		}
+	}
+	var result []string
+	for imp := range set {
+		result = append(result, imp)
+	}
+	sort.Strings(result)
+	return result
+}
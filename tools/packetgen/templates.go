@@ -0,0 +1,158 @@
+package main
+
+var (
+	sourceTemplate = `// Code generated by "packetgen -in={{ .InFile }} -out={{ .OutFile }} -test={{ .TestFile }} -pkg={{ .Package }}"; DO NOT EDIT.
+
+package {{ .Package }}
+
+import (
+	"io"
+	"reflect"
+{{ range imports .Packets }}
+	"{{ . }}"
+{{- end }}
+)
+
+// IDs of the generated packets.
+const (
+{{- range .Packets }}
+	ID{{ .Name }} ID = {{ printf "0x%02X" .ID }}
+{{- end }}
+)
+
+func init() {
+{{- range .Packets }}
+	Register{{ if eq .Direction "serverbound" }}Serverbound{{ else }}Clientbound{{ end }}({{ phaseName .Phase }}, reflect.TypeOf({{ .Name }}{}))
+{{- end }}
+}
+{{ range .Packets }}
+{{ comment "" .Doc -}}
+type {{ .Name }} struct {
+{{- range .Fields }}
+{{ comment "\t" .Doc -}}
+	{{ .Name }} {{ .GoType }}
+{{- end }}
+}
+
+// ID returns the constant packet ID.
+func ({{ .Name }}) ID() ID { return ID{{ .Name }} }
+
+// Name returns the constant packet name.
+func ({{ .Name }}) Name() string { return {{ printf "%q" .Title }} }
+
+// EncodeInto writes this packet into the given writer.
+func (p {{ .Name }}) EncodeInto(w io.Writer) (err error) {
+{{- if .Fields }}
+	defer recoverAndSetErr(&err)
+
+	enc := Encoder{w}
+
+{{ range .Fields }}{{ template "encode" . }}{{ end }}
+{{ end }}
+	return
+}
+
+// DecodeFrom will fill this struct with values read from the given reader.
+func (p *{{ .Name }}) DecodeFrom(rd io.Reader) (err error) {
+{{- if .Fields }}
+	defer recoverAndSetErr(&err)
+
+	dec := Decoder{rd}
+
+{{ range .Fields }}{{ template "decode" . }}{{ end }}
+{{ end }}
+	return
+}
+{{ end }}
+
+{{- define "encode" }}
+{{- if .Optional }}
+	enc.WriteBoolean("{{ wirename .Name }} present", p.{{ .Name }} != nil)
+	if p.{{ .Name }} != nil {
+		enc.{{ .T.Write }}("{{ wirename .Name }}", *p.{{ .Name }})
+	}
+{{- else if .Array }}
+	enc.WriteVarInt("{{ wirename .Name }} count", len(p.{{ .Name }}))
+	for _, v := range p.{{ .Name }} {
+		enc.{{ .T.Write }}("{{ wirename .Name }}", v)
+	}
+{{- else }}
+	enc.{{ .T.Write }}("{{ wirename .Name }}", p.{{ .Name }})
+{{- end }}
+{{ end }}
+
+{{- define "decode" }}
+{{- if .Optional }}
+	if dec.ReadBoolean("{{ wirename .Name }} present") {
+		v := dec.{{ .T.Read }}("{{ wirename .Name }}")
+		p.{{ .Name }} = &v
+	}
+{{- else if .Array }}
+	// no preallocation, since the count is sent by the client
+	for i, n := 0, dec.ReadVarInt("{{ wirename .Name }} count"); i < n; i++ {
+		p.{{ .Name }} = append(p.{{ .Name }}, dec.{{ .T.Read }}("{{ wirename .Name }}"))
+	}
+{{- else }}
+	p.{{ .Name }} = dec.{{ .T.Read }}("{{ wirename .Name }}")
+{{- end }}
+{{ end }}
+`
+
+	testTemplate = `// Code generated by "packetgen -in={{ .InFile }} -out={{ .OutFile }} -test={{ .TestFile }} -pkg={{ .Package }}"; DO NOT EDIT.
+
+package {{ .Package }}
+
+import (
+	"bytes"
+	"reflect"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+{{ range imports .Packets }}
+	"{{ . }}"
+{{- end }}
+)
+
+func TestGeneratedPacketsRoundTrip(t *testing.T) {
+	tests := []struct {
+		name    string
+		packet  Clientbound
+		decoded Serverbound
+	}{
+{{- range .Packets }}
+		{
+			"{{ .Name }}",
+			&{{ .Name }}{
+{{- range .Fields }}
+				{{ .Name }}: {{ .Sample }},
+{{- end }}
+			},
+			&{{ .Name }}{},
+		},
+{{- end }}
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert := assert.New(t)
+
+			var buf bytes.Buffer
+			assert.NoError(tt.packet.EncodeInto(&buf))
+			assert.NoError(tt.decoded.DecodeFrom(&buf))
+			assert.Equal(tt.packet, tt.decoded)
+			assert.Zero(buf.Len(), "decoding must consume the whole packet")
+		})
+	}
+}
+
+func TestGeneratedPacketsRegistered(t *testing.T) {
+	assert := assert.New(t)
+{{ range .Packets }}
+{{- if eq .Direction "serverbound" }}
+	assert.Equal(reflect.TypeOf({{ .Name }}{}), Latest.serverbound[{{ phaseName .Phase }}][ID{{ .Name }}])
+{{- else }}
+	assert.Equal(ID{{ .Name }}, Latest.clientbound[reflect.TypeOf({{ .Name }}{})])
+{{- end }}
+{{- end }}
+}
+`
+)